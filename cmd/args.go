@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// argsPreset bisects the arguments of a failing command line to find the
+// one that breaks it. The program itself is the known good line, and each
+// candidate runs it with the arguments up to the one being tested.
+func argsPreset(args []string) (*presetSetup, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("--preset args takes a command and its arguments after --, like bsct --preset args -- ./app --verbose input.txt")
+	}
+	return &presetSetup{
+		lines:      args,
+		source:     argsSource(args),
+		test:       `"$@"`,
+		testPrefix: ". {file} && ",
+		unit:       "argument",
+	}, nil
+}
+
+// argsSource is a Source of command-line arguments whose candidates are
+// written as shell code that appends each one to "$@". One argument per
+// line keeps candidates growable in place, and the quoting keeps arguments
+// with spaces or quotes whole.
+type argsSource lib.Lines
+
+// Len returns the number of arguments, including the program
+func (a argsSource) Len() int { return len(a) }
+
+// Line returns argument i as given
+func (a argsSource) Line(i int) (string, error) { return lib.Lines(a).Line(i) }
+
+// WriteLines writes arguments from through to (inclusive) to w, each as a
+// set command
+func (a argsSource) WriteLines(w io.Writer, from, to int) (int64, error) {
+	sets := make(lib.Lines, 0, max(to-from+1, 0))
+	for i := from; i <= to; i++ {
+		arg, err := a.Line(i)
+		if err != nil {
+			return 0, err
+		}
+		sets = append(sets, `set -- "$@" `+shellQuote(arg))
+	}
+	return sets.WriteLines(w, 0, len(sets)-1)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var blame bool
+
+// blameInfo is the commit that last touched a line
+type blameInfo struct {
+	commit  string
+	author  string
+	date    time.Time
+	summary string
+}
+
+// blameLine runs git blame for the 1-indexed line of path
+func blameLine(path string, line int) (*blameInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", line, line), "--", filepath.Base(abs))
+	cmd.Dir = filepath.Dir(abs)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git blame: %s", msg)
+		}
+		return nil, fmt.Errorf("git blame: %w", err)
+	}
+
+	// The first line holds the commit, followed by "key value" headers
+	info := &blameInfo{}
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if info.commit == "" {
+			info.commit, _, _ = strings.Cut(text, " ")
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			info.author = value
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.date = time.Unix(secs, 0)
+			}
+		case "summary":
+			info.summary = value
+		}
+	}
+	if info.commit == "" {
+		return nil, fmt.Errorf("git blame returned no commit for line %d", line)
+	}
+	return info, nil
+}
+
+// String formats the blame for the report
+func (b *blameInfo) String() string {
+	if strings.Trim(b.commit, "0") == "" {
+		return "Not committed yet"
+	}
+	short := b.commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	return fmt.Sprintf("Last changed in %s by %s on %s: %s", short, b.author, b.date.Format("2006-01-02"), b.summary)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+// cacheDir is where --cache-dir keeps verdicts across runs, if set
+var cacheDir string
+
+// verdictFlags are the flags that decide which verdict a candidate gets. Their
+// values scope --cache-dir entries along with the candidate's content, so a
+// changed test never reuses the verdicts of the old one. --test is left out
+// since verdictCache takes the test command a preset made of it.
+var verdictFlags = []string{
+	"preset", "after", "test-http", "expect-status", "expect-body", "http-upload", "test-tcp", "tcp-timeout",
+	"test-exists", "non-empty", "expect-stdout", "expect-stderr", "expect-exit", "expect-json", "match",
+	"combine", "invert", "webhook", "answers-fifo", "on-exec-error", "on-crash", "max-output-bytes",
+	"retries", "retry-mode", "probe-timeout", "ssh", "ssh-opt", "ssh-dir", "docker", "mount", "docker-arg",
+	"k8s", "k8s-namespace", "kubectl-arg", "pty", "shell", "limit-cpu", "limit-mem", "target",
+}
+
+// verdictCache returns the --cache-dir store for the verdicts of bisections
+// judged like this one, where before and test are the before and test commands
+// as any preset left them
+func verdictCache(cmd *cobra.Command, before, test string) lib.Cache {
+	var scope strings.Builder
+	fmt.Fprintf(&scope, "before=%q\ntest=%q\n", before, test)
+	for _, name := range verdictFlags {
+		fmt.Fprintf(&scope, "%s=%q\n", name, cmd.Flags().Lookup(name).Value.String())
+	}
+	return lib.ScopedCache(lib.DiskCache{Dir: cacheDir}, scope.String())
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// reportCI prints the result as key=value lines for scripts and, on GitHub
+// Actions, annotates the bad line and adds it to the job summary. name is the
+// input file as quickfixName gives it, "-" when there is none to annotate.
+func reportCI(w io.Writer, name string, result *lib.Result) {
+	if result.FirstGoodLine > 0 {
+		fmt.Fprintf(w, "first_good_line=%d\n", result.FirstGoodLine)
+	}
+	fmt.Fprintf(w, "bad_line=%d\n", result.BadLineNumber)
+	fmt.Fprintf(w, "bad_content=%s\n", ciValue(result.BadLineContent))
+	fmt.Fprintf(w, "last_good_line=%d\n", result.LastGoodLineNumber)
+	fmt.Fprintf(w, "steps=%d\n", result.StepsTaken)
+	fmt.Fprintf(w, "verified=%t\n", result.Verified)
+	fmt.Fprintf(w, "skipped=%s\n", joinInts(result.Skipped, ","))
+
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return
+	}
+
+	found := foundVerdict(result)
+	message := fmt.Sprintf("First %s line found by bsct in %d steps", found, result.StepsTaken)
+	if !result.Verified {
+		message += fmt.Sprintf(" (assumed %s, never tested)", found)
+	}
+	if name != "-" {
+		fmt.Fprintf(w, "::error file=%s,line=%d,title=bsct::%s\n", escapeAnnotation(name), result.BadLineNumber, escapeAnnotation(message))
+	} else {
+		fmt.Fprintf(w, "::error title=bsct::%s: line %d\n", escapeAnnotation(message), result.BadLineNumber)
+	}
+
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		if f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			fmt.Fprintf(f, "### bsct\n\nThe first %s line is **%d**, found in %d steps:\n\n```\n%s\n```\n",
+				found, result.BadLineNumber, result.StepsTaken, result.BadLineContent)
+			f.Close()
+		}
+	}
+}
+
+// ciValue keeps s on one key=value line. Values spanning lines, or starting
+// with a double quote, are written as a double-quoted Go string literal.
+func ciValue(s string) string {
+	if strings.ContainsAny(s, "\r\n") || strings.HasPrefix(s, `"`) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// escapeAnnotation escapes s for a GitHub Actions workflow command
+func escapeAnnotation(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
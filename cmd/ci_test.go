@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{"file", "deploy.log", "timeout: 0", []string{"bad_content=timeout: 0\n", "::error file=deploy.log,line=4,"}},
+		{"stdin", "-", "x", []string{"::error title=bsct::"}},
+		{"section", "-", "[db]\nport = 0", []string{"bad_content=\"[db]\\nport = 0\"\n"}},
+		{"leading quote", "-", `"quoted"`, []string{`bad_content="\"quoted\""` + "\n"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			reportCI(&buf, tc.file, &lib.Result{BadLineNumber: 4, BadLineContent: tc.content, Verified: true})
+			for _, want := range tc.want {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
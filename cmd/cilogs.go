@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// Job and run pages of the CI providers whose logs can be read directly
+var (
+	githubRunURL = regexp.MustCompile(`^https://([^/]+)/([^/]+/[^/]+)/actions/runs/(\d+)(?:/job/(\d+))?`)
+	gitlabJobURL = regexp.MustCompile(`^https://([^/]+)/(.+?)/-/jobs/(\d+)`)
+	circleJobURL = regexp.MustCompile(`^https://app\.circleci\.com/pipelines/([^/]+)/([^/]+)/([^/]+)/\d+/workflows/[^/]+/jobs/(\d+)`)
+)
+
+// isJobLogURL reports whether the input argument is the page of a CI job
+// whose log should be downloaded
+func isJobLogURL(arg string) bool {
+	return githubRunURL.MatchString(arg) || gitlabJobURL.MatchString(arg) || circleJobURL.MatchString(arg)
+}
+
+// readJobLog downloads the raw log behind a GitHub Actions, GitLab or CircleCI
+// job page, authenticating with the provider's usual token variable
+func readJobLog(page string) ([]string, error) {
+	var lines []string
+	var err error
+	switch {
+	case githubRunURL.MatchString(page):
+		lines, err = readGitHubLog(githubRunURL.FindStringSubmatch(page))
+	case gitlabJobURL.MatchString(page):
+		lines, err = readGitLabLog(gitlabJobURL.FindStringSubmatch(page))
+	default:
+		lines, err = readCircleLog(circleJobURL.FindStringSubmatch(page))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", page, err)
+	}
+	return lines, nil
+}
+
+// readGitHubLog reads one job's log, or the logs of every job in a run in
+// order when the URL names a whole run. GITHUB_TOKEN or GH_TOKEN is used if
+// set.
+func readGitHubLog(m []string) ([]string, error) {
+	host, repo, run, job := m[1], m[2], m[3], m[4]
+	api := "https://api.github.com"
+	if host != "github.com" {
+		api = "https://" + host + "/api/v3" // GitHub Enterprise Server
+	}
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if token := firstEnv("GITHUB_TOKEN", "GH_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	jobs := []string{job}
+	if job == "" {
+		jobs = nil
+		next := fmt.Sprintf("%s/repos/%s/actions/runs/%s/jobs?per_page=100", api, repo, run)
+		for next != "" {
+			var page struct {
+				Jobs []struct {
+					ID int64 `json:"id"`
+				} `json:"jobs"`
+			}
+			resp, err := ciGet(next, headers)
+			if err != nil {
+				return nil, err
+			}
+			err = json.NewDecoder(resp.Body).Decode(&page)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse job list: %w", err)
+			}
+			for _, j := range page.Jobs {
+				jobs = append(jobs, fmt.Sprint(j.ID))
+			}
+			next = nextLink(resp.Header.Get("Link"))
+		}
+	}
+
+	var lines []string
+	for _, id := range jobs {
+		// This redirects to the log itself, dropping the token on the way
+		jobLines, err := ciLines(fmt.Sprintf("%s/repos/%s/actions/jobs/%s/logs", api, repo, id), headers)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, jobLines...)
+	}
+	return lines, nil
+}
+
+// readGitLabLog reads a job's trace, using GITLAB_TOKEN, or CI_JOB_TOKEN
+// inside another job, if set
+func readGitLabLog(m []string) ([]string, error) {
+	host, project, job := m[1], m[2], m[3]
+	headers := map[string]string{}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	} else if token := os.Getenv("CI_JOB_TOKEN"); token != "" {
+		headers["JOB-TOKEN"] = token
+	}
+	return ciLines(fmt.Sprintf("https://%s/api/v4/projects/%s/jobs/%s/trace", host, url.PathEscape(project), job), headers)
+}
+
+// readCircleLog reads the output of every step of a job in order, using
+// CIRCLE_TOKEN if set
+func readCircleLog(m []string) ([]string, error) {
+	vcs, org, repo, job := m[1], m[2], m[3], m[4]
+	switch vcs {
+	case "github":
+		vcs = "gh"
+	case "bitbucket":
+		vcs = "bb"
+	}
+	headers := map[string]string{"Accept": "application/json"}
+	if token := os.Getenv("CIRCLE_TOKEN"); token != "" {
+		headers["Circle-Token"] = token
+	}
+
+	resp, err := ciGet(fmt.Sprintf("https://circleci.com/api/v1.1/project/%s/%s/%s/%s", vcs, org, repo, job), headers)
+	if err != nil {
+		return nil, err
+	}
+	var details struct {
+		Steps []struct {
+			Actions []struct {
+				OutputURL string `json:"output_url"`
+			} `json:"actions"`
+		} `json:"steps"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&details)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse job details: %w", err)
+	}
+
+	var out strings.Builder
+	for _, step := range details.Steps {
+		for _, action := range step.Actions {
+			if action.OutputURL == "" {
+				continue
+			}
+			// Output URLs are presigned, so they get no token
+			resp, err := ciGet(action.OutputURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			var messages []struct {
+				Message string `json:"message"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&messages)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse step output: %w", err)
+			}
+			for _, msg := range messages {
+				out.WriteString(msg.Message)
+			}
+		}
+	}
+	return lib.ReadLines(strings.NewReader(out.String()))
+}
+
+// ciLines streams the lines of a log download
+func ciLines(u string, headers map[string]string) ([]string, error) {
+	resp, err := ciGet(u, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return lib.ReadLines(resp.Body)
+}
+
+// ciGet requests u with headers and fails unless it succeeds
+func ciGet(u string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %s (is the provider's token set?)", resp.Status, strings.TrimSpace(string(body)))
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// nextLink returns the rel="next" URL of a Link header, if any
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
+
+// firstEnv returns the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLogURLs(t *testing.T) {
+	testCases := []struct {
+		url   string
+		match []string // Submatches of the provider's pattern, nil if no provider matches
+	}{
+		{"https://github.com/acme/api/actions/runs/9120/job/25001", []string{"github.com", "acme/api", "9120", "25001"}},
+		{"https://github.com/acme/api/actions/runs/9120", []string{"github.com", "acme/api", "9120", ""}},
+		{"https://ghe.acme.com/team/api/actions/runs/7/job/8", []string{"ghe.acme.com", "team/api", "7", "8"}},
+		{"https://gitlab.com/acme/group/api/-/jobs/6120344", []string{"gitlab.com", "acme/group/api", "6120344"}},
+		{"https://app.circleci.com/pipelines/github/acme/api/812/workflows/4f1c/jobs/2291", []string{"github", "acme", "api", "2291"}},
+		{"https://github.com/acme/api/pull/12", nil},
+		{"http://gitlab.com/acme/api/-/jobs/1", nil},
+		{"logs/build.txt", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			assert.Equal(t, tc.match != nil, isJobLogURL(tc.url))
+			if tc.match == nil {
+				return
+			}
+			var m []string
+			for _, re := range []*regexp.Regexp{githubRunURL, gitlabJobURL, circleJobURL} {
+				if m = re.FindStringSubmatch(tc.url); m != nil {
+					break
+				}
+			}
+			require.NotNil(t, m)
+			assert.Equal(t, tc.match, m[1:])
+		})
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{`<https://api.github.com/jobs?page=2>; rel="next", <https://api.github.com/jobs?page=5>; rel="last"`, "https://api.github.com/jobs?page=2"},
+		{`<https://api.github.com/jobs?page=1>; rel="prev", <https://api.github.com/jobs?page=3>; rel="next"`, "https://api.github.com/jobs?page=3"},
+		{`<https://api.github.com/jobs?page=1>; rel="first"`, ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, nextLink(tc.header), tc.header)
+	}
+}
+
+func TestCILines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("step 1\r\nstep 2\nERROR: failed\n"))
+	}))
+	defer server.Close()
+
+	lines, err := ciLines(server.URL, map[string]string{"PRIVATE-TOKEN": "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"step 1", "step 2", "ERROR: failed"}, lines)
+
+	_, err = ciLines(server.URL, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is the provider's token set?")
+}
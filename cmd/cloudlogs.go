@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cloudWatch  string
+	stackdriver string
+)
+
+// readCloudWatch reads the events of the --cloudwatch log group between
+// --since and --until, following filter-log-events pages to the end. Every
+// event is held in memory before the bisection starts, so --since and --until
+// should narrow large groups down.
+func readCloudWatch() ([]string, error) {
+	args := []string{"logs", "filter-log-events", "--log-group-name", cloudWatch, "--output", "json", "--no-paginate"}
+	if since != "" {
+		t, err := cloudTime(since)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--start-time", strconv.FormatInt(t.UnixMilli(), 10))
+	}
+	if until != "" {
+		t, err := cloudTime(until)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--end-time", strconv.FormatInt(t.UnixMilli(), 10))
+	}
+
+	var lines []string
+	token := ""
+	for {
+		page := args
+		if token != "" {
+			page = append(page[:len(page):len(page)], "--next-token", token)
+		}
+		out, err := runTool("aws", page...)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Events []struct {
+				Timestamp int64  `json:"timestamp"` // Milliseconds since the epoch
+				Message   string `json:"message"`
+			} `json:"events"`
+			NextToken string `json:"nextToken"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse filter-log-events output: %w", err)
+		}
+		for _, e := range resp.Events {
+			lines = append(lines, cloudLine(time.UnixMilli(e.Timestamp), e.Message))
+		}
+		// Pages can be empty before the end, so only a missing token ends it
+		if resp.NextToken == "" || resp.NextToken == token {
+			return lines, nil
+		}
+		token = resp.NextToken
+	}
+}
+
+// readStackdriver reads the Google Cloud Logging entries matching the
+// --stackdriver filter between --since and --until, oldest first
+func readStackdriver() ([]string, error) {
+	filter := "(" + stackdriver + ")"
+	if since != "" {
+		t, err := cloudTime(since)
+		if err != nil {
+			return nil, err
+		}
+		filter += fmt.Sprintf(` AND timestamp>="%s"`, t.UTC().Format(time.RFC3339Nano))
+	}
+	if until != "" {
+		t, err := cloudTime(until)
+		if err != nil {
+			return nil, err
+		}
+		filter += fmt.Sprintf(` AND timestamp<="%s"`, t.UTC().Format(time.RFC3339Nano))
+	}
+
+	out, err := runTool("gcloud", "logging", "read", filter, "--format=json", "--order=asc")
+	if err != nil {
+		return nil, err
+	}
+	var entries []struct {
+		Timestamp   time.Time       `json:"timestamp"`
+		TextPayload string          `json:"textPayload"`
+		JSONPayload json.RawMessage `json:"jsonPayload"`
+	}
+	if err := json.Unmarshal(out, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse gcloud logging output: %w", err)
+	}
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		msg := e.TextPayload
+		if msg == "" && len(e.JSONPayload) > 0 {
+			msg = string(e.JSONPayload)
+		}
+		lines = append(lines, cloudLine(e.Timestamp, msg))
+	}
+	return lines, nil
+}
+
+// cloudTime parses --since or --until for the cloud log adapters, either as a
+// duration before now or as an RFC 3339 time
+func cloudTime(spec string) (time.Time, error) {
+	if d, err := time.ParseDuration(spec); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, spec)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q: want a duration like 2h or an RFC 3339 time", errUsage, spec)
+	}
+	return t, nil
+}
+
+// cloudLine formats one log event as a single timestamped line
+func cloudLine(t time.Time, msg string) string {
+	msg = strings.TrimRight(msg, "\r\n")
+	msg = strings.ReplaceAll(msg, "\n", `\n`)
+	return t.UTC().Format(time.RFC3339Nano) + " " + msg
+}
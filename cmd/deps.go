@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// The dependency presets find which upgrade breaks the tests. Line 1 stands
+// for the dependencies as the manifest or lockfile has them, which is good;
+// every later line upgrades one more dependency, so the last line, everything
+// upgraded, is bad. The before hook puts the baseline back for every probe and
+// then applies the candidate's upgrades, so nothing carries over between
+// probes.
+
+// pipPreset bisects upgrading the requirements of a requirements.txt to their
+// newest versions. Every probe gets a fresh virtualenv with the file installed
+// as written; the candidate's requirements are then upgraded in it. Options
+// such as --index-url apply to the upgrades too.
+func pipPreset(args []string) (*presetSetup, error) {
+	data, err := presetFile(args, "requirements.txt")
+	if err != nil {
+		return nil, err
+	}
+	options, upgrades := pipRequirements(string(data))
+	requirements, err := filepath.Abs(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "bsct-pip-")
+	if err != nil {
+		return nil, err
+	}
+	venv := filepath.Join(dir, "venv")
+	upgrade := "--upgrade"
+	if len(options) > 0 {
+		path := filepath.Join(dir, "options.txt")
+		if err := os.WriteFile(path, []byte(strings.Join(options, "\n")+"\n"), 0644); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+		upgrade += " -r " + shellQuote(path)
+	}
+	pip := shellQuote(filepath.Join(venv, "bin", "pip"))
+	lines := baselineLines(args[0], upgrades)
+
+	return &presetSetup{
+		lines:  lines,
+		source: upgradeSource(lines),
+		before: fmt.Sprintf("rm -rf %s && python -m venv %s && %s install --quiet -r %s && if [ -s {file} ]; then %s install --quiet %s -r {file}; fi",
+			shellQuote(venv), shellQuote(venv), pip, shellQuote(requirements), pip, upgrade),
+		test:       "python -m pip install --quiet pytest && python -m pytest",
+		testPrefix: ". " + shellQuote(filepath.Join(venv, "bin", "activate")) + " && ",
+		cleanup:    func() error { return os.RemoveAll(dir) },
+	}, nil
+}
+
+// pipRequirement matches the name and extras at the start of a requirement
+var pipRequirement = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(\[[^\]]*\])?`)
+
+// pipRequirements splits a requirements.txt into its option lines and the
+// requirements it lists, without their version specifiers so installing them
+// with --upgrade picks the newest version. Environment markers are kept.
+// Requirements installed from a URL or path can't be upgraded and are left out.
+func pipRequirements(requirements string) (options, upgrades []string) {
+	for _, line := range strings.Split(requirements, "\n") {
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "-"):
+			options = append(options, line)
+		default:
+			spec, marker, _ := strings.Cut(line, ";")
+			name := pipRequirement.FindString(spec)
+			if name == "" || strings.HasPrefix(strings.TrimSpace(spec[len(name):]), "@") {
+				continue
+			}
+			if marker = strings.TrimSpace(marker); marker != "" {
+				name += "; " + marker
+			}
+			upgrades = append(upgrades, name)
+		}
+	}
+	return options, upgrades
+}
+
+// npmPreset bisects upgrading the dependencies of a package.json from the
+// versions in its package-lock.json to the newest ones its version ranges
+// allow. npm ci reinstalls the lockfile for every probe, and the candidate's
+// dependencies are then installed without touching package.json or the
+// lockfile.
+func npmPreset(args []string) (*presetSetup, error) {
+	data, err := presetFile(args, "package.json")
+	if err != nil {
+		return nil, err
+	}
+	upgrades, err := npmDependencies(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", args[0], err)
+	}
+	dir := filepath.Dir(args[0])
+	if _, err := os.Stat(filepath.Join(dir, "package-lock.json")); err != nil {
+		return nil, fmt.Errorf("--preset npm needs a package-lock.json next to %s for the versions to start from: %w", args[0], err)
+	}
+	lines := baselineLines(args[0], upgrades)
+
+	return &presetSetup{
+		lines:  lines,
+		source: upgradeSource(lines),
+		before: fmt.Sprintf("(cd %s && npm ci --silent) && if [ -s {file} ]; then (cd %s && xargs npm install --no-save --silent) < {file}; fi",
+			shellQuote(dir), shellQuote(dir)),
+		test: "npm test",
+	}, nil
+}
+
+// npmDependencies returns the dependencies and then the devDependencies of a
+// package.json as name@range, each sorted by name
+func npmDependencies(packageJSON []byte) ([]string, error) {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(packageJSON, &pkg); err != nil {
+		return nil, err
+	}
+
+	var deps []string
+	for _, group := range []map[string]string{pkg.Dependencies, pkg.DevDependencies} {
+		var names []string
+		for name := range group {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, name+"@"+group[name])
+		}
+	}
+	return deps, nil
+}
+
+// goPreset bisects upgrading the direct requirements of a go.mod to their
+// latest versions. go.mod and go.sum are put back before every probe and the
+// candidate's requirements are then upgraded with go get; the originals are
+// restored at the end.
+func goPreset(args []string) (*presetSetup, error) {
+	data, err := presetFile(args, "go.mod")
+	if err != nil {
+		return nil, err
+	}
+	var upgrades []string
+	for _, req := range goRequirements(string(data)) {
+		path, _, _ := strings.Cut(req, "@")
+		upgrades = append(upgrades, path+"@latest")
+	}
+
+	// Back up what go get will rewrite
+	dir, err := os.MkdirTemp("", "bsct-go-")
+	if err != nil {
+		return nil, err
+	}
+	backups := map[string][]byte{args[0]: data}
+	sum := filepath.Join(filepath.Dir(args[0]), "go.sum")
+	restore := []string{"cp " + shellQuote(filepath.Join(dir, "go.mod")) + " " + shellQuote(args[0])}
+	if sumData, err := os.ReadFile(sum); err == nil {
+		backups[sum] = sumData
+		restore = append(restore, "cp "+shellQuote(filepath.Join(dir, "go.sum"))+" "+shellQuote(sum))
+	} else if errors.Is(err, fs.ErrNotExist) {
+		restore = append(restore, "rm -f "+shellQuote(sum))
+	} else {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	lines := baselineLines(args[0], upgrades)
+	for path, name := range map[string]string{args[0]: "go.mod", sum: "go.sum"} {
+		if data, ok := backups[path]; ok {
+			if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+				os.RemoveAll(dir)
+				return nil, err
+			}
+		}
+	}
+
+	return &presetSetup{
+		lines:  lines,
+		source: upgradeSource(lines),
+		before: fmt.Sprintf("%s && if [ -s {file} ]; then (cd %s && xargs go get) < {file}; fi",
+			strings.Join(restore, " && "), shellQuote(filepath.Dir(args[0]))),
+		test: "go test ./...",
+		cleanup: func() error {
+			var errs []error
+			for path, data := range backups {
+				errs = append(errs, os.WriteFile(path, data, 0644))
+			}
+			errs = append(errs, os.RemoveAll(dir))
+			return errors.Join(errs...)
+		},
+	}, nil
+}
+
+// goRequirements returns the direct requirements in a go.mod as path@version.
+// Requirements marked // indirect are left out.
+func goRequirements(gomod string) []string {
+	var reqs []string
+	inBlock := false
+	for _, line := range strings.Split(gomod, "\n") {
+		line, comment, _ := strings.Cut(line, "//")
+		if strings.TrimSpace(comment) == "indirect" {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock && len(fields) == 2:
+			reqs = append(reqs, fields[0]+"@"+fields[1])
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require" && len(fields) == 3:
+			reqs = append(reqs, fields[1]+"@"+fields[2])
+		}
+	}
+	return reqs
+}
+
+// baselineLines puts a line standing for the unchanged dependencies of the
+// manifest at path ahead of its upgrades
+func baselineLines(path string, upgrades []string) []string {
+	return append([]string{filepath.Base(path) + " as it is"}, upgrades...)
+}
+
+// upgradeSource is a Source of baselineLines. Candidates list the upgrades to
+// apply on top of the baseline, one per line, so the first line writes
+// nothing.
+type upgradeSource lib.Lines
+
+// Len returns the number of lines, including the baseline
+func (u upgradeSource) Len() int { return len(u) }
+
+// Line returns line i
+func (u upgradeSource) Line(i int) (string, error) { return lib.Lines(u).Line(i) }
+
+// WriteLines writes the upgrades among lines from through to (inclusive) to w
+func (u upgradeSource) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if from == 0 {
+		if _, err := u.Line(to); err != nil {
+			return 0, err
+		}
+		if to == 0 {
+			return 0, nil
+		}
+		from = 1
+	}
+	return lib.Lines(u).WriteLines(w, from, to)
+}
+
+// presetFile reads the single file argument of a dependency preset
+func presetFile(args []string, kind string) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("--preset %s takes the path of a %s", preset, kind)
+	}
+	return os.ReadFile(args[0])
+}
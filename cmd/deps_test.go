@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoRequirements(t *testing.T) {
+	testCases := []struct {
+		name  string
+		gomod string
+		reqs  []string
+	}{
+		{"none", "module example.com/m\n\ngo 1.22\n", nil},
+		{"single", "module m\n\nrequire golang.org/x/text v0.14.0\n", []string{"golang.org/x/text@v0.14.0"}},
+		{
+			"block",
+			"module m\n\nrequire (\n\tgithub.com/a/b v1.2.3\n\tgithub.com/c/d v0.1.0 // pinned for CI\n)\n",
+			[]string{"github.com/a/b@v1.2.3", "github.com/c/d@v0.1.0"},
+		},
+		{
+			"indirect left out",
+			"module m\n\nrequire (\n\tgithub.com/a/b v1.2.3\n\tgithub.com/e/f v2.0.0 // indirect\n)\n\nrequire github.com/g/h v1.0.0 // indirect\n",
+			[]string{"github.com/a/b@v1.2.3"},
+		},
+		{"replace ignored", "module m\n\nreplace github.com/a/b => ../b\n", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reqs, goRequirements(tc.gomod))
+		})
+	}
+}
+
+func TestNpmDependencies(t *testing.T) {
+	deps, err := npmDependencies([]byte(`{
+		"name": "app",
+		"dependencies": {"react": "^18.2.0", "axios": "~1.6.0"},
+		"devDependencies": {"jest": "^29.0.0"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"axios@~1.6.0", "react@^18.2.0", "jest@^29.0.0"}, deps)
+
+	_, err = npmDependencies([]byte(`{"dependencies": [`))
+	assert.Error(t, err)
+}
+
+func TestPipRequirements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		options  []string
+		upgrades []string
+	}{
+		{"pinned", "requests==2.31.0\nflask>=2.0,<3\n", nil, []string{"requests", "flask"}},
+		{"comments", "# tools\nblack==23.1.0  # formatter\n\n", nil, []string{"black"}},
+		{"options", "--index-url https://pypi.example.com/simple\n-e ./local\nnumpy==1.26.0\n", []string{"--index-url https://pypi.example.com/simple", "-e ./local"}, []string{"numpy"}},
+		{"extras", "uvicorn[standard]==0.23.2\n", nil, []string{"uvicorn[standard]"}},
+		{"markers", "pywin32==306; sys_platform == 'win32'\n", nil, []string{"pywin32; sys_platform == 'win32'"}},
+		{"urls left out", "mypkg @ https://example.com/mypkg.tar.gz\n./vendored\nsix\n", nil, []string{"six"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, upgrades := pipRequirements(tc.input)
+			assert.Equal(t, tc.options, options)
+			assert.Equal(t, tc.upgrades, upgrades)
+		})
+	}
+}
+
+func TestUpgradeSource(t *testing.T) {
+	src := upgradeSource(baselineLines("go.mod", []string{"a@latest", "b@latest"}))
+	require.Equal(t, 3, src.Len())
+
+	line, err := src.Line(0)
+	require.NoError(t, err)
+	assert.Equal(t, "go.mod as it is", line)
+
+	testCases := []struct {
+		from, to int
+		want     string
+	}{
+		{0, 0, ""},
+		{0, 1, "a@latest\n"},
+		{0, 2, "a@latest\nb@latest\n"},
+		{2, 2, "b@latest\n"},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		n, err := src.WriteLines(&buf, tc.from, tc.to)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, buf.String())
+		assert.Equal(t, int64(len(tc.want)), n)
+	}
+
+	_, err = src.WriteLines(&bytes.Buffer{}, 0, 3)
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// dockerfileImage tags the image built from each --preset dockerfile candidate
+const dockerfileImage = "bsct-dockerfile-probe"
+
+// dockerfilePreset bisects the instructions of a Dockerfile after its first
+// FROM, one instruction per line. Each candidate is built in the Dockerfile's
+// directory as part of the test, so a failing build is bad too, and the test
+// command, if you don't give one, runs the image's default command.
+func dockerfilePreset(args []string) (*presetSetup, error) {
+	data, err := presetFile(args, "Dockerfile")
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := dockerfileInstructions(string(data))
+	base := -1
+	for i, inst := range instructions {
+		if keyword, _, _ := strings.Cut(inst, " "); strings.EqualFold(keyword, "FROM") {
+			base = i
+			break
+		}
+	}
+	if base < 0 {
+		return nil, fmt.Errorf("%s has no FROM instruction", args[0])
+	}
+
+	// Parser directives, global ARGs and the first FROM start every candidate
+	header := strings.Join(instructions[:base+1], "\n") + "\n"
+	lines := instructions[base+1:]
+	build := fmt.Sprintf("docker build --quiet --tag %s --file {file} %s > /dev/null && ",
+		dockerfileImage, shellQuote(filepath.Dir(args[0])))
+
+	return &presetSetup{
+		lines:      lines,
+		source:     lib.Framed(lib.Lines(lines), header, ""),
+		test:       "docker run --rm " + dockerfileImage,
+		testPrefix: build,
+		cleanup: func() error {
+			// No candidate may have built, so there may be nothing to remove
+			runTool("docker", "image", "rm", "--force", dockerfileImage)
+			return nil
+		},
+	}, nil
+}
+
+// dockerfileInstructions splits a Dockerfile into instructions, joining
+// continuation lines and dropping comments. Parser directives like
+// "# syntax=..." at the very top are kept since they change how it builds.
+func dockerfileInstructions(dockerfile string) []string {
+	var instructions []string
+	var current strings.Builder
+	directives := true
+	for _, line := range strings.Split(dockerfile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if directives && strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, "=") {
+			instructions = append(instructions, trimmed)
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		directives = false
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if rest, ok := strings.CutSuffix(trimmed, `\`); ok {
+			current.WriteString(strings.TrimSpace(rest) + " ")
+			continue
+		}
+		current.WriteString(trimmed)
+		instructions = append(instructions, current.String())
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		instructions = append(instructions, strings.TrimSpace(current.String()))
+	}
+	return instructions
+}
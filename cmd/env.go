@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// envPreset bisects the variables of a .env file. Candidates are sourced into
+// the test command's environment rather than being read as a file, so the
+// culprit is the variable whose presence breaks the application.
+func envPreset(args []string) (*presetSetup, error) {
+	data, err := presetFile(args, ".env file")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		if key, _, ok := strings.Cut(line, "="); !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%s: %q is not a KEY=VALUE line", args[0], line)
+		}
+		lines = append(lines, line)
+	}
+
+	return &presetSetup{
+		lines:      lines,
+		source:     envSource(lines),
+		testPrefix: ". {file} && ",
+	}, nil
+}
+
+// envSource is a Source of KEY=VALUE lines whose candidates are written as
+// shell exports, so values with spaces or quotes survive being sourced
+type envSource lib.Lines
+
+// Len returns the number of variables
+func (e envSource) Len() int { return len(e) }
+
+// Line returns variable i as it appeared in the .env file
+func (e envSource) Line(i int) (string, error) { return lib.Lines(e).Line(i) }
+
+// WriteLines writes variables from through to (inclusive) to w as exports
+func (e envSource) WriteLines(w io.Writer, from, to int) (int64, error) {
+	exports := make(lib.Lines, 0, max(to-from+1, 0))
+	for i := from; i <= to; i++ {
+		line, err := e.Line(i)
+		if err != nil {
+			return 0, err
+		}
+		key, value, _ := strings.Cut(line, "=")
+		exports = append(exports, "export "+strings.TrimSpace(key)+"="+shellQuote(unquote(strings.TrimSpace(value))))
+	}
+	return exports.WriteLines(w, 0, len(exports)-1)
+}
+
+// unquote strips one pair of matching quotes around a .env value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
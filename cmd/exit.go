@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// Exit codes returned by bsct besides 0 for a completed bisection
+const (
+	exitFailure     = 1   // Any error not listed below
+	exitUsage       = 2   // Bad input or boundaries
+	exitTestCommand = 3   // The test command could not produce a verdict
+	exitTimeout     = 124 // --timeout elapsed, like timeout(1) reports
+	exitInterrupted = 130 // Stopped by a signal, like a shell reports SIGINT
+)
+
+// errUsage marks errors in how bsct was invoked
+var errUsage = errors.New("invalid usage")
+
+// ExitCode maps an error returned by Execute to the process exit code, so
+// scripts can tell why a bisection didn't finish
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, lib.ErrInterrupted) && errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, lib.ErrInterrupted):
+		return exitInterrupted
+	case errors.Is(err, lib.ErrTestCommandFailed), errors.Is(err, lib.ErrProbeTimeout):
+		return exitTestCommand
+	case errors.Is(err, errUsage),
+		errors.Is(err, lib.ErrNoInput),
+		errors.Is(err, lib.ErrBadBeforeGood),
+		errors.Is(err, lib.ErrGoodBeforeBad),
+		errors.Is(err, lib.ErrPatternNotFound):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
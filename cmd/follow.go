@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+// follow keeps reading the input as it grows until a --bad line appears
+var follow bool
+
+// followInput reads the input file, or stdin without one, until its first
+// line matching --bad, waiting for lines to be appended like tail -f while
+// none does yet. Lines after the bad one aren't read, as they can't be bisected.
+func followInput(cmd *cobra.Command, args []string) ([]string, bool, error) {
+	if badPattern == "" {
+		return nil, false, fmt.Errorf("%w: --follow needs --bad to know when to stop reading", errUsage)
+	}
+	isBad := func(line string) bool { return strings.Contains(line, badPattern) }
+	if usePatternRE {
+		re, err := regexp.Compile(badPattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: invalid --bad: %v", errUsage, err)
+		}
+		isBad = re.MatchString
+	}
+
+	name, r, usingStdin := "stdin", io.Reader(os.Stdin), true
+	if len(args) > 0 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+		name, r, usingStdin = args[0], file, false
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	lines, idle := make(chan string), make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() { done <- tailLines(ctx, r, !usingStdin, lines, idle) }()
+
+	var read []string
+	waited := false
+	for {
+		select {
+		case line := <-lines:
+			read = append(read, line)
+			if isBad(line) {
+				if waited {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Line %d of %s matches --bad, bisecting\n", len(read), name)
+				}
+				return read, usingStdin, nil
+			}
+		case <-idle:
+			if !waited {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Read %d lines of %s, waiting for one matching --bad...\n", len(read), name)
+				waited = true
+			}
+		case err := <-done:
+			if err == nil {
+				err = fmt.Errorf("%s ended before a line matched --bad", name)
+			}
+			return nil, false, err
+		case <-cmd.Context().Done():
+			return nil, false, fmt.Errorf("%w: %w", lib.ErrInterrupted, cmd.Context().Err())
+		}
+	}
+}
+
+// tailLines sends the lines of r to lines until ctx is done. At the end of a
+// file that can grow it polls for more rather than stopping; a line still
+// being written is held back until its newline arrives. Reaching the end of
+// what has been written so far is signalled on idle.
+func tailLines(ctx context.Context, r io.Reader, grows bool, lines chan<- string, idle chan<- struct{}) error {
+	br := bufio.NewReader(r)
+	var partial string
+	for {
+		chunk, err := br.ReadString('\n')
+		partial += chunk
+		if err == nil {
+			select {
+			case lines <- strings.TrimSuffix(strings.TrimSuffix(partial, "\n"), "\r"):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			partial = ""
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !grows {
+			if partial != "" {
+				select {
+				case lines <- partial:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}
+		select {
+		case idle <- struct{}{}:
+		default:
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchPoll):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalEntry holds the journalctl JSON fields used to render a line
+type journalEntry struct {
+	Timestamp  string          `json:"__REALTIME_TIMESTAMP"` // Microseconds since the epoch
+	Hostname   string          `json:"_HOSTNAME"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+	Comm       string          `json:"_COMM"`
+	PID        string          `json:"_PID"`
+	Message    json.RawMessage `json:"MESSAGE"`
+}
+
+// readJournal reads the entries selected by --journal, --since, --until and
+// --boots as one plain-text line each
+func readJournal() ([]string, error) {
+	args := []string{"--output=json", "--no-pager", "--quiet"}
+	if since != "" {
+		args = append(args, "--since", since)
+	}
+	if until != "" {
+		args = append(args, "--until", until)
+	}
+	if boots != "" {
+		first, last, err := bootRange(boots)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--since", first, "--until", last)
+	}
+	if journal != "all" {
+		args = append(args, strings.Fields(journal)...)
+	}
+
+	out, err := runTool("journalctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journalctl output: %w", err)
+		}
+		lines = append(lines, entry.String())
+	}
+	return lines, scanner.Err()
+}
+
+// String renders the entry like journalctl -o short-iso, on a single line
+func (e journalEntry) String() string {
+	ident := e.Identifier
+	if ident == "" {
+		ident = e.Comm
+	}
+	if e.PID != "" {
+		ident += "[" + e.PID + "]"
+	}
+	msg := strings.ReplaceAll(journalMessage(e.Message), "\n", `\n`)
+	return fmt.Sprintf("%s %s %s: %s", journalTime(e.Timestamp), e.Hostname, ident, msg)
+}
+
+// journalTime formats a __REALTIME_TIMESTAMP
+func journalTime(usec string) string {
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil {
+		return usec
+	}
+	return time.UnixMicro(n).UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// journalMessage decodes MESSAGE, which journalctl writes as an array of
+// bytes when it isn't valid UTF-8
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, n := range ints {
+			b = append(b, byte(n))
+		}
+		return strings.ToValidUTF8(string(b), "�")
+	}
+	return ""
+}
+
+// bootRange resolves a range of boots like -3..0 to the time of the first
+// boot's first entry and the last boot's last entry, as journalctl timestamps
+func bootRange(spec string) (string, string, error) {
+	firstSpec, lastSpec, ok := strings.Cut(spec, "..")
+	if !ok {
+		lastSpec = firstSpec
+	}
+
+	out, err := runTool("journalctl", "--list-boots", "--output=json", "--no-pager")
+	if err != nil {
+		return "", "", err
+	}
+	var list []struct {
+		Index      int    `json:"index"`
+		BootID     string `json:"boot_id"`
+		FirstEntry int64  `json:"first_entry"`
+		LastEntry  int64  `json:"last_entry"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return "", "", fmt.Errorf("failed to parse journalctl --list-boots: %w", err)
+	}
+
+	find := func(spec string) (int, error) {
+		for i, boot := range list {
+			if boot.BootID == spec || strconv.Itoa(boot.Index) == spec {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("no boot %q in the journal", spec)
+	}
+	first, err := find(firstSpec)
+	if err != nil {
+		return "", "", err
+	}
+	last, err := find(lastSpec)
+	if err != nil {
+		return "", "", err
+	}
+	if list[first].FirstEntry > list[last].LastEntry {
+		return "", "", fmt.Errorf("--boots %s: boot %s comes after boot %s", spec, firstSpec, lastSpec)
+	}
+
+	// journalctl reads @ as seconds since the epoch
+	stamp := func(usec int64) string { return fmt.Sprintf("@%d", usec/1_000_000) }
+	return stamp(list[first].FirstEntry), stamp(list[last].LastEntry + 1_000_000), nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalEntry_String(t *testing.T) {
+	testCases := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			"identifier and pid",
+			`{"__REALTIME_TIMESTAMP":"1714557600123456","_HOSTNAME":"web-1","SYSLOG_IDENTIFIER":"nginx","_PID":"812","MESSAGE":"started"}`,
+			"2024-05-01T10:00:00.123456Z web-1 nginx[812]: started",
+		},
+		{
+			"command without identifier",
+			`{"__REALTIME_TIMESTAMP":"1714557600000000","_HOSTNAME":"web-1","_COMM":"sshd","MESSAGE":"accepted"}`,
+			"2024-05-01T10:00:00.000000Z web-1 sshd: accepted",
+		},
+		{
+			"multi-line message",
+			`{"__REALTIME_TIMESTAMP":"1714557600000000","_HOSTNAME":"web-1","SYSLOG_IDENTIFIER":"app","MESSAGE":"panic\ngoroutine 1"}`,
+			`2024-05-01T10:00:00.000000Z web-1 app: panic\ngoroutine 1`,
+		},
+		{
+			"binary message",
+			`{"__REALTIME_TIMESTAMP":"1714557600000000","_HOSTNAME":"web-1","SYSLOG_IDENTIFIER":"app","MESSAGE":[104,105,255]}`,
+			"2024-05-01T10:00:00.000000Z web-1 app: hi�",
+		},
+		{
+			"bad timestamp kept as is",
+			`{"__REALTIME_TIMESTAMP":"soon","_HOSTNAME":"web-1","SYSLOG_IDENTIFIER":"app","MESSAGE":"x"}`,
+			"soon web-1 app: x",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var entry journalEntry
+			require.NoError(t, json.Unmarshal([]byte(tc.json), &entry))
+			assert.Equal(t, tc.want, entry.String())
+		})
+	}
+}
+
+func TestJournalMessage(t *testing.T) {
+	testCases := []struct {
+		raw  string
+		want string
+	}{
+		{`"plain"`, "plain"},
+		{`[65,66,67]`, "ABC"},
+		{`null`, ""},
+		{`{"unexpected":true}`, ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, journalMessage(json.RawMessage(tc.raw)), tc.raw)
+	}
+}
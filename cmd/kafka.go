@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	kafkaTopic   string
+	kafkaBrokers string
+	kafkaOffsets string
+	kafkaFraming string
+)
+
+// readKafka consumes the --kafka partition between --offsets with kcat. Each
+// line is a message; candidates hold the message payloads up to the probe,
+// framed as --kafka-framing says, and the lines shown in reports lead with the
+// message offset.
+func readKafka() ([]string, lib.Source, error) {
+	if kafkaBrokers == "" {
+		return nil, nil, fmt.Errorf("%w: --kafka needs --brokers", errUsage)
+	}
+	switch kafkaFraming {
+	case "newline", "nul", "json":
+	default:
+		return nil, nil, fmt.Errorf("%w: invalid --kafka-framing %q: must be newline, nul or json", errUsage, kafkaFraming)
+	}
+	topic, partition, _ := strings.Cut(kafkaTopic, ":")
+	if partition == "" {
+		partition = "0"
+	}
+
+	args := []string{"-C", "-b", kafkaBrokers, "-t", topic, "-p", partition, "-e", "-q", "-J"}
+	if kafkaOffsets != "" {
+		first, last, err := parseOffsets(kafkaOffsets)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, "-o", strconv.FormatInt(first, 10))
+		if last >= 0 {
+			args = append(args, "-c", strconv.FormatInt(last-first+1, 10))
+		}
+	} else {
+		args = append(args, "-o", "beginning")
+	}
+
+	out, err := runTool("kcat", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src := kafkaSource{framing: kafkaFraming}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Offset  int64   `json:"offset"`
+			Payload *string `json:"payload"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse kcat output: %w", err)
+		}
+		payload := ""
+		if msg.Payload != nil {
+			payload = *msg.Payload
+		}
+		src.offsets = append(src.offsets, msg.Offset)
+		src.payloads = append(src.payloads, payload)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	lines := make([]string, src.Len())
+	for i := range lines {
+		lines[i], _ = src.Line(i)
+	}
+	return lines, src, nil
+}
+
+// parseOffsets parses an inclusive first..last offset range; last is -1 when
+// omitted, meaning the end of the partition
+func parseOffsets(spec string) (int64, int64, error) {
+	firstStr, lastStr, _ := strings.Cut(spec, "..")
+	first, err := strconv.ParseInt(firstStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: invalid --offsets %q: want first..last", errUsage, spec)
+	}
+	if lastStr == "" {
+		return first, -1, nil
+	}
+	last, err := strconv.ParseInt(lastStr, 10, 64)
+	if err != nil || last < first {
+		return 0, 0, fmt.Errorf("%w: invalid --offsets %q: want first..last", errUsage, spec)
+	}
+	return first, last, nil
+}
+
+// kafkaSource is a Source of messages whose lines read "offset: payload" but
+// whose candidates hold only the payloads, byte for byte
+type kafkaSource struct {
+	offsets  []int64
+	payloads lib.Lines
+	framing  string // How payloads are separated: newline, nul or json
+}
+
+// Len returns the number of messages
+func (k kafkaSource) Len() int { return len(k.payloads) }
+
+// Line returns message i with its offset. Newlines in the payload are shown
+// escaped so the message stays on one line.
+func (k kafkaSource) Line(i int) (string, error) {
+	payload, err := k.payloads.Line(i)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d: %s", k.offsets[i], strings.ReplaceAll(payload, "\n", `\n`)), nil
+}
+
+// WriteLines writes the payloads of messages from through to (inclusive),
+// each followed by a newline, each followed by a NUL byte, or each as a JSON
+// string on its own line
+func (k kafkaSource) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if from < 0 || to >= k.Len() || from > to {
+		return 0, fmt.Errorf("line range %d-%d out of range for %d lines", from, to, k.Len())
+	}
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, payload := range k.payloads[from : to+1] {
+		var framed string
+		switch k.framing {
+		case "nul":
+			framed = payload + "\x00"
+		case "json":
+			encoded, _ := json.Marshal(payload)
+			framed = string(encoded) + "\n"
+		default:
+			framed = payload + "\n"
+		}
+		written, err := bw.WriteString(framed)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaSource(t *testing.T) {
+	payloads := lib.Lines{`{"id":1}`, "line one\nline two", ""}
+
+	testCases := []struct {
+		framing string
+		want    string
+	}{
+		{"newline", "{\"id\":1}\nline one\nline two\n\n"},
+		{"nul", "{\"id\":1}\x00line one\nline two\x00\x00"},
+		{"json", "\"{\\\"id\\\":1}\"\n\"line one\\nline two\"\n\"\"\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.framing, func(t *testing.T) {
+			src := kafkaSource{offsets: []int64{40, 41, 42}, payloads: payloads, framing: tc.framing}
+
+			var buf bytes.Buffer
+			n, err := src.WriteLines(&buf, 0, 2)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, buf.String())
+			assert.Equal(t, int64(buf.Len()), n)
+
+			// Payloads are only escaped for display
+			line, err := src.Line(1)
+			require.NoError(t, err)
+			assert.Equal(t, `41: line one\nline two`, line)
+
+			_, err = src.WriteLines(&buf, 1, 3)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseOffsets(t *testing.T) {
+	first, last, err := parseOffsets("1000..2000")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1000, 2000}, []int64{first, last})
+
+	first, last, err = parseOffsets("1000..")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1000, -1}, []int64{first, last})
+
+	for _, spec := range []string{"", "x..2", "20..10", "1..y"} {
+		_, _, err := parseOffsets(spec)
+		assert.ErrorIs(t, err, errUsage, spec)
+	}
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+// replayed is the log bsct replay is applying, if any
+var replayed *replayLog
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Print the decisions of the last bisection",
+	Long: `log prints the bisect log of the last bisection: the invocation that started
+it and every decision made, with the line, verdict and time. Save it to a file,
+edit out mistakes if need be, and apply it again with bsct replay.`,
+	Args: cobra.NoArgs,
+	RunE: printLog,
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <logfile>",
+	Short: "Apply the decisions of a bisect log again",
+	Long: `replay starts the bisection recorded in a log written by bsct log again, with
+the same arguments, in the directory it was started from, and applies its
+decisions without testing or asking about their lines. The bisection then
+continues from there if the log didn't finish it. The input must not have
+changed since. Passwords in URLs and --webhook URLs are redacted in the log;
+put them back, or remove them and pass passwords in the environment, e.g.
+PGPASSWORD.`,
+	Args: cobra.ExactArgs(1),
+	RunE: replay,
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd, replayCmd)
+}
+
+// logPath returns where the last bisection's log is kept
+func logPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bsct", "bisect.log"), nil
+}
+
+func printLog(cmd *cobra.Command, args []string) error {
+	path, err := logPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.New("no bisection has been logged yet")
+	} else if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func replay(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	log, err := parseLog(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+	log.path = args[0]
+	if log.dir != "" {
+		if err := os.Chdir(log.dir); err != nil {
+			return fmt.Errorf("failed to return to the log's directory: %w", err)
+		}
+	}
+
+	if err := rootCmd.ParseFlags(log.args); err != nil {
+		return fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+	replayed = log
+	fmt.Fprintf(cmd.ErrOrStderr(), "Replaying %s: bsct %s\n", args[0], strings.Join(log.args, " "))
+	return run(rootCmd, rootCmd.Flags().Args())
+}
+
+// logEntry is a decision in a bisect log
+type logEntry struct {
+	time time.Time
+	step lib.Step
+}
+
+// replayLog is a bisect log read back by bsct replay
+type replayLog struct {
+	path    string
+	args    []string // Command-line arguments, without the program name
+	dir     string   // Working directory the arguments are relative to
+	sha256  string   // Of the input, if it was recorded
+	entries []logEntry
+}
+
+// parseLog reads a bisect log. Comments record the invocation and input, and
+// every other line is a decision like "2026-01-02T15:04:05Z line 42 good".
+func parseLog(r io.Reader) (*replayLog, error) {
+	log := &replayLog{}
+	hasArgs := false
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			comment = strings.TrimSpace(comment)
+			if args, ok := strings.CutPrefix(comment, "args: "); ok {
+				if err := json.Unmarshal([]byte(args), &log.args); err != nil {
+					return nil, fmt.Errorf("line %d: invalid args: %w", n, err)
+				}
+				hasArgs = true
+			} else if dir, ok := strings.CutPrefix(comment, "dir: "); ok {
+				log.dir = dir
+			} else if input, ok := strings.CutPrefix(comment, "input: "); ok {
+				if _, sum, ok := strings.Cut(input, "sha256 "); ok {
+					log.sha256 = sum
+				}
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		decision, _, _ := strings.Cut(line, "#")
+		fields := strings.Fields(decision)
+		if len(fields) != 4 || fields[1] != "line" {
+			return nil, fmt.Errorf("line %d: %q isn't a decision like \"2026-01-02T15:04:05Z line 42 good\"", n, line)
+		}
+		t, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time: %w", n, err)
+		}
+		number, err := strconv.Atoi(fields[2])
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("line %d: %q is not a line number", n, fields[2])
+		}
+		var v lib.Verdict
+		if err := v.UnmarshalText([]byte(fields[3])); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		log.entries = append(log.entries, logEntry{time: t, step: lib.Step{Index: number - 1, Verdict: v}})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !hasArgs {
+		return nil, errors.New("no \"# args:\" line; is it a bisect log?")
+	}
+	return log, nil
+}
+
+// apply checks that src is the input the log was written for and applies its
+// decisions to bisector
+func (l *replayLog) apply(bisector lib.Bisector, src lib.Source, errOut io.Writer) error {
+	replayer, ok := bisector.(interface{ Replay(steps []lib.Step) error })
+	if !ok {
+		return fmt.Errorf("%w: bsct replay can't replay --property bisections", errUsage)
+	}
+	if l.sha256 != "" {
+		_, sum, err := fingerprint(src)
+		if err != nil {
+			return err
+		}
+		if sum != l.sha256 {
+			return fmt.Errorf("the input changed since %s was logged", l.path)
+		}
+	}
+	steps := make([]lib.Step, len(l.entries))
+	for i, e := range l.entries {
+		steps[i] = e.step
+	}
+	if err := replayer.Replay(steps); err != nil {
+		return fmt.Errorf("failed to replay %s: %w", l.path, err)
+	}
+	fmt.Fprintf(errOut, "Replayed %d decisions from %s\n", len(steps), l.path)
+	return nil
+}
+
+// bisectLog records the decisions of a bisection as they are made, for bsct
+// log to print and bsct replay to apply again, like git bisect log
+type bisectLog struct {
+	path     string
+	header   string
+	bisector interface{ History() []lib.Step }
+	entries  []logEntry
+	errOut   io.Writer
+	failed   bool // Whether writing the log failed already, which is warned about once
+}
+
+func newBisectLog(errOut io.Writer) *bisectLog {
+	return &bisectLog{errOut: errOut}
+}
+
+// start records the invocation and input of bisector's bisection of src and
+// writes the log, seeded with the decisions being replayed, if any. Bisectors
+// that keep no single history, such as --property ones, aren't logged.
+func (l *bisectLog) start(bisector lib.Bisector, src lib.Source) {
+	historian, ok := bisector.(interface{ History() []lib.Step })
+	if !ok {
+		return
+	}
+	path, err := logPath()
+	if err != nil {
+		l.warn(err)
+		return
+	}
+	args, err := json.Marshal(redactArgs(invocationArgs()))
+	if err != nil {
+		l.warn(err)
+		return
+	}
+	dir, _ := os.Getwd()
+	_, sum, err := fingerprint(src)
+	if err != nil {
+		l.warn(err)
+		return
+	}
+
+	var header strings.Builder
+	fmt.Fprintln(&header, "# bsct bisect log; apply it again with: bsct replay <file>")
+	fmt.Fprintf(&header, "# args: %s\n", args)
+	fmt.Fprintf(&header, "# dir: %s\n", dir)
+	fmt.Fprintf(&header, "# input: %d lines", src.Len())
+	if sum != "" {
+		fmt.Fprintf(&header, ", sha256 %s", sum)
+	}
+	fmt.Fprintln(&header)
+	l.path, l.header, l.bisector = path, header.String(), historian
+	if replayed != nil {
+		l.entries = replayed.entries
+	}
+	l.update()
+}
+
+// observer updates the log whenever a verdict narrows the range
+func (l *bisectLog) observer() lib.Observer {
+	return lib.Observer{OnRangeNarrowed: func(goodIdx, badIdx int) { l.update() }}
+}
+
+// update brings the log in line with the bisector's history, keeping the
+// times of decisions already logged, and writes it. Decisions taken back,
+// such as with undo, are dropped.
+func (l *bisectLog) update() {
+	if l.bisector == nil || l.failed {
+		return
+	}
+	history := l.bisector.History()
+	kept := 0
+	for kept < len(l.entries) && kept < len(history) && l.entries[kept].step == history[kept] {
+		kept++
+	}
+	l.entries = l.entries[:kept:kept]
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, step := range history[kept:] {
+		l.entries = append(l.entries, logEntry{time: now, step: step})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(l.header)
+	for _, e := range l.entries {
+		fmt.Fprintf(&buf, "%s line %d %s\n", e.time.Format(time.RFC3339), e.step.Index+1, e.step.Verdict)
+	}
+	err := os.MkdirAll(filepath.Dir(l.path), 0o700)
+	if err == nil {
+		err = os.WriteFile(l.path, buf.Bytes(), 0o600)
+	}
+	if err != nil {
+		l.warn(err)
+	}
+}
+
+func (l *bisectLog) warn(err error) {
+	l.failed = true
+	fmt.Fprintf(l.errOut, "Warning: failed to write the bisect log: %v\n", err)
+}
+
+// invocationArgs returns the command-line arguments of the bisection being
+// run, which bsct resume and bsct replay take from where they were saved
+func invocationArgs() []string {
+	switch {
+	case resumed != nil:
+		return resumed.Args
+	case replayed != nil:
+		return replayed.args
+	}
+	return os.Args[1:]
+}
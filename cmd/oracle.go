@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	retries      int
+	retryMode    string
+	retryBackoff time.Duration
+	testHTTP     string
+	expectStatus int
+	expectBody   string
+	httpUpload   bool
+	testTCP      string
+	tcpTimeout   time.Duration
+	testExists   string
+	nonEmpty     bool
+	expectStdout string
+	expectStderr string
+	expectExit   string
+	expectJSON   []string
+	matchMode    string
+	combineMode  string
+	invert       bool
+	webhook      string
+	webhookAddr  string
+	webhookURL   string
+	probeTimeout time.Duration
+	onExecError  string
+	onCrash      string
+	maxOutput    string
+	answersFIFO  string
+)
+
+// addOracleFlags registers the flags that choose how lines are judged
+func addOracleFlags() {
+	rootCmd.Flags().IntVar(&retries, "retries", 0, "Extra attempts for each test, to absorb flaky test commands")
+	rootCmd.Flags().StringVar(&retryMode, "retry-mode", "error", "How --retries are used: error (retry commands that fail to run), bad (good if any attempt passes) or majority (majority vote)")
+	rootCmd.Flags().DurationVar(&retryBackoff, "retry-backoff", 0, "Wait before the first retry, doubling before each one after")
+	rootCmd.Flags().StringVar(&testHTTP, "test-http", "", "URL to request for automatic testing (expected status = good). Supports {file} and {line} placeholders")
+	rootCmd.Flags().IntVar(&expectStatus, "expect-status", 200, "Status code --test-http expects from a good line")
+	rootCmd.Flags().StringVar(&expectBody, "expect-body", "", "Regular expression the --test-http response body must match for a good line")
+	rootCmd.Flags().BoolVar(&httpUpload, "http-upload", false, "POST the candidate content to --test-http instead of only referencing it")
+	rootCmd.Flags().StringVar(&testTCP, "test-tcp", "", "host:port that must accept TCP connections for a good line, e.g. after --before restarts a daemon")
+	rootCmd.Flags().DurationVar(&tcpTimeout, "tcp-timeout", 10*time.Second, "How long --test-tcp waits for the port to come up")
+	rootCmd.Flags().StringVar(&testExists, "test-exists", "", "Path or glob that must exist after --before for a good line. Supports {file} and {line} placeholders")
+	rootCmd.Flags().BoolVar(&nonEmpty, "non-empty", false, "Require --test-exists to match a non-empty file or directory")
+	rootCmd.Flags().StringVar(&expectStdout, "expect-stdout", "", "Regular expression the --test command's stdout must match for a good line")
+	rootCmd.Flags().StringVar(&expectStderr, "expect-stderr", "", "Regular expression the --test command's stderr must match for a good line")
+	rootCmd.Flags().StringVar(&expectExit, "expect-exit", "", "Exit code or range (e.g. 0 or 0-2) of the --test command for a good line")
+	rootCmd.Flags().StringArrayVar(&expectJSON, "expect-json", nil, "path=value the --test command's JSON stdout must contain for a good line, e.g. items.0.status=ok. May be repeated")
+	rootCmd.Flags().StringArrayVar(&propertySpecs, "property", nil, "name=command to find the first bad line of, like --test, in place of --test. May be repeated to bisect several properties in one session, where each candidate is set up by --before once and tested for every property")
+	rootCmd.Flags().StringVar(&combineMode, "combine", "all", "Whether all or any of --test, --test-http, --test-tcp, --test-exists, --webhook and --answers-fifo must pass when several are given")
+	rootCmd.Flags().BoolVar(&invert, "invert", false, "Swap good and bad, e.g. to find the first line where a problem went away")
+	rootCmd.Flags().DurationVar(&probeTimeout, "probe-timeout", 0, "Give up on a test that runs longer than this, e.g. 5m; each retry gets its own limit")
+	rootCmd.Flags().StringVar(&webhook, "webhook", "", "Post each probe to this Slack or Teams incoming webhook and wait for someone to follow its good or bad link")
+	rootCmd.Flags().StringVar(&webhookAddr, "webhook-listen", ":8090", "Address the --webhook links are served on")
+	rootCmd.Flags().StringVar(&webhookURL, "webhook-callback", "", "Public base URL that reaches --webhook-listen, if the links need one, e.g. https://bsct.example.com")
+	rootCmd.Flags().StringVar(&answersFIFO, "answers-fifo", "", "Let another program judge lines through the named pipes PATH.probes, where each probe is written as a line of JSON, and PATH.verdicts, where it answers good, bad or skip. They are created if needed")
+	rootCmd.Flags().StringVar(&onExecError, "on-exec-error", "abort", "What to do when the test command can't run at all, e.g. exit 127 for command not found or 126 for not executable: abort, skip (test a neighbouring line instead) or bad")
+	rootCmd.Flags().StringVar(&onCrash, "on-crash", "bad", "What to do when the test command is killed by a signal such as SIGSEGV or the OOM killer's SIGKILL: bad, skip (test a neighbouring line instead) or abort")
+	rootCmd.Flags().StringVar(&maxOutput, "max-output-bytes", "64M", "Keep at most this much of the --test command's stdout and of its stderr for the --expect conditions, half from the start and half from the end, e.g. 1G. 0 keeps it all")
+	rootCmd.Flags().StringVar(&matchMode, "match", "all", "Whether all or any of the --expect-stdout, --expect-stderr, --expect-exit and --expect-json conditions make a line good")
+}
+
+// buildOracle returns the oracle described by the test flags, or nil for
+// interactive mode. Several test flags are combined with --combine.
+func buildOracle(runner lib.Runner) (lib.Oracle, error) {
+	var oracles []lib.Oracle
+
+	if testCommand != "" {
+		o, err := commandOracle(testCommand, runner)
+		if err != nil {
+			return nil, err
+		}
+		oracles = append(oracles, o)
+	}
+	if testHTTP != "" {
+		o := &lib.HTTPOracle{URL: testHTTP, Upload: httpUpload, ExpectStatus: expectStatus}
+		if expectBody != "" {
+			pattern, err := regexp.Compile(expectBody)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --expect-body: %w", err)
+			}
+			o.BodyPattern = pattern
+		}
+		oracles = append(oracles, o)
+	}
+	if testTCP != "" {
+		oracles = append(oracles, &lib.TCPOracle{Address: testTCP, Timeout: tcpTimeout})
+	}
+	if testExists != "" {
+		oracles = append(oracles, &lib.ExistsOracle{Pattern: testExists, NonEmpty: nonEmpty})
+	}
+
+	if webhook != "" {
+		oracles = append(oracles, &lib.WebhookOracle{URL: webhook, Listen: webhookAddr, CallbackURL: webhookURL})
+	}
+	if answersFIFO != "" {
+		oracles = append(oracles, &lib.FIFOOracle{Path: answersFIFO})
+	}
+
+	if len(oracles) == 0 {
+		if invert {
+			return nil, fmt.Errorf("--invert needs a test flag such as --test")
+		}
+		return nil, nil
+	}
+
+	oracle := oracles[0]
+	if len(oracles) > 1 {
+		switch combineMode {
+		case "all":
+			oracle = lib.AllOf(oracles...)
+		case "any":
+			oracle = lib.AnyOf(oracles...)
+		default:
+			return nil, fmt.Errorf("invalid --combine %q: must be all or any", combineMode)
+		}
+	}
+	return wrapOracle(oracle)
+}
+
+// commandOracle returns the oracle that judges lines by running command,
+// checking its output when --expect-* flags are given
+func commandOracle(command string, runner lib.Runner) (lib.Oracle, error) {
+	matchers, err := outputMatchers()
+	if err != nil {
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		return &lib.CommandOracle{Command: command, Runner: runner}, nil
+	}
+	var limit int64
+	if maxOutput != "0" {
+		if limit, err = parseSize(maxOutput); err != nil {
+			return nil, fmt.Errorf("%w: invalid --max-output-bytes: %v", errUsage, err)
+		}
+	}
+	return &lib.OutputOracle{Command: command, Matchers: matchers, Any: matchMode == "any", Runner: runner, MaxOutputBytes: int(limit)}, nil
+}
+
+// wrapOracle applies --invert, --probe-timeout and --retries to oracle
+func wrapOracle(oracle lib.Oracle) (lib.Oracle, error) {
+	if invert {
+		oracle = lib.Not(oracle)
+	}
+	oracle = lib.TimeoutOracle(oracle, probeTimeout)
+	if retries <= 0 {
+		return oracle, nil
+	}
+
+	strategy := lib.RetryStrategy{Backoff: retryBackoff}
+	switch retryMode {
+	case "error":
+		strategy.Mode = lib.RetryOnError
+	case "bad":
+		strategy.Mode = lib.RetryOnBad
+	case "majority":
+		strategy.Mode = lib.MajorityVote
+	default:
+		return nil, fmt.Errorf("invalid --retry-mode %q: must be error, bad or majority", retryMode)
+	}
+	return lib.RetryOracle(oracle, retries+1, strategy), nil
+}
+
+// outputMatchers returns the matchers described by the --expect-* flags for
+// the test command
+func outputMatchers() ([]lib.Matcher, error) {
+	if matchMode != "all" && matchMode != "any" {
+		return nil, fmt.Errorf("invalid --match %q: must be all or any", matchMode)
+	}
+
+	var matchers []lib.Matcher
+	if expectStdout != "" {
+		re, err := regexp.Compile(expectStdout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect-stdout: %w", err)
+		}
+		matchers = append(matchers, lib.StdoutMatches(re))
+	}
+	if expectStderr != "" {
+		re, err := regexp.Compile(expectStderr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect-stderr: %w", err)
+		}
+		matchers = append(matchers, lib.StderrMatches(re))
+	}
+	if expectExit != "" {
+		lo, hi, err := parseExitRange(expectExit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect-exit: %w", err)
+		}
+		matchers = append(matchers, lib.ExitCodeIn(lo, hi))
+	}
+	for _, spec := range expectJSON {
+		path, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --expect-json %q: must be path=value", spec)
+		}
+		matchers = append(matchers, lib.JSONPathEquals(path, value))
+	}
+	return matchers, nil
+}
+
+// parseExitRange parses an exit code like "0" or an inclusive range like "0-2"
+func parseExitRange(s string) (int, int, error) {
+	loStr, hiStr, isRange := strings.Cut(s, "-")
+	lo, err := strconv.Atoi(loStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return lo, lo, nil
+	}
+	hi, err := strconv.Atoi(hiStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if hi < lo {
+		return 0, 0, fmt.Errorf("range %q ends before it starts", s)
+	}
+	return lo, hi, nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// readPodLogs reads the logs of the --kubectl pod, one timestamped line per
+// log line
+func readPodLogs() ([]string, error) {
+	pod, container, _ := strings.Cut(kubeLogs, ",")
+	args := append([]string{}, kubeArgs...)
+	if k8sNS != "" {
+		args = append(args, "--namespace", k8sNS)
+	}
+	args = append(args, "logs", pod, "--timestamps")
+	if container != "" {
+		args = append(args, "--container", container)
+	}
+	if since != "" {
+		// kubectl takes a relative --since or an absolute --since-time
+		if _, err := time.ParseDuration(since); err == nil {
+			args = append(args, "--since="+since)
+		} else {
+			args = append(args, "--since-time="+since)
+		}
+	}
+
+	out, err := runTool("kubectl", args...)
+	if err != nil {
+		return nil, err
+	}
+	return lib.ReadLines(bytes.NewReader(out))
+}
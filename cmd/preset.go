@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	preset string
+	revs   string
+)
+
+// presetSetup is the input and hooks a preset generates for a well-known kind
+// of bisection
+type presetSetup struct {
+	lines      []string
+	source     lib.Source          // Candidate content when it isn't just lines
+	before     string              // Runs ahead of the user's --before
+	test       string              // Test command used when none is given
+	testPrefix string              // Prepended to the test command, e.g. to set it up
+	unit       string              // What the report calls a line, like "argument", if not "line"
+	describe   func(string) string // Extra detail about the bad line for the report, if set
+	cleanup    func() error        // Undoes the preset's changes once the bisection is over
+}
+
+// loadPreset builds the setup for --preset from the positional arguments
+func loadPreset(args []string) (*presetSetup, error) {
+	switch preset {
+	case "git":
+		return gitPreset(args)
+	case "git-file":
+		return gitFilePreset(args)
+	case "pip":
+		return pipPreset(args)
+	case "npm":
+		return npmPreset(args)
+	case "go":
+		return goPreset(args)
+	case "env":
+		return envPreset(args)
+	case "dockerfile":
+		return dockerfilePreset(args)
+	case "args":
+		return argsPreset(args)
+	default:
+		return nil, fmt.Errorf("unknown --preset %q: must be git, git-file, pip, npm, go, env, dockerfile or args", preset)
+	}
+}
+
+// gitPreset bisects the commits in a good..bad range, giving git bisect run
+// ergonomics. Each commit is checked out before its test and the original
+// HEAD is restored afterwards.
+func gitPreset(args []string) (*presetSetup, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("--preset git takes a commit range like v1.0..HEAD")
+	}
+	goodRev, badRev, _ := strings.Cut(args[0], "..")
+	if badRev == "" {
+		badRev = "HEAD"
+	}
+
+	good, err := resolveRange(goodRev, badRev)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checkouts would fail or carry changes along in a dirty tree
+	if status, err := git("status", "--porcelain", "--untracked-files=no"); err != nil {
+		return nil, err
+	} else if status != "" {
+		return nil, fmt.Errorf("--preset git needs a clean working tree; commit or stash your changes first")
+	}
+
+	revs, err := git("rev-list", "--reverse", good+".."+badRev)
+	if err != nil {
+		return nil, err
+	}
+	if revs == "" {
+		return nil, fmt.Errorf("no commits between %s and %s", goodRev, badRev)
+	}
+
+	// Return to the branch if there is one, otherwise the detached commit
+	head, err := git("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if head, err = git("rev-parse", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	return &presetSetup{
+		lines:    append([]string{good}, strings.Split(revs, "\n")...),
+		before:   "git checkout --quiet {line}",
+		describe: describeCommit,
+		cleanup: func() error {
+			_, err := git("checkout", "--quiet", head)
+			return err
+		},
+	}, nil
+}
+
+// gitFilePreset bisects the revisions of a single file between the commits in
+// --revs. The candidate file holds the file as of each commit, so the test
+// command can check {file} without anything being checked out.
+func gitFilePreset(args []string) (*presetSetup, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("--preset git-file takes the path of a file tracked by git")
+	}
+	path := args[0]
+	goodRev, badRev, _ := strings.Cut(revs, "..")
+	if goodRev == "" {
+		return nil, fmt.Errorf("--preset git-file needs --revs with a commit range like v1.0..HEAD")
+	}
+	if badRev == "" {
+		badRev = "HEAD"
+	}
+
+	good, err := resolveRange(goodRev, badRev)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := git("rev-list", "--reverse", good+".."+badRev, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	if changes == "" {
+		return nil, fmt.Errorf("%s didn't change between %s and %s", path, goodRev, badRev)
+	}
+
+	// ./ makes git resolve the path relative to the current directory
+	spec := "./" + filepath.ToSlash(filepath.Clean(path))
+	return &presetSetup{
+		lines:    append([]string{good}, strings.Split(changes, "\n")...),
+		before:   fmt.Sprintf("git show {line}:%s > {file}", shellQuote(spec)),
+		describe: describeCommit,
+	}, nil
+}
+
+// usesOtherTest reports whether a test other than --test was requested, in
+// which case a preset's default test command isn't wanted
+func usesOtherTest(cmd *cobra.Command) bool {
+	for _, name := range []string{"test-http", "test-tcp", "test-exists", "webhook", "answers-fifo"} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRange verifies both ends of a commit range and returns the full hash
+// of the good end
+func resolveRange(goodRev, badRev string) (string, error) {
+	good, err := git("rev-parse", "--verify", goodRev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	if _, err := git("rev-parse", "--verify", badRev+"^{commit}"); err != nil {
+		return "", err
+	}
+	return good, nil
+}
+
+// describeCommit summarizes a commit for the report
+func describeCommit(rev string) string {
+	summary, err := git("log", "-1", "--format=%h %an %ad%n%s", "--date=short", rev)
+	if err != nil {
+		return ""
+	}
+	return summary
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// git runs git with args and returns its trimmed output
+func git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
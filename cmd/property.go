@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var propertySpecs []string
+
+// buildProperties returns the --property tests, each judged like --test and
+// wrapped by --invert, --probe-timeout and --retries
+func buildProperties(runner lib.Runner) ([]lib.Property, error) {
+	var props []lib.Property
+	for _, spec := range propertySpecs {
+		name, command, ok := strings.Cut(spec, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || command == "" {
+			return nil, fmt.Errorf("%w: invalid --property %q: must be name=command", errUsage, spec)
+		}
+		o, err := commandOracle(command, runner)
+		if err != nil {
+			return nil, err
+		}
+		if o, err = wrapOracle(o); err != nil {
+			return nil, err
+		}
+		props = append(props, lib.Property{Name: name, Oracle: o})
+	}
+	return props, nil
+}
+
+// reportProperties prints the first bad line of every property among the
+// lines of input
+func reportProperties(w io.Writer, result *lib.MultiResult, input lib.Source) error {
+	const separator = "═════════════════════════════════════════════════════════════"
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s%s%s\n", colors.Green, separator, colors.Reset)
+	fmt.Fprintf(w, "%s%s✓ Bisection Complete%s\n", colors.Bold, colors.Green, colors.Reset)
+	fmt.Fprintf(w, "%s%s%s\n", colors.Green, separator, colors.Reset)
+	fmt.Fprintln(w)
+	tests := 0
+	for _, p := range result.Properties {
+		tests += p.StepsTaken
+		if p.RangeStart < p.RangeEnd {
+			fmt.Fprintf(w, "%s%s:%s the first bad line is one of lines %s%s%d-%d%s\n", colors.Bold, p.Name, colors.Reset, colors.Bold, colors.Red, p.RangeStart, p.RangeEnd, colors.Reset)
+		} else {
+			fmt.Fprintf(w, "%s%s:%s the first bad line is %s%s%d%s\n", colors.Bold, p.Name, colors.Reset, colors.Bold, colors.Red, p.BadLineNumber, colors.Reset)
+		}
+		if !p.Verified {
+			fmt.Fprintf(w, "%sLine %d was assumed bad and never tested%s\n", colors.Faded, p.BadLineNumber, colors.Reset)
+		}
+		if len(p.Skipped) > 0 {
+			fmt.Fprintf(w, "%sSkipped lines: %s%s\n", colors.Faded, joinInts(p.Skipped, ", "), colors.Reset)
+		}
+		if err := displayResultContext(w, input, p.BadLineNumber-1); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "%sProbes:%s %d, shared by %d tests\n", colors.Bold, colors.Reset, result.Probes, tests)
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	outputFormat string
+	formatProbes bool
+)
+
+// quickfixName is the file name quickfix lines refer to: the input file when
+// lines are its lines, or "-" when they were piped or generated
+func quickfixName(args []string, fileInput bool) string {
+	if fileInput && len(args) > 0 {
+		return args[0]
+	}
+	return "-"
+}
+
+// quickfixObserver prints a quickfix line for every verdict
+func quickfixObserver(w io.Writer, name string) lib.Observer {
+	return lib.Observer{
+		OnVerdict: func(p lib.Probe, v lib.Verdict) {
+			fmt.Fprintf(w, "%s:%d: probe %d: %s\n", name, p.Index+1, p.Step, v)
+		},
+	}
+}
+
+// reportQuickfix prints the result as a file:line: message line for vim -q
+// and other errorformat-based tools
+func reportQuickfix(w io.Writer, name string, result *lib.Result) {
+	found := foundVerdict(result)
+	message := fmt.Sprintf("first %s line, found in %d steps", found, result.StepsTaken)
+	if !result.Verified {
+		message += fmt.Sprintf(" (assumed %s, never tested)", found)
+	}
+	if result.RangeStart < result.RangeEnd {
+		message += fmt.Sprintf(" (may be as early as line %d, skipped lines hide it)", result.RangeStart)
+	}
+	fmt.Fprintf(w, "%s:%d: %s: %s\n", name, result.BadLineNumber, message, result.BadLineContent)
+}
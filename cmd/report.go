@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// reportPath is where --report writes the session report, if set
+var reportPath string
+
+// secretName matches the names of environment variables whose values the
+// report leaves out
+var secretName = regexp.MustCompile(`(?i)token|secret|passw|key|credential|auth|cookie|session|private`)
+
+// envReference matches $NAME and ${NAME} in a shell command
+var envReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// reportedEnv are the environment variables the report always includes when
+// set, as they change how commands behave
+var reportedEnv = []string{"PATH", "SHELL", "HOME", "PWD", "TMPDIR", "TZ", "LANG", "LC_ALL", "LC_CTYPE", "CI"}
+
+// sessionReport is what --report records about a bisection so its result can
+// be trusted and reproduced later
+type sessionReport struct {
+	Invocation []string          `json:"invocation"`
+	Version    string            `json:"version"`
+	Revision   string            `json:"revision,omitempty"`
+	GoVersion  string            `json:"go_version"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	Dir        string            `json:"dir"`
+	Started    time.Time         `json:"started"`
+	Config     map[string]string `json:"config"` // Every flag's value, defaults included
+	Env        map[string]string `json:"env"`
+	Input      reportInput       `json:"input"`
+	Result     *reportResult     `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// reportInput identifies the input bisected
+type reportInput struct {
+	Name   string `json:"name"`
+	Lines  int    `json:"lines"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"` // Of the whole input as candidates hold it
+}
+
+type reportResult struct {
+	FirstGoodLine      int          `json:"first_good_line_number,omitempty"`
+	BadLineNumber      int          `json:"bad_line_number"`
+	BadLineContent     string       `json:"bad_line_content"`
+	LastGoodLineNumber int          `json:"last_good_line_number"`
+	RangeStart         int          `json:"range_start"`
+	RangeEnd           int          `json:"range_end"`
+	Verified           bool         `json:"verified"`
+	StepsTaken         int          `json:"steps_taken"`
+	Skipped            []int        `json:"skipped,omitempty"`
+	History            []reportStep `json:"history"`
+	DurationSeconds    float64      `json:"duration_seconds"`
+}
+
+type reportStep struct {
+	LineNumber int         `json:"line_number"`
+	Verdict    lib.Verdict `json:"verdict"`
+}
+
+// newReport records the invocation, build, platform, flags, environment and
+// input of a bisection of src that starts now
+func newReport(cmd *cobra.Command, args []string, src lib.Source) (*sessionReport, error) {
+	r := &sessionReport{
+		Invocation: redactArgs(os.Args),
+		Version:    "(unknown)",
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Started:    time.Now(),
+		Config:     make(map[string]string),
+		Env:        make(map[string]string),
+	}
+	if dir, err := os.Getwd(); err == nil {
+		r.Dir = dir
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		r.Version = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				r.Revision = s.Value
+			}
+		}
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if secretFlags[f.Name] && f.Value.String() != "" {
+			r.Config[f.Name] = "[redacted]"
+		} else {
+			r.Config[f.Name] = redactSecrets(f.Value.String())
+		}
+	})
+	names := slices.Clone(reportedEnv)
+	for _, command := range []string{testCommand, beforeCommand, afterCommand} {
+		for _, m := range envReference.FindAllStringSubmatch(command, -1) {
+			names = append(names, m[1])
+		}
+	}
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		switch {
+		case !ok:
+		case secretName.MatchString(name):
+			r.Env[name] = "[redacted]"
+		default:
+			r.Env[name] = redactSecrets(value)
+		}
+	}
+
+	r.Input.Name = "stdin"
+	if len(args) > 0 {
+		r.Input.Name = strings.Join(args, " ")
+	}
+	r.Input.Lines = src.Len()
+	var err error
+	if r.Input.Bytes, r.Input.SHA256, err = fingerprint(src); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// fingerprint returns the size and SHA-256 of the whole of src as candidates
+// hold it, or nothing for an empty input
+func fingerprint(src lib.Source) (int64, string, error) {
+	if src.Len() == 0 {
+		return 0, "", nil
+	}
+	if in, ok := src.(indexedInput); ok {
+		return in.size, in.sum, nil
+	}
+	h := sha256.New()
+	n, err := src.WriteLines(h, 0, src.Len()-1)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to fingerprint input: %w", err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// indexedInput is the LineIndex of an input file together with its
+// fingerprint, taken while it was indexed so a multi-GB file isn't read again
+// just to be fingerprinted
+type indexedInput struct {
+	*lib.LineIndex
+	size int64  // As fingerprint counts it
+	sum  string // As fingerprint computes it
+}
+
+// indexInput indexes the first size bytes of r and fingerprints them
+func indexInput(r io.ReaderAt, size int64) (indexedInput, error) {
+	h := sha256.New()
+	tail := &lastByte{}
+	index, err := lib.NewLineIndexTee(r, size, io.MultiWriter(h, tail))
+	if err != nil {
+		return indexedInput{}, err
+	}
+	if size > 0 && tail.b != '\n' {
+		// Candidates end the last line with the newline the file is missing
+		h.Write([]byte("\n"))
+		size++
+	}
+	return indexedInput{LineIndex: index, size: size, sum: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// lastByte remembers the last byte written to it
+type lastByte struct{ b byte }
+
+func (l *lastByte) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.b = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+// secretFlags are the flags whose whole value is a secret, like an incoming
+// webhook URL that anyone holding it can post to
+var secretFlags = map[string]bool{"webhook": true}
+
+// redactArgs returns args with the values of secretFlags hidden, including in
+// --flag=value form, and the secrets redactSecrets finds hidden in the rest
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		switch {
+		case ok && strings.HasPrefix(name, "--") && secretFlags[name[2:]]:
+			redacted[i] = name + "=[redacted]"
+		case ok && strings.HasPrefix(name, "-"):
+			redacted[i] = name + "=" + redactSecrets(value)
+		case i > 0 && strings.HasPrefix(args[i-1], "--") && secretFlags[args[i-1][2:]]:
+			redacted[i] = "[redacted]"
+		default:
+			redacted[i] = redactSecrets(arg)
+		}
+	}
+	return redacted
+}
+
+var (
+	// embeddedURL matches a URL inside a command or other value
+	embeddedURL = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s'"]+`)
+	// secretHeader matches an HTTP header such as Authorization or X-Api-Key
+	// and its value, after an optional scheme like Bearer
+	secretHeader = regexp.MustCompile(`(?i)\b([A-Za-z][A-Za-z0-9-]*):[ \t]+((?:bearer|basic|token)[ \t]+)?[^\s'"]+`)
+	// namedValue matches a name=value pair, as in an environment assignment,
+	// a key=value DSN or a URL query, with the value possibly quoted
+	namedValue = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.-]*)=('[^']*'|"[^"]*"|[^\s&;'"]*)`)
+)
+
+// redactSecrets hides the passwords of URLs in s, the values of headers and
+// name=value pairs whose name matches secretName, such as Authorization,
+// PASSWORD=... or ?token=..., leaving the rest as it is
+func redactSecrets(s string) string {
+	s = embeddedURL.ReplaceAllStringFunc(s, redactURL)
+	s = secretHeader.ReplaceAllStringFunc(s, func(header string) string {
+		m := secretHeader.FindStringSubmatch(header)
+		if !secretName.MatchString(m[1]) {
+			return header
+		}
+		return m[1] + ": " + m[2] + "[redacted]"
+	})
+	return namedValue.ReplaceAllStringFunc(s, func(pair string) string {
+		name, _, _ := strings.Cut(pair, "=")
+		if !secretName.MatchString(name) {
+			return pair
+		}
+		return name + "=[redacted]"
+	})
+}
+
+// redactURL hides the password of a URL like a --dsn, leaving other values
+// as they are
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	return u.Redacted()
+}
+
+// write records result, or the error that ended the bisection, and writes the
+// report to reportPath
+func (r *sessionReport) write(result *lib.Result, bisectErr error) error {
+	if bisectErr != nil {
+		r.Error = bisectErr.Error()
+	}
+	if result != nil {
+		r.Result = &reportResult{
+			FirstGoodLine:      result.FirstGoodLine,
+			BadLineNumber:      result.BadLineNumber,
+			BadLineContent:     result.BadLineContent,
+			LastGoodLineNumber: result.LastGoodLineNumber,
+			RangeStart:         result.RangeStart,
+			RangeEnd:           result.RangeEnd,
+			Verified:           result.Verified,
+			StepsTaken:         result.StepsTaken,
+			Skipped:            result.Skipped,
+			History:            make([]reportStep, 0, len(result.History)),
+			DurationSeconds:    result.Duration.Seconds(),
+		}
+		for _, s := range result.History {
+			r.Result.History = append(r.Result.History, reportStep{LineNumber: s.Index + 1, Verdict: s.Verdict})
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // Commands are easier to read with their && and <
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write --report: %w", err)
+	}
+	return nil
+}
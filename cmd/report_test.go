@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{
+		"bsct", "--sql", "SELECT 1", "--dsn", "postgres://etl:s3cret@db/shop",
+		"--webhook", "https://hooks.slack.com/services/T0/B0/abc",
+		"--webhook=https://hooks.slack.com/services/T0/B0/def",
+		"--dsn=mysql://root:hunter2@db/app", "input.log",
+	}
+	assert.Equal(t, []string{
+		"bsct", "--sql", "SELECT 1", "--dsn", "postgres://etl:xxxxx@db/shop",
+		"--webhook", "[redacted]",
+		"--webhook=[redacted]",
+		"--dsn=mysql://root:xxxxx@db/app", "input.log",
+	}, redactArgs(args))
+}
+
+func TestRedactSecrets(t *testing.T) {
+	testCases := []struct {
+		name, in, want string
+	}{
+		{"plain", "./check.sh {file}", "./check.sh {file}"},
+		{"env assignment", "PASSWORD=hunter2 ./check.sh", "PASSWORD=[redacted] ./check.sh"},
+		{"quoted assignment", `API_KEY='a b' ./check.sh`, "API_KEY=[redacted] ./check.sh"},
+		{"other assignment", "LANG=C sort", "LANG=C sort"},
+		{"bearer header", `curl -H 'Authorization: Bearer abc123' http://api/health`, `curl -H 'Authorization: Bearer [redacted]' http://api/health`},
+		{"api key header", `curl -H "X-Api-Key: abc123" http://api`, `curl -H "X-Api-Key: [redacted]" http://api`},
+		{"other header", `curl -H 'Accept: text/plain' http://api`, `curl -H 'Accept: text/plain' http://api`},
+		{"query token", "https://api.example.com/health?token=abc&verbose=1", "https://api.example.com/health?token=[redacted]&verbose=1"},
+		{"url password", "psql postgres://etl:s3cret@db/shop -c 'SELECT 1'", "psql postgres://etl:xxxxx@db/shop -c 'SELECT 1'"},
+		{"key=value dsn", "host=db user=etl password=s3cret dbname=shop", "host=db user=etl password=[redacted] dbname=shop"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, redactSecrets(tc.in))
+		})
+	}
+
+	assert.Equal(t, []string{"bsct", "--test", "PGPASSWORD=[redacted] psql", "--test-http=http://svc/ready?auth=[redacted]"},
+		redactArgs([]string{"bsct", "--test", "PGPASSWORD=x psql", "--test-http=http://svc/ready?auth=y"}))
+}
+
+func TestIndexInput(t *testing.T) {
+	for _, input := range []string{"a\nb\n", "a\r\nb", "single", ""} {
+		indexed, err := indexInput(strings.NewReader(input), int64(len(input)))
+		require.NoError(t, err)
+		index, err := lib.NewLineIndex(strings.NewReader(input), int64(len(input)))
+		require.NoError(t, err)
+
+		// The fingerprint taken while indexing matches reading the input again
+		size, sum, err := fingerprint(indexed)
+		require.NoError(t, err)
+		wantSize, wantSum, err := fingerprint(index)
+		require.NoError(t, err)
+		assert.Equal(t, wantSize, size, "%q", input)
+		assert.Equal(t, wantSum, sum, "%q", input)
+	}
+}
@@ -1,13 +1,22 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/knpwrs/bsct/lib"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -16,6 +25,29 @@ var (
 	testCommand   string
 	beforeCommand string
 	afterCommand  string
+	ciMode        bool
+	timeout       time.Duration
+	metricsAddr   string
+	tmuxView      bool
+	prewarm       bool
+	speculate     bool
+	targetPath    string
+	syncWrites    bool
+	estimate      bool
+	rawDisplay    bool
+	noColor       bool
+	colors        lib.Palette // Styles of the output, none unless useColor
+	contextLines  int
+	displayWidth  int // Columns lines are wrapped to, 0 when stdout isn't a terminal
+	usePatternRE  bool
+	findFirstGood bool
+	goodLine      int
+	badLine       int
+	termGood      string
+	termBad       string
+	maxTempBytes  string
+	checkTest     bool
+	streamOutput  bool
 )
 
 var rootCmd = &cobra.Command{
@@ -26,11 +58,16 @@ It works similar to git bisect but for text files or stdin.
 
 You can provide input via:
   - A file path argument
+  - An s3:// or gs:// URL, read with the aws or gcloud CLI
+  - A GitHub Actions, GitLab or CircleCI job URL, whose log is downloaded
   - stdin (pipe or redirect)
 
 By default, the first line is assumed good and the last line is assumed bad.
 Use --good and --bad flags to specify content patterns for automatic boundary detection.
 Use --test to run a command automatically instead of interactive prompts.
+Use --test-http to judge lines by a service's HTTP response instead,
+--test-tcp by whether a port accepts connections, or --test-exists by whether
+a file was produced.
 
 Placeholders (supported in --test, --before, and --after):
   {file} or {} - replaced with temp file path (lines 1 through test line)
@@ -39,183 +76,823 @@ Placeholders (supported in --test, --before, and --after):
 Hooks:
   --before - runs before each test (useful for setup steps)
   --after - runs after each test (useful for cleanup steps)`,
-	Args: cobra.MaximumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		// --preset args takes a whole command line
+		if preset == "args" {
+			return nil
+		}
+		return cobra.MaximumNArgs(1)(cmd, args)
+	},
 	RunE: run,
 }
 
+// Execute runs bsct. SIGINT, SIGTERM and SIGHUP cancel the bisection instead
+// of killing bsct outright, so running commands are stopped and the after
+// hook, temp file removal and preset cleanup still happen; a second signal
+// kills bsct right away.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	rootCmd.Flags().StringVar(&goodPattern, "good", "", "Content pattern to identify a known good line")
 	rootCmd.Flags().StringVar(&badPattern, "bad", "", "Content pattern to identify a known bad line")
-	rootCmd.Flags().StringVar(&testCommand, "test", "", "Command to run for automatic testing (exit 0 = good, non-zero = bad). Supports {file}, {}, and {line} placeholders")
+	rootCmd.Flags().IntVar(&goodLine, "good-line", 0, "1-indexed number of a known good line, e.g. from a previous run, in place of a --good pattern")
+	rootCmd.Flags().IntVar(&badLine, "bad-line", 0, "1-indexed number of a known bad line, in place of a --bad pattern")
+	rootCmd.Flags().BoolVarP(&usePatternRE, "regexp", "E", false, "Treat --good and --bad as regular expressions instead of plain text")
+	rootCmd.Flags().StringVar(&termGood, "term-good", "good", "What good lines are called in prompts, answers and output, like git bisect terms, e.g. old or fast")
+	rootCmd.Flags().StringVar(&termBad, "term-bad", "bad", "What bad lines are called in prompts, answers and output, e.g. new or slow")
+	rootCmd.Flags().BoolVar(&findFirstGood, "find-first-good", false, "Find the first good line after bad ones instead of the first bad line, e.g. where a service recovered. The --bad line then comes before the --good one, by default the first and last lines")
+	rootCmd.Flags().StringVar(&testCommand, "test", "", "Command to run for automatic testing (exit 0 = good, 125 = skip, other non-zero = bad). Supports {file}, {}, and {line} placeholders")
 	rootCmd.Flags().StringVar(&beforeCommand, "before", "", "Command to run before each test (useful for setup). Supports {file}, {}, and {line} placeholders")
 	rootCmd.Flags().StringVar(&afterCommand, "after", "", "Command to run after each test (useful for cleanup). Supports {file}, {}, and {line} placeholders")
+	rootCmd.Flags().StringVar(&preset, "preset", "", "Generate the input and hooks for a well-known bisection instead of reading a file: git (the argument is a commit range like v1.0..HEAD), git-file (the argument is a file whose revisions in --revs are bisected), pip, npm or go (the argument is a requirements.txt, package.json or go.mod, and line 1 is its dependencies as they are while each later line upgrades one more), env (the argument is a .env file whose variables are set for --test), dockerfile (the argument is a Dockerfile whose instructions are built and run), or args (the arguments after -- are a failing command line whose arguments are bisected, running \"$@\" with each prefix of them)")
+	rootCmd.Flags().StringVar(&revs, "revs", "", "Commit range like v1.0..HEAD for --preset git-file")
+	rootCmd.Flags().BoolVar(&ciMode, "ci", false, "Run non-interactively for pipelines: require a test flag, print porcelain output, default --timeout to 1h and --probe-timeout to 10m, and annotate the bad line on GitHub Actions")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0, "Stop the whole bisection after this long, e.g. 30m, exiting with status 124")
+	rootCmd.Flags().BoolVar(&checkTest, "check-test", false, "Test the known good and bad lines first and warn if they get the same verdict, e.g. because --test doesn't read {file}")
+	rootCmd.Flags().BoolVar(&streamOutput, "stream-output", false, "Show what the test command prints while it runs, each line prefixed with its step like [step 3]")
+	rootCmd.Flags().BoolVar(&estimate, "estimate", false, "Time two representative probes and print the projected number of steps and total duration instead of bisecting")
+	rootCmd.Flags().BoolVar(&prewarm, "prewarm", false, "Run --before for the likeliest next line while the current test runs, so expensive setup overlaps with testing. Hooks must tolerate running alongside a test")
+	rootCmd.Flags().BoolVar(&speculate, "speculate", false, "While a line is tested, also test both lines that may come next and stop the one its verdict rules out, so slow tests take about half as long. Tests and hooks must tolerate running three at a time")
+	rootCmd.Flags().StringVar(&targetPath, "target", "", "Write each candidate over this file before testing it, such as a config file the test reloads, keeping its mode, owner and extended attributes. The original is put back afterwards")
+	rootCmd.Flags().BoolVar(&syncWrites, "sync", false, "Flush each candidate file and its directory to disk before testing it, for tests that read it from another container or an NFS mount")
+	rootCmd.Flags().StringVar(&maxTempBytes, "max-temp-bytes", "", "Fail with a clear error instead of filling the disk once candidate files would take up more than this, e.g. 500M or 2G")
+	rootCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on /metrics at this address while bisecting, e.g. :9090")
+	rootCmd.Flags().BoolVar(&tmuxView, "tmux", false, "Inside tmux, show the whole candidate in a pane next to bsct, refreshed every step")
+	rootCmd.Flags().BoolVar(&watch, "watch", false, "After an automatic bisection, bisect the file again whenever it changes, reusing verdicts for candidates already tested")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Keep every verdict in this directory, keyed by the candidate's content and the test flags, and reuse it instead of testing the same candidate again, e.g. when bisecting again after a crash")
+	rootCmd.Flags().StringVar(&outputFormat, "format", "text", "How to print the result: text, or quickfix for file:line: message lines that vim -q and errorformat tools read (progress then goes to stderr)")
+	rootCmd.Flags().BoolVar(&formatProbes, "format-probes", false, "With --format quickfix, also print a line for every probe and its verdict")
+	rootCmd.Flags().IntVar(&contextLines, "context", 1, "Show this many lines before and after the line being asked about and the result; type + or - at the prompt to change it as you go")
+	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Print without ANSI colors, as is done anyway when NO_COLOR is set or stdout isn't a terminal")
+	rootCmd.Flags().BoolVar(&rawDisplay, "raw-display", false, "Print lines as they are instead of escaping ANSI codes and other control characters in them, e.g. to see a log's own colors")
+	rootCmd.Flags().BoolVar(&blame, "blame", false, "Report the git commit that last changed the bad line of the input file")
+	rootCmd.Flags().StringVar(&sessionName, "session", "", "Save the bisection's progress under this name after every step, so it can be continued with bsct resume <name> after an interruption")
+	rootCmd.Flags().StringVar(&reportPath, "report", "", "Write a JSON report of the session to this path: the invocation, bsct version, OS and architecture, every flag's value, the environment variables the commands use (with secrets redacted by name), a fingerprint of the input and the result or error")
+	rootCmd.Flags().StringVar(&emitScript, "emit-script", "", "After bisecting, write a shell script to this path that recreates the bad line's candidate and runs --before, --test and --after on it, so anyone can check the finding without bsct")
+	addOracleFlags()
+	addRunnerFlags()
+	addInputFlags()
 }
 
 func run(cmd *cobra.Command, args []string) error {
-	// Read input lines
-	lines, usingStdin, err := readInput(args)
-	if err != nil {
-		return fmt.Errorf("failed to read input: %w", err)
+	colors = lib.NewPalette(useColor())
+	if watch {
+		return watchInput(cmd, args)
 	}
+	return bisectOnce(cmd, args)
+}
 
-	if len(lines) == 0 {
-		return fmt.Errorf("no input lines provided")
+// bisectOnce reads the input, bisects it and prints the report
+func bisectOnce(cmd *cobra.Command, args []string) error {
+	// Read input lines, or generate them for a preset
+	var lines []string
+	var src lib.Source   // Candidate content when it isn't just lines
+	var input lib.Source // Lines as numbered in the input, when they aren't in lines
+	var usingStdin bool
+	var fileInput bool // Lines are the lines of the file argument
+	var setup *presetSetup
+	var err error
+	before := beforeCommand
+	if findFirstGood && (follow || len(propertySpecs) > 0) {
+		return fmt.Errorf("%w: --find-first-good can't be combined with --follow or --property", errUsage)
+	}
+	if follow && (preset != "" || watch || len(args) > 0 && (isObjectURL(args[0]) || isJobLogURL(args[0]))) {
+		return fmt.Errorf("%w: --follow needs a local file or stdin, and can't be combined with --preset or --watch", errUsage)
+	}
+	if preset != "" {
+		setup, err = loadPreset(args)
+		if err != nil {
+			return err
+		}
+		if setup.cleanup != nil {
+			defer func() {
+				if err := setup.cleanup(); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to clean up after --preset %s: %v\n", preset, err)
+				}
+			}()
+		}
+		lines, src = setup.lines, setup.source
+		if testCommand == "" && !usesOtherTest(cmd) {
+			testCommand = setup.test
+		}
+		if setup.testPrefix != "" {
+			if testCommand == "" {
+				return fmt.Errorf("--preset %s needs --test", preset)
+			}
+			testCommand = setup.testPrefix + testCommand
+		}
+		if setup.before != "" {
+			before = strings.TrimSuffix(setup.before+" && "+beforeCommand, " && ")
+		}
+	} else if adapted, adaptedSrc, ok, err := readAdapterInput(args); ok {
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		if follow {
+			return fmt.Errorf("%w: --follow can't be combined with an input flag", errUsage)
+		}
+		lines, src = adapted, adaptedSrc
+	} else if follow {
+		lines, usingStdin, err = followInput(cmd, args)
+		if err != nil {
+			return err
+		}
+		fileInput = !usingStdin
+	} else if len(args) > 0 && !withinLine && !isObjectURL(args[0]) && !isJobLogURL(args[0]) {
+		file, indexed, err := openInput(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		defer file.Close()
+		src, input, fileInput = indexed, indexed, true
+	} else {
+		lines, usingStdin, err = readInput(args)
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		fileInput = !usingStdin
+	}
+
+	if withinLine {
+		if setup != nil || src != nil {
+			return fmt.Errorf("%w: --within-line can't be combined with --preset or an input flag", errUsage)
+		}
+		if lines, src, err = splitWithin(lines); err != nil {
+			return err
+		}
+	}
+	if src == nil {
+		src = lib.Lines(lines)
+	}
+	if input == nil {
+		input = lib.Lines(lines)
+	}
+	var sections *lib.SectionSource
+	if sectionStart != "" || sectionEnd != "" {
+		if withinLine {
+			return fmt.Errorf("%w: --section-start and --section-end can't be combined with --within-line", errUsage)
+		}
+		if sections, err = splitSections(src); err != nil {
+			return err
+		}
+		src = sections
+	}
+	// The line numbers of results no longer count lines of the file
+	fileInput = fileInput && !withinLine && sections == nil
+	if outputFormat != "text" && outputFormat != "quickfix" {
+		return fmt.Errorf("%w: --format must be text or quickfix", errUsage)
 	}
 
 	// Find initial boundaries
-	goodIdx, badIdx, err := findBoundaries(lines, goodPattern, badPattern)
+	goodIdx, badIdx, err := findBoundaries(src)
 	if err != nil {
 		return err
 	}
 
+	if ciMode {
+		// Pipeline logs should end with the error, not the flag reference
+		cmd.SilenceUsage = true
+		if !cmd.Flags().Changed("timeout") {
+			timeout = time.Hour
+		}
+		if !cmd.Flags().Changed("probe-timeout") {
+			probeTimeout = 10 * time.Minute
+		}
+	}
+
 	// Create bisector
-	var bisector lib.Bisector
-	if testCommand != "" {
-		bisector = lib.NewAutomaticBisector(lines, goodIdx, badIdx, testCommand, beforeCommand, afterCommand)
-	} else {
-		bisector = lib.NewInteractiveBisector(lines, goodIdx, badIdx, usingStdin)
+	opts := []lib.Option{
+		lib.WithBoundaries(goodIdx, badIdx),
+		lib.WithTestCommand(testCommand),
+		lib.WithBeforeCommand(before),
+		lib.WithAfterCommand(afterCommand),
+		lib.WithOutput(cmd.OutOrStdout()),
+		lib.WithErrorOutput(cmd.ErrOrStderr()),
+	}
+	if findFirstGood {
+		opts = append(opts, lib.WithFindFirstGood())
+	}
+	if termGood != "good" || termBad != "bad" {
+		opts = append(opts, lib.WithTerms(termGood, termBad))
+	}
+	if ciMode {
+		opts = append(opts, lib.WithOutput(io.Discard))
+	} else if outputFormat == "quickfix" {
+		// Keep stdout for the quickfix lines
+		opts = append(opts, lib.WithOutput(cmd.ErrOrStderr()))
+	}
+	if !ciMode && usingStdin {
+		opts = append(opts, lib.WithTTY())
+	}
+	runner, err := buildRunner()
+	if err != nil {
+		return err
+	}
+	if runner != nil {
+		opts = append(opts, lib.WithRunner(runner))
+	}
+	if prewarm {
+		if targetPath != "" {
+			return fmt.Errorf("%w: --prewarm can't be combined with --target", errUsage)
+		}
+		opts = append(opts, lib.WithPrewarm())
+	}
+	if speculate {
+		if targetPath != "" || prewarm {
+			return fmt.Errorf("%w: --speculate can't be combined with --target or --prewarm", errUsage)
+		}
+		opts = append(opts, lib.WithSpeculation())
+	}
+	if targetPath != "" {
+		opts = append(opts, lib.WithTarget(targetPath))
+	}
+	if syncWrites {
+		opts = append(opts, lib.WithSync())
+	}
+	if checkTest {
+		opts = append(opts, lib.WithTestCheck())
+	}
+	if rawDisplay {
+		opts = append(opts, lib.WithRawDisplay())
+	}
+	displayWidth = terminalWidth()
+	opts = append(opts, lib.WithDisplayWidth(displayWidth))
+	if colors == (lib.Palette{}) {
+		opts = append(opts, lib.WithoutColor())
+	}
+	if contextLines < 0 {
+		return fmt.Errorf("%w: --context can't be negative", errUsage)
+	}
+	opts = append(opts, lib.WithContextLines(contextLines))
+	var execAction lib.Action
+	if err := execAction.UnmarshalText([]byte(onExecError)); err != nil {
+		return fmt.Errorf("%w: invalid --on-exec-error: %v", errUsage, err)
+	}
+	var crashAction lib.Action
+	if err := crashAction.UnmarshalText([]byte(onCrash)); err != nil {
+		return fmt.Errorf("%w: invalid --on-crash: %v", errUsage, err)
+	}
+	opts = append(opts, lib.WithOnExecError(execAction), lib.WithOnCrash(crashAction))
+	if streamOutput {
+		// Kept out of stdout where it holds the porcelain or quickfix result
+		stream := cmd.OutOrStdout()
+		if ciMode || outputFormat == "quickfix" {
+			stream = cmd.ErrOrStderr()
+		}
+		opts = append(opts, lib.WithStreamOutput(stream))
+	}
+	if maxTempBytes != "" {
+		n, err := parseSize(maxTempBytes)
+		if err != nil {
+			return fmt.Errorf("%w: invalid --max-temp-bytes: %v", errUsage, err)
+		}
+		opts = append(opts, lib.WithMaxTempBytes(n))
+	}
+	oracleRunner, err := testRunner(runner)
+	if err != nil {
+		return err
+	}
+	oracle, err := buildOracle(oracleRunner)
+	if err != nil {
+		return err
+	}
+	if len(propertySpecs) > 0 {
+		switch {
+		case oracle != nil:
+			return fmt.Errorf("%w: --property replaces --test and the other test flags", errUsage)
+		case ciMode || outputFormat != "text" || estimate || watch || emitScript != "" || reportPath != "" || speculate || cacheDir != "":
+			return fmt.Errorf("%w: --property can't be combined with --ci, --format, --estimate, --watch, --emit-script, --report, --speculate or --cache-dir", errUsage)
+		}
+		props, err := buildProperties(oracleRunner)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, lib.WithProperties(props...))
+	}
+	if oracle != nil {
+		if cacheDir != "" {
+			oracle = lib.CachedOracle(oracle, verdictCache(cmd, before, testCommand))
+		}
+		if watchCache != nil {
+			oracle = lib.CachedOracle(oracle, watchCache)
+		}
+		opts = append(opts, lib.WithOracle(oracle))
+	} else if ciMode {
+		return fmt.Errorf("%w: --ci needs --test or another test flag", errUsage)
+	}
+	if speculate && oracle == nil {
+		return fmt.Errorf("%w: --speculate needs --test or another test flag", errUsage)
+	}
+	if cacheDir != "" && oracle == nil {
+		return fmt.Errorf("%w: --cache-dir needs --test or another test flag", errUsage)
+	}
+	if emitScript != "" && testCommand == "" {
+		return fmt.Errorf("%w: --emit-script needs --test", errUsage)
+	}
+	if emitScript != "" && !posixShell() {
+		return fmt.Errorf("%w: --emit-script writes a POSIX shell script, which can't run --shell %s commands", errUsage, shell)
+	}
+	if tmuxView {
+		viewer, err := newTmuxViewer()
+		if err != nil {
+			return err
+		}
+		defer viewer.Close()
+		opts = append(opts, lib.WithObserver(viewer.observer()))
+	}
+	if metricsAddr != "" {
+		metrics, stop, err := serveMetrics(metricsAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		opts = append(opts, lib.WithMetrics(metrics))
+	}
+	var sess *session
+	if sessionName != "" {
+		switch {
+		case usingStdin:
+			return fmt.Errorf("%w: --session needs an input that can be read again, not stdin", errUsage)
+		case watch || estimate:
+			return fmt.Errorf("%w: --session can't be combined with --watch or --estimate", errUsage)
+		}
+		if sess, err = newSession(cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+		opts = append(opts, lib.WithObserver(sess.observer()))
+	}
+	if replayed != nil && (watch || estimate) {
+		return fmt.Errorf("%w: bsct replay can't be combined with --watch or --estimate", errUsage)
+	}
+	var blog *bisectLog
+	if !estimate {
+		blog = newBisectLog(cmd.ErrOrStderr())
+		opts = append(opts, lib.WithObserver(blog.observer()))
+	}
+	qfName := quickfixName(args, fileInput)
+	if outputFormat == "quickfix" && formatProbes {
+		opts = append(opts, lib.WithObserver(quickfixObserver(cmd.OutOrStdout(), qfName)))
+	}
+	bisector, err := lib.NewFromSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	if replayed != nil {
+		if err := replayed.apply(bisector, src, cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+	}
+	if blog != nil {
+		blog.start(bisector, src)
+	}
+	if sess != nil {
+		if err := sess.start(bisector); err != nil {
+			return err
+		}
 	}
 
 	// Run bisection
-	result, err := bisector.Bisect()
+	ctx := cmd.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if estimate {
+		return printEstimate(ctx, cmd.OutOrStdout(), bisector)
+	}
+	if multi, ok := bisector.(*lib.MultiBisector); ok {
+		result, err := multi.BisectAll(ctx)
+		if sess != nil {
+			sess.finish(err)
+		}
+		if err != nil {
+			if result != nil {
+				for _, p := range result.Properties {
+					reportInterrupted(cmd.ErrOrStderr(), p.Name+": ", "line", p.Result)
+				}
+			}
+			return err
+		}
+		return reportProperties(cmd.OutOrStdout(), result, input)
+	}
+	var report *sessionReport
+	if reportPath != "" {
+		if report, err = newReport(cmd, args, src); err != nil {
+			return err
+		}
+	}
+	result, err := bisector.BisectContext(ctx)
+	if sess != nil {
+		sess.finish(err)
+	}
+	if report != nil {
+		if reportErr := report.write(result, err); reportErr != nil {
+			if err == nil {
+				return reportErr
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", reportErr)
+		}
+	}
 	if err != nil {
+		if result != nil && result.Interrupted {
+			reportInterrupted(cmd.ErrOrStderr(), "", resultUnit(sections, setup), result)
+		}
+		var contradiction *lib.ContradictionError
+		if errors.As(err, &contradiction) {
+			reportContradiction(cmd.ErrOrStderr(), contradiction)
+		}
+		if errors.Is(err, lib.ErrCommandNotFound) || errors.Is(err, lib.ErrNotExecutable) {
+			fmt.Fprintln(cmd.ErrOrStderr(), "The test command couldn't run, so the line says nothing about the problem. Fix the command, or use --on-exec-error skip to test neighbouring lines instead.")
+		}
 		return err
 	}
+	if emitScript != "" {
+		if err := writeScript(bisector, result, cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+	}
+	if outputFormat == "quickfix" {
+		reportQuickfix(cmd.OutOrStdout(), qfName, result)
+		return nil
+	}
+	if ciMode {
+		reportCI(cmd.OutOrStdout(), qfName, result)
+		return nil
+	}
 
 	// Print results
-	const (
-		colorReset  = "\033[0m"
-		colorGreen  = "\033[32m"
-		colorRed    = "\033[31m"
-		colorFaded  = "\033[2m"
-		colorBold   = "\033[1m"
-		separator   = "═════════════════════════════════════════════════════════════"
-	)
-
-	fmt.Println()
-	fmt.Printf("%s%s%s\n", colorGreen, separator, colorReset)
-	fmt.Printf("%s%s✓ Bisection Complete%s\n", colorBold, colorGreen, colorReset)
-	fmt.Printf("%s%s%s\n", colorGreen, separator, colorReset)
-	fmt.Println()
-	fmt.Printf("The first bad line is %s%s%d%s\n", colorBold, colorRed, result.BadLineNumber, colorReset)
+	const separator = "═════════════════════════════════════════════════════════════"
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "%s%s%s\n", colors.Green, separator, colors.Reset)
+	fmt.Fprintf(out, "%s%s✓ Bisection Complete%s\n", colors.Bold, colors.Green, colors.Reset)
+	fmt.Fprintf(out, "%s%s%s\n", colors.Green, separator, colors.Reset)
+	fmt.Fprintln(out)
+	unit := resultUnit(sections, setup)
+	found, color := foundVerdict(result), colors.Red
+	if result.FirstGoodLine > 0 {
+		color = colors.Green
+	}
+	if result.RangeStart < result.RangeEnd {
+		fmt.Fprintf(out, "The first %s %s is one of %ss %s%s%d-%d%s\n", found, unit, unit, colors.Bold, color, result.RangeStart, result.RangeEnd, colors.Reset)
+		fmt.Fprintf(out, "%sSkipped %ss hide where it starts; %s %d is the first %s known to be %s%s\n", colors.Faded, unit, unit, result.BadLineNumber, unit, found, colors.Reset)
+	} else {
+		fmt.Fprintf(out, "The first %s %s is %s%s%d%s\n", found, unit, colors.Bold, color, result.BadLineNumber, colors.Reset)
+	}
+	if !result.Verified {
+		fmt.Fprintf(out, "%s%s %d was assumed %s and never tested%s\n", colors.Faded, capitalize(unit), result.BadLineNumber, found, colors.Reset)
+	}
+	if len(result.Skipped) > 0 {
+		fmt.Fprintf(out, "%sSkipped %ss: %s%s\n", colors.Faded, unit, joinInts(result.Skipped, ", "), colors.Reset)
+	}
 
 	// Display the bad line with context
 	badLineIdx := result.BadLineNumber - 1 // Convert to 0-indexed
-	displayResultContext(lines, badLineIdx)
+	blameNumber := result.BadLineNumber
+	switch {
+	case withinLine && fieldSep == "":
+		fmt.Fprintln(out)
+		displayColumn(out, lines, badLineIdx)
+	case sections != nil:
+		fmt.Fprintln(out)
+		if err := displaySection(out, input, sections, badLineIdx); err != nil {
+			return err
+		}
+		first, _ := sections.Span(badLineIdx)
+		blameNumber = first + 1
+	default:
+		if err := displayResultContext(out, input, badLineIdx); err != nil {
+			return err
+		}
+	}
 
-	fmt.Printf("%sSteps taken:%s %d\n", colorBold, colorReset, result.StepsTaken)
-	fmt.Println()
+	if blame && withinLine {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: --blame can't be combined with --within-line\n")
+	} else if blame {
+		if len(args) == 0 || setup != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: --blame needs a file argument\n")
+		} else if info, err := blameLine(args[0], blameNumber); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+		} else {
+			fmt.Fprintf(out, "%s\n\n", info)
+		}
+	}
+	if setup != nil && setup.describe != nil {
+		if detail := setup.describe(result.BadLineContent); detail != "" {
+			fmt.Fprintf(out, "%s\n\n", detail)
+		}
+	}
+
+	fmt.Fprintf(out, "%sSteps taken:%s %d\n", colors.Bold, colors.Reset, result.StepsTaken)
+	fmt.Fprintln(out)
 
 	return nil
 }
 
-func readInput(args []string) ([]string, bool, error) {
-	var scanner *bufio.Scanner
-	usingStdin := false
+// foundVerdict returns what the line result found is called: the --term-bad
+// term, or the --term-good one with --find-first-good
+func foundVerdict(result *lib.Result) string {
+	if result.FirstGoodLine > 0 {
+		return strings.ToLower(termGood)
+	}
+	return strings.ToLower(termBad)
+}
 
-	if len(args) > 0 {
-		// Read from file
-		file, err := os.Open(args[0])
-		if err != nil {
-			return nil, false, err
-		}
-		defer file.Close()
-		scanner = bufio.NewScanner(file)
+// resultUnit names what the bisection's lines are: characters or fields with
+// --within-line, sections, or the entries of a preset
+func resultUnit(sections *lib.SectionSource, setup *presetSetup) string {
+	switch {
+	case withinLine:
+		return withinUnit()
+	case sections != nil:
+		return "section"
+	case setup != nil && setup.unit != "":
+		return setup.unit
+	}
+	return "line"
+}
+
+// reportInterrupted prints the range an interrupted bisection narrowed the
+// first bad unit down to, prefixed by prefix
+func reportInterrupted(w io.Writer, prefix, unit string, result *lib.Result) {
+	found := foundVerdict(result)
+	if result.RangeStart < result.RangeEnd {
+		fmt.Fprintf(w, "%sInterrupted after %d steps; the first %s %s is one of %ss %d-%d\n", prefix, result.StepsTaken, found, unit, unit, result.RangeStart, result.RangeEnd)
 	} else {
-		// Check if stdin is from pipe or redirect
-		stat, err := os.Stdin.Stat()
-		if err != nil {
-			return nil, false, err
-		}
-		if (stat.Mode() & os.ModeCharDevice) != 0 {
-			return nil, false, fmt.Errorf("no input provided: specify a file argument or pipe/redirect stdin")
-		}
-		scanner = bufio.NewScanner(os.Stdin)
-		usingStdin = true
+		fmt.Fprintf(w, "%sInterrupted after %d steps; the first %s %s is %d\n", prefix, result.StepsTaken, found, unit, result.BadLineNumber)
 	}
+}
 
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// printEstimate times probes with bisector and prints how long bisecting
+// would take
+func printEstimate(ctx context.Context, w io.Writer, bisector lib.Bisector) error {
+	estimator, ok := bisector.(interface {
+		Estimate(ctx context.Context, probes int) (*lib.Estimate, error)
+	})
+	if !ok {
+		return fmt.Errorf("%w: --estimate needs --test or another test flag", errUsage)
+	}
+	e, err := estimator.Estimate(ctx, 2)
+	if err != nil {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, false, err
+	fmt.Fprintln(w)
+	if e.Probes == 0 {
+		fmt.Fprintln(w, "There is nothing left to test between the known good and bad lines")
+		return nil
 	}
+	fmt.Fprintf(w, "Probes took %s on average (%d timed)\n", e.ProbeDuration.Round(time.Millisecond), e.Probes)
+	total := e.Duration.Round(time.Second)
+	if e.Duration < time.Minute {
+		total = e.Duration.Round(100 * time.Millisecond)
+	}
+	fmt.Fprintf(w, "Bisecting %d lines takes up to %d steps, about %s\n", e.Lines, e.Steps, total)
+	return nil
+}
 
-	return lines, usingStdin, nil
+// capitalize upper-cases the first letter of an ASCII word
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
 }
 
-func findBoundaries(lines []string, goodPattern, badPattern string) (int, int, error) {
-	goodIdx := 0
-	badIdx := len(lines) - 1
+// joinInts formats ns separated by sep
+func joinInts(ns []int, sep string) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, sep)
+}
 
-	// Search for good pattern if provided
-	if goodPattern != "" {
-		found := false
-		for i, line := range lines {
-			if strings.Contains(line, goodPattern) {
-				goodIdx = i
-				found = true
-				break
-			}
+// reportContradiction shows what the two conflicting tests printed
+func reportContradiction(w io.Writer, err *lib.ContradictionError) {
+	for _, run := range []struct {
+		step   lib.Step
+		output string
+	}{{err.Earlier, err.EarlierOutput}, {err.Later, err.LaterOutput}} {
+		fmt.Fprintf(w, "Output of the test of line %d (%s):\n", run.step.Index+1, run.step.Verdict)
+		if run.output == "" {
+			fmt.Fprintln(w, "  (none)")
+			continue
 		}
-		if !found {
-			return 0, 0, fmt.Errorf("good pattern %q not found in input", goodPattern)
+		for _, line := range strings.Split(strings.TrimRight(run.output, "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
 		}
 	}
+}
 
-	// Search for bad pattern if provided
-	if badPattern != "" {
-		found := false
-		for i, line := range lines {
-			if strings.Contains(line, badPattern) {
-				badIdx = i
-				found = true
-				break
-			}
+// findBoundaries locates the --good and --bad lines in src, or takes them from
+// --good-line and --bad-line
+func findBoundaries(src lib.Source) (int, int, error) {
+	switch {
+	case goodLine != 0 && goodPattern != "":
+		return 0, 0, fmt.Errorf("%w: --good and --good-line can't be combined", errUsage)
+	case badLine != 0 && badPattern != "":
+		return 0, 0, fmt.Errorf("%w: --bad and --bad-line can't be combined", errUsage)
+	}
+	for _, flag := range []struct {
+		name string
+		line int
+	}{{"--good-line", goodLine}, {"--bad-line", badLine}} {
+		if flag.line < 0 || flag.line > src.Len() {
+			return 0, 0, fmt.Errorf("%w: %s %d is outside the input's %d lines", errUsage, flag.name, flag.line, src.Len())
+		}
+	}
+
+	goodIdx, badIdx, err := findPatterns(src)
+	if err != nil || goodLine == 0 && badLine == 0 {
+		return goodIdx, badIdx, err
+	}
+	if goodLine != 0 {
+		goodIdx = goodLine - 1
+	}
+	if badLine != 0 {
+		badIdx = badLine - 1
+	}
+	if findFirstGood && badIdx >= goodIdx {
+		return 0, 0, fmt.Errorf("%w (good line %d, bad line %d)", lib.ErrGoodBeforeBad, goodIdx+1, badIdx+1)
+	}
+	if !findFirstGood && goodIdx >= badIdx {
+		return 0, 0, fmt.Errorf("%w (good line %d, bad line %d)", lib.ErrBadBeforeGood, goodIdx+1, badIdx+1)
+	}
+	return goodIdx, badIdx, nil
+}
+
+// findPatterns locates the --good and --bad lines in src
+func findPatterns(src lib.Source) (int, int, error) {
+	if !usePatternRE && findFirstGood {
+		return lib.FindFirstGoodBoundaries(src, goodPattern, badPattern)
+	}
+	if !usePatternRE {
+		return lib.FindBoundaries(src, goodPattern, badPattern)
+	}
+	var good, bad *regexp.Regexp
+	var err error
+	if goodPattern != "" {
+		if good, err = regexp.Compile(goodPattern); err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid --good: %v", errUsage, err)
 		}
-		if !found {
-			return 0, 0, fmt.Errorf("bad pattern %q not found in input", badPattern)
+	}
+	if badPattern != "" {
+		if bad, err = regexp.Compile(badPattern); err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid --bad: %v", errUsage, err)
 		}
 	}
+	if findFirstGood {
+		return lib.FindFirstGoodBoundariesRegexp(src, good, bad)
+	}
+	return lib.FindBoundariesRegexp(src, good, bad)
+}
 
-	if goodIdx >= badIdx {
-		return 0, 0, fmt.Errorf("good line (index %d) must come before bad line (index %d)", goodIdx, badIdx)
+// parseSize parses a byte count with an optional K, M, G or T suffix in
+// powers of 1024, e.g. 500M
+func parseSize(s string) (int64, error) {
+	units := map[byte]int64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+	num, mult := strings.TrimSuffix(strings.ToUpper(s), "B"), int64(1)
+	if num != "" {
+		if m, ok := units[num[len(num)-1]]; ok {
+			num, mult = num[:len(num)-1], m
+		}
 	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("%q is not a positive size like 500M or 2G", s)
+	}
+	return n * mult, nil
+}
 
-	return goodIdx, badIdx, nil
+// openInput opens the input file at path. A regular file is indexed so its
+// lines are read on demand instead of held in memory, which lets files larger
+// than RAM be bisected, and is fingerprinted in the same pass; a pipe like
+// <(cmd) is read whole. The file is for the caller to close once done.
+func openInput(path string) (*os.File, lib.Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err == nil && info.Mode().IsRegular() {
+		var indexed indexedInput
+		if indexed, err = indexInput(file, info.Size()); err == nil {
+			return file, indexed, nil
+		}
+	} else if err == nil {
+		var lines lib.Lines
+		if lines, err = lib.ReadLines(file); err == nil {
+			return file, lines, nil
+		}
+	}
+	file.Close()
+	return nil, nil, err
 }
 
-func displayResultContext(lines []string, badIdx int) {
-	const (
-		colorReset = "\033[0m"
-		colorRed   = "\033[31m"
-		colorFaded = "\033[2m"
-		colorBold  = "\033[1m"
-	)
+func readInput(args []string) ([]string, bool, error) {
+	if len(args) > 0 {
+		if isObjectURL(args[0]) {
+			lines, err := readObject(args[0])
+			return lines, false, err
+		}
+		if isJobLogURL(args[0]) {
+			lines, err := readJobLog(args[0])
+			return lines, false, err
+		}
 
-	fmt.Println()
+		// Read from file
+		file, err := os.Open(args[0])
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+		lines, err := lib.ReadLines(file)
+		return lines, false, err
+	}
+
+	// Check if stdin is from pipe or redirect
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		return nil, false, fmt.Errorf("no input provided: specify a file argument or pipe/redirect stdin")
+	}
+	lines, err := lib.ReadLines(os.Stdin)
+	return lines, true, err
+}
+
+// displayResultContext shows the bad line of input with --context lines
+// around it
+func displayResultContext(w io.Writer, input lib.Source, badIdx int) error {
+	fmt.Fprintln(w)
+	for i := max(badIdx-contextLines, 0); i <= min(badIdx+contextLines, input.Len()-1); i++ {
+		line, err := input.Line(i)
+		if err != nil {
+			return err
+		}
+		if i == badIdx {
+			// The bad line is highlighted in red
+			writeContextLine(w, i+1, colors.Bold+colors.Red, line)
+		} else {
+			writeContextLine(w, i+1, colors.Faded, line)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
 
-	// Show line before (if exists)
-	if badIdx > 0 {
-		lineNum := badIdx // Line number is badIdx (0-indexed badIdx = line badIdx in 1-indexed)
-		fmt.Printf("%s%4d | %s%s\n", colorFaded, lineNum, lines[badIdx-1], colorReset)
+// writeContextLine writes line numbered num in style, wrapped to the terminal
+// width with the continuation pieces under an empty gutter
+func writeContextLine(w io.Writer, num int, style, line string) {
+	for i, piece := range lib.WrapLine(displayLine(line), displayWidth-len("  42 | ")) {
+		if i == 0 {
+			fmt.Fprintf(w, "%s%4d | %s%s\n", style, num, piece, colors.Reset)
+		} else {
+			fmt.Fprintf(w, "%s     | %s%s\n", style, piece, colors.Reset)
+		}
 	}
+}
 
-	// Show the bad line (highlighted in red)
-	lineNum := badIdx + 1 // Convert 0-indexed to 1-indexed for display
-	fmt.Printf("%s%s%4d | %s%s%s\n", colorBold, colorRed, lineNum, lines[badIdx], colorReset, colorReset)
+// terminalWidth returns the width of the terminal on stdout, or 0 if stdout
+// isn't one
+func terminalWidth() int {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return 0
+	}
+	return width
+}
 
-	// Show line after (if exists)
-	if badIdx < len(lines)-1 {
-		lineNum := badIdx + 2 // Line after the bad line
-		fmt.Printf("%s%4d | %s%s\n", colorFaded, lineNum, lines[badIdx+1], colorReset)
+// useColor reports whether output is colored: unless --no-color or NO_COLOR
+// (https://no-color.org) is set, when stdout is a terminal
+func useColor() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
 
-	fmt.Println()
+// displayLine escapes control characters in line for the terminal, unless
+// --raw-display is set
+func displayLine(line string) string {
+	if rawDisplay {
+		return line
+	}
+	return lib.EscapeControl(line)
 }
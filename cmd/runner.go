@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	sshTarget string
+	sshOpts   []string
+	sshDir    string
+	dockerImg string
+	mounts    []string
+	dockArgs  []string
+	k8sImage  string
+	k8sNS     string
+	kubeArgs  []string
+	usePTY    bool
+	limitCPU  time.Duration
+	limitMem  string
+	niceness  int
+	shell     string
+)
+
+// addRunnerFlags registers the flags that choose where commands run
+func addRunnerFlags() {
+	rootCmd.Flags().StringVar(&sshTarget, "ssh", "", "Run the test, before and after commands on this host (user@host), copying each candidate file there with scp")
+	rootCmd.Flags().StringArrayVar(&sshOpts, "ssh-opt", nil, "Extra option for ssh and scp, e.g. --ssh-opt=-oPort=2222. May be repeated")
+	rootCmd.Flags().StringVar(&sshDir, "ssh-dir", "/tmp", "Remote directory --ssh copies candidate files to")
+	rootCmd.Flags().StringVar(&dockerImg, "docker", "", "Run the test, before and after commands in a fresh container of this image, with the candidate file mounted under /bsct")
+	rootCmd.Flags().StringArrayVar(&mounts, "mount", nil, "Extra volume for --docker in docker -v syntax, e.g. ./src:/src:ro. May be repeated")
+	rootCmd.Flags().StringVar(&k8sImage, "k8s", "", "Run the test, before and after commands as Kubernetes Jobs of this image, with the candidate file in a ConfigMap mounted under /bsct")
+	rootCmd.Flags().StringVar(&k8sNS, "k8s-namespace", "", "Namespace for --k8s Jobs and ConfigMaps")
+	rootCmd.Flags().StringArrayVar(&kubeArgs, "kubectl-arg", nil, "Extra kubectl option for --k8s, e.g. --kubectl-arg=--context=staging. May be repeated")
+	rootCmd.Flags().StringArrayVar(&dockArgs, "docker-arg", nil, "Extra docker run option for --docker, e.g. --docker-arg=--network=host. May be repeated")
+	rootCmd.Flags().BoolVar(&usePTY, "pty", false, "Run the test command on a pseudo-terminal, for tools that behave differently without a TTY. Its stderr is merged into stdout")
+	rootCmd.Flags().DurationVar(&limitCPU, "limit-cpu", 0, "Kill a test command that uses more than this much CPU time, e.g. 5m (rounded up to whole seconds)")
+	rootCmd.Flags().StringVar(&limitMem, "limit-mem", "", "Limit the virtual memory of each test command, e.g. 4G")
+	rootCmd.Flags().IntVar(&niceness, "nice", 0, "Run test commands at this niceness, e.g. 10 to yield to other work on the machine")
+	rootCmd.Flags().StringVar(&shell, "shell", "", "Run the test, before and after commands with this shell instead of sh (cmd on Windows), e.g. bash, zsh, fish, pwsh or a path, optionally with the arguments to give it before the command, like \"bash -euo pipefail -c\"")
+}
+
+// posixShell reports whether --shell, if given, is a POSIX shell that the
+// --limit flags and scripts written by --emit-script work in
+func posixShell() bool {
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		return true
+	}
+	switch strings.TrimSuffix(filepath.Base(fields[0]), ".exe") {
+	case "sh", "bash", "zsh", "dash", "ksh", "ash", "mksh":
+		return true
+	}
+	return false
+}
+
+// buildRunner returns the Runner described by the runner flags, or nil to run
+// commands in the local shell
+func buildRunner() (lib.Runner, error) {
+	given := 0
+	for _, flag := range []string{sshTarget, dockerImg, k8sImage} {
+		if flag != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, fmt.Errorf("only one of --ssh, --docker and --k8s may be given")
+	}
+	if shell != "" && given > 0 {
+		return nil, fmt.Errorf("%w: --shell runs commands locally, so it can't be combined with --ssh, --docker or --k8s", errUsage)
+	}
+
+	switch {
+	case sshTarget != "":
+		return &lib.SSHRunner{Target: sshTarget, Args: sshOpts, RemoteDir: sshDir}, nil
+	case dockerImg != "":
+		return &lib.DockerRunner{Image: dockerImg, Mounts: mounts, Args: dockArgs}, nil
+	case k8sImage != "":
+		return &lib.KubernetesRunner{Image: k8sImage, Namespace: k8sNS, Args: kubeArgs}, nil
+	case shell != "":
+		return lib.ShellRunner{Shell: shell}, nil
+	}
+	return nil, nil
+}
+
+// testRunner returns the Runner for the test command, which is runner itself
+// unless --pty asks for a pseudo-terminal or the --limit flags and --nice
+// hold it to limits
+func testRunner(runner lib.Runner) (lib.Runner, error) {
+	if usePTY {
+		local, ok := runner.(lib.ShellRunner)
+		if runner != nil && !ok {
+			return nil, fmt.Errorf("%w: --pty can't be combined with --ssh, --docker or --k8s", errUsage)
+		}
+		runner = lib.PTYRunner{Shell: local.Shell}
+	}
+
+	limits := lib.Limits{CPU: limitCPU, Nice: niceness}
+	if limitMem != "" {
+		n, err := parseSize(limitMem)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid --limit-mem: %v", errUsage, err)
+		}
+		limits.Memory = n
+	}
+	if limits == (lib.Limits{}) {
+		return runner, nil
+	}
+	if !posixShell() {
+		return nil, fmt.Errorf("%w: --limit-cpu, --limit-mem and --nice need a POSIX shell, not --shell %s", errUsage, shell)
+	}
+	if runner == nil && runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("%w: --limit-cpu, --limit-mem and --nice need a POSIX shell, which Windows doesn't have", errUsage)
+	}
+	return lib.LimitedRunner(runner, limits), nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// emitScript is where --emit-script writes the reproduction script, if set
+var emitScript string
+
+// writeScript writes a script reproducing the test of the bad line in result
+// to emitScript, made executable
+func writeScript(bisector lib.Bisector, result *lib.Result, errOut io.Writer) error {
+	scripter, ok := bisector.(interface {
+		WriteScript(w io.Writer, r *lib.Result) error
+	})
+	if !ok {
+		return fmt.Errorf("%w: --emit-script needs --test", errUsage)
+	}
+	f, err := os.OpenFile(emitScript, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to write --emit-script: %w", err)
+	}
+	err = scripter.WriteScript(f, result)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write --emit-script: %w", err)
+	}
+	fmt.Fprintf(errOut, "Wrote a script reproducing the test of line %d to %s\n", result.BadLineNumber, emitScript)
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	sectionStart string
+	sectionEnd   string
+)
+
+// splitSections groups the lines of src into the sections --section-start
+// and --section-end delimit
+func splitSections(src lib.Source) (*lib.SectionSource, error) {
+	var start, end *regexp.Regexp
+	var err error
+	if sectionStart != "" {
+		if start, err = regexp.Compile(sectionStart); err != nil {
+			return nil, fmt.Errorf("%w: invalid --section-start: %v", errUsage, err)
+		}
+	}
+	if sectionEnd != "" {
+		if end, err = regexp.Compile(sectionEnd); err != nil {
+			return nil, fmt.Errorf("%w: invalid --section-end: %v", errUsage, err)
+		}
+	}
+	return lib.Sections(src, start, end)
+}
+
+// displaySection shows the lines of the bad section with the line before and
+// after it, numbered as in the input. Long sections show their start and end.
+func displaySection(w io.Writer, input lib.Source, sections *lib.SectionSource, badIdx int) error {
+	const shown = 8 // Lines shown at either end of a long section
+
+	first, last := sections.Span(badIdx)
+	fmt.Fprintf(w, "%sSection %d spans lines %d-%d%s\n\n", colors.Faded, badIdx+1, first+1, last+1, colors.Reset)
+	for i := max(first-1, 0); i <= min(last+1, input.Len()-1); i++ {
+		if i == first+shown && last-first+1 > 2*shown {
+			fmt.Fprintf(w, "%s     | ... %d more lines%s\n", colors.Faded, last-first+1-2*shown, colors.Reset)
+			i = last - shown
+			continue
+		}
+		line, err := input.Line(i)
+		if err != nil {
+			return err
+		}
+		if i < first || i > last {
+			writeContextLine(w, i+1, colors.Faded, line)
+		} else {
+			writeContextLine(w, i+1, colors.Bold+colors.Red, line)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr  string
+	serveStdio bool
+	serveWeb   bool
+	serveToken string
+)
+
+//go:embed web.html
+var webPage []byte
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [file]",
+	Short: "Serve a REST API for driving bisections over the network",
+	Long: `serve starts an HTTP server that lets other tools and remote teammates drive
+bisections programmatically:
+
+  POST   /sessions                 start a session from {"lines": [...]}
+                                   (optionally "good_pattern" and "bad_pattern")
+  GET    /sessions/{id}            current probe and range
+  GET    /sessions/{id}/candidate  content of the current probe's candidate
+  GET    /sessions/{id}/context    input lines around the current probe
+                                   (?around=5 on each side, at most 100)
+  POST   /sessions/{id}/verdict    judge the current probe with {"verdict": "good"}
+  GET    /sessions/{id}/result     outcome once done
+  GET    /sessions/{id}/events     server-sent events for every change
+  DELETE /sessions/{id}            end a session
+  GET    /metrics                  Prometheus metrics for every session
+
+With --token, every /sessions request must send "Authorization: Bearer
+<token>" or a token query parameter. Sessions unused for a day are ended, and
+at most 100 are kept at a time.
+
+With --stdio, the same sessions are driven over JSON-RPC 2.0 on stdin and
+stdout with LSP-style Content-Length framing, for editor plugins. The methods
+are bisect/start, bisect/state, bisect/verdict, bisect/result and
+bisect/cancel, and a bisect/done notification carries the result with a
+diagnostic for the first bad line.
+
+With --web, a session is started for the file (or stdin) and a local web page
+for judging it is opened in the browser. Its URL carries a token generated
+for the page unless --token is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: serve,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().BoolVar(&serveStdio, "stdio", false, "Speak JSON-RPC on stdin and stdout instead of listening for HTTP")
+	serveCmd.Flags().BoolVar(&serveWeb, "web", false, "Bisect the file or stdin from a web page opened in the browser")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Require this bearer token of every session request")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	if serveStdio {
+		return (&lib.RPCServer{}).Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+	}
+
+	if len(args) > 0 && !serveWeb {
+		return fmt.Errorf("a file can only be given with --web")
+	}
+
+	if serveWeb && serveToken == "" {
+		serveToken = rand.Text()
+	}
+	metrics := &lib.PrometheusMetrics{}
+	api := &lib.Server{Metrics: metrics, Token: serveToken}
+	mux := http.NewServeMux()
+	mux.Handle("/sessions", api)
+	mux.Handle("/sessions/", api)
+	mux.Handle("GET /metrics", metrics)
+
+	var id string
+	if serveWeb {
+		lines, _, err := readInput(args)
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		if id, err = api.NewSession(lines, "", ""); err != nil {
+			return err
+		}
+		mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(webPage)
+		})
+	}
+
+	ln, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return err
+	}
+	if serveWeb {
+		page := fmt.Sprintf("http://%s/?session=%s&token=%s", ln.Addr(), id, url.QueryEscape(serveToken))
+		fmt.Fprintf(cmd.OutOrStdout(), "Open %s to bisect\n", page)
+		openBrowser(page)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Listening on http://%s\n", ln.Addr())
+	}
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-cmd.Context().Done()
+		srv.Shutdown(context.Background())
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveMetrics serves Prometheus metrics on /metrics at addr in the
+// background until stop is called
+func serveMetrics(addr string) (metrics *lib.PrometheusMetrics, stop func(), err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	metrics = &lib.PrometheusMetrics{}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	return metrics, func() { srv.Close() }, nil
+}
+
+// openBrowser tries to show url in the default browser. Failing is fine since
+// the URL has been printed.
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	cmd.Start()
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionName string
+	resumed     *savedSession // The session bsct resume is continuing, if any
+)
+
+// savedSession is a --session file: the invocation that started it and the
+// bisector's progress, written after every step
+type savedSession struct {
+	Args  []string        `json:"args"` // Command-line arguments, without the program name
+	Dir   string          `json:"dir"`  // Working directory the arguments are relative to
+	State json.RawMessage `json:"state"`
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <name>",
+	Short: "Resume a bisection started with --session",
+	Long: `resume continues a bisection started with --session <name> where it left off,
+with the same arguments, in the directory it was started from. The input must
+not have changed since.`,
+	Args: cobra.ExactArgs(1),
+	RunE: resume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
+
+// sessionPath returns where the session called name is kept
+func sessionPath(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: invalid session name %q", errUsage, name)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bsct", "sessions", name+".json"), nil
+}
+
+func resume(cmd *cobra.Command, args []string) error {
+	path, err := sessionPath(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no session named %s", args[0])
+	} else if err != nil {
+		return err
+	}
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to read session %s: %w", args[0], err)
+	}
+	if err := os.Chdir(saved.Dir); err != nil {
+		return fmt.Errorf("failed to return to the session's directory: %w", err)
+	}
+
+	if err := rootCmd.ParseFlags(saved.Args); err != nil {
+		return fmt.Errorf("failed to read session %s: %w", args[0], err)
+	}
+	if sessionName != args[0] {
+		return fmt.Errorf("session %s was saved under another name", args[0])
+	}
+	resumed = &saved
+	fmt.Fprintf(cmd.ErrOrStderr(), "Resuming session %s: bsct %s\n", args[0], strings.Join(saved.Args, " "))
+	return run(rootCmd, rootCmd.Flags().Args())
+}
+
+// session saves the progress of a --session bisection after every step
+type session struct {
+	name, path string
+	saved      savedSession
+	bisector   lib.Bisector
+	errOut     io.Writer
+}
+
+// newSession starts the session called sessionName, or continues the one
+// being resumed. A new session can't take the name of one that exists.
+func newSession(errOut io.Writer) (*session, error) {
+	path, err := sessionPath(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{name: sessionName, path: path, errOut: errOut}
+	if resumed != nil {
+		s.saved = *resumed
+		return s, nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: session %s already exists; continue it with bsct resume %s or delete %s", errUsage, s.name, s.name, path)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	s.saved = savedSession{Args: invocationArgs(), Dir: dir}
+	return s, nil
+}
+
+// start loads the progress of a resumed session into bisector and saves it,
+// so an interrupted session can be resumed even before its first step
+func (s *session) start(bisector lib.Bisector) error {
+	s.bisector = bisector
+	if resumed != nil {
+		if err := bisector.Load(bytes.NewReader(s.saved.State)); err != nil {
+			return fmt.Errorf("failed to resume session %s: %w", s.name, err)
+		}
+	}
+	return s.save()
+}
+
+// observer saves the session whenever a verdict narrows the range
+func (s *session) observer() lib.Observer {
+	return lib.Observer{OnRangeNarrowed: func(goodIdx, badIdx int) {
+		if err := s.save(); err != nil {
+			fmt.Fprintf(s.errOut, "Warning: failed to save session %s: %v\n", s.name, err)
+		}
+	}}
+}
+
+// save writes the bisector's progress to the session file, through a temp
+// file so an interruption never leaves half of it
+func (s *session) save() error {
+	var state bytes.Buffer
+	if err := s.bisector.Save(&state); err != nil {
+		return err
+	}
+	s.saved.State = state.Bytes()
+	data, err := json.MarshalIndent(s.saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+s.name+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// finish removes the session file once the bisection is over, or says how to
+// resume it when it stopped early
+func (s *session) finish(err error) {
+	if err == nil {
+		os.Remove(s.path)
+		return
+	}
+	if _, statErr := os.Stat(s.path); statErr == nil {
+		fmt.Fprintf(s.errOut, "Session %s saved; continue it with: bsct resume %s\n", s.name, s.name)
+	}
+}
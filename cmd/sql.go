@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	sqlQuery  string
+	dsn       string
+	sqlFormat string
+)
+
+// readQuery runs --sql against --dsn with the database's own CLI and returns
+// one line per row. CSV candidates start with the header line so every
+// candidate is a valid file on its own.
+func readQuery() ([]string, lib.Source, error) {
+	if dsn == "" {
+		return nil, nil, fmt.Errorf("%w: --sql needs --dsn", errUsage)
+	}
+	if sqlFormat != "csv" && sqlFormat != "json" {
+		return nil, nil, fmt.Errorf("%w: invalid --sql-format %q: must be csv or json", errUsage, sqlFormat)
+	}
+
+	header, rows, err := queryRows(dsn, sqlQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		if sqlFormat == "json" {
+			lines[i] = jsonRow(header, row)
+		} else {
+			lines[i] = csvRow(row)
+		}
+	}
+	if sqlFormat == "json" {
+		return lines, nil, nil
+	}
+	return lines, lib.Framed(lib.Lines(lines), csvRow(header)+"\n", ""), nil
+}
+
+// queryRows runs query with psql, mysql or sqlite3 depending on the DSN and
+// returns the column names and rows
+func queryRows(dsn, query string) ([]string, [][]string, error) {
+	var cmd *exec.Cmd
+	tsv := false
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		conn, pass, err := splitPostgresPassword(dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --dsn: %w", err)
+		}
+		cmd = exec.Command("psql", conn, "--csv", "--no-psqlrc", "--quiet", "-c", query)
+		// Keep the password out of the process list
+		if pass != "" {
+			cmd.Env = append(os.Environ(), "PGPASSWORD="+pass)
+		}
+	case strings.HasPrefix(dsn, "mysql://"):
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --dsn: %w", err)
+		}
+		args := []string{"--batch", "--host", u.Hostname(), "--user", u.User.Username()}
+		if port := u.Port(); port != "" {
+			args = append(args, "--port", port)
+		}
+		args = append(args, strings.TrimPrefix(u.Path, "/"), "-e", query)
+		cmd = exec.Command("mysql", args...)
+		// Keep the password out of the process list
+		if pass, ok := u.User.Password(); ok {
+			cmd.Env = append(os.Environ(), "MYSQL_PWD="+pass)
+		}
+		tsv = true
+	case strings.HasPrefix(dsn, "sqlite:"):
+		cmd = exec.Command("sqlite3", "-csv", "-header", strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite:"), "//"), query)
+	default:
+		return nil, nil, fmt.Errorf("%w: --dsn must start with postgres://, mysql:// or sqlite:", errUsage)
+	}
+
+	out, err := runToolCmd(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records [][]string
+	if tsv {
+		records = parseMySQLBatch(out)
+	} else if records, err = csv.NewReader(bytes.NewReader(out)).ReadAll(); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse query output: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("query returned no columns")
+	}
+	return records[0], records[1:], nil
+}
+
+// splitPostgresPassword removes the password from a postgres:// DSN, whether
+// it is in the user info or a password parameter, and returns it separately
+func splitPostgresPassword(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	pass, _ := u.User.Password()
+	if u.User != nil {
+		u.User = url.User(u.User.Username())
+	}
+	if q := u.Query(); q.Has("password") {
+		if pass == "" {
+			pass = q.Get("password")
+		}
+		q.Del("password")
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), pass, nil
+}
+
+// parseMySQLBatch splits mysql --batch output, which is tab-separated with
+// backslash escapes, into records
+func parseMySQLBatch(out []byte) [][]string {
+	unescape := strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\0`, "\x00", `\\`, `\`)
+	var records [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		for i, f := range fields {
+			fields[i] = unescape.Replace(f)
+		}
+		records = append(records, fields)
+	}
+	return records
+}
+
+// csvRow encodes one row as a CSV line without its line ending
+func csvRow(row []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(row)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonRow encodes one row as a JSON object with the columns in query order
+func jsonRow(header, row []string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range header {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(col)
+		value := ""
+		if i < len(row) {
+			value = row[i]
+		}
+		val, _ := json.Marshal(value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPostgresPassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		dsn  string
+		conn string
+		pass string
+	}{
+		{"no password", "postgres://etl@db/shop", "postgres://etl@db/shop", ""},
+		{"user info", "postgres://etl:s3cret@db:5432/shop?sslmode=require", "postgres://etl@db:5432/shop?sslmode=require", "s3cret"},
+		{"escaped", "postgresql://etl:p%40ss@db/shop", "postgresql://etl@db/shop", "p@ss"},
+		{"parameter", "postgres://db/shop?password=s3cret&user=etl", "postgres://db/shop?user=etl", "s3cret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, pass, err := splitPostgresPassword(tc.dsn)
+			require.NoError(t, err)
+			assert.Equal(t, tc.conn, conn)
+			assert.Equal(t, tc.pass, pass)
+		})
+	}
+}
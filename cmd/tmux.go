@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+// tmuxViewer keeps a tmux pane next to bsct showing the candidate being
+// judged, so interactive decisions can be made with all of it in view
+type tmuxViewer struct {
+	path     string              // File the pane displays
+	pane     string              // tmux pane ID, e.g. %3
+	prepared map[int]chan string // Files for the probes that may come next, by line index
+}
+
+// newTmuxViewer splits the current tmux window for the viewer pane
+func newTmuxViewer() (*tmuxViewer, error) {
+	if os.Getenv("TMUX") == "" {
+		return nil, fmt.Errorf("--tmux must be used inside a tmux session")
+	}
+
+	f, err := os.CreateTemp("", "bsct-view-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	v := &tmuxViewer{path: f.Name()}
+	pane, err := tmux("split-window", "-h", "-d", "-P", "-F", "#{pane_id}", v.pager())
+	if err != nil {
+		os.Remove(v.path)
+		return nil, err
+	}
+	v.pane = pane
+	return v, nil
+}
+
+// observer shows every probe's candidate in the pane as it comes up. While
+// one is being judged, the candidates for either answer are written in the
+// background, so the next one shows up at once even for huge inputs.
+func (v *tmuxViewer) observer() lib.Observer {
+	return lib.Observer{
+		OnStep: func(p lib.Probe) {
+			err := v.show(p)
+			v.prepare(p)
+			if err == nil {
+				// Restart the pager so it opens at the end, where the probed line is
+				tmux("respawn-pane", "-k", "-t", v.pane, v.pager())
+			}
+		},
+	}
+}
+
+// show puts p's candidate in the viewed file, using a prepared one if there is
+// one, and discards the other prepared files
+func (v *tmuxViewer) show(p lib.Probe) error {
+	ready, ok := v.prepared[p.Index]
+	delete(v.prepared, p.Index)
+	go v.discardPrepared(v.prepared)
+	v.prepared = nil
+	if ok {
+		if path := <-ready; path != "" {
+			return os.Rename(path, v.path)
+		}
+	}
+	return writeFile(v.path, p.Candidate)
+}
+
+// prepare starts writing the candidates that follow p for either verdict
+func (v *tmuxViewer) prepare(p lib.Probe) {
+	v.prepared = make(map[int]chan string)
+	for _, verdict := range []lib.Verdict{lib.Good, lib.Bad} {
+		next, ok := p.Next(verdict)
+		if !ok {
+			continue
+		}
+		ready := make(chan string, 1)
+		v.prepared[next.Index] = ready
+		go func() {
+			f, err := os.CreateTemp("", "bsct-view-*.txt")
+			if err != nil {
+				ready <- ""
+				return
+			}
+			f.Close()
+			if err := writeFile(f.Name(), next); err != nil {
+				os.Remove(f.Name())
+				ready <- ""
+				return
+			}
+			ready <- f.Name()
+		}()
+	}
+}
+
+// discardPrepared removes prepared files once they are written
+func (v *tmuxViewer) discardPrepared(prepared map[int]chan string) {
+	for _, ready := range prepared {
+		if path := <-ready; path != "" {
+			os.Remove(path)
+		}
+	}
+}
+
+// writeFile writes c's content to path
+func writeFile(path string, c lib.Candidate) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = c.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// pager is the command the pane runs
+func (v *tmuxViewer) pager() string {
+	return "less -N +G " + shellQuote(v.path)
+}
+
+// Close removes the pane and its file
+func (v *tmuxViewer) Close() error {
+	_, err := tmux("kill-pane", "-t", v.pane)
+	v.discardPrepared(v.prepared)
+	os.Remove(v.path)
+	return err
+}
+
+// tmux runs tmux with args and returns its trimmed output
+func tmux(args ...string) (string, error) {
+	out, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/knpwrs/bsct/lib"
+	"github.com/spf13/cobra"
+)
+
+// watchPoll is how often --watch checks the input file for changes
+const watchPoll = 500 * time.Millisecond
+
+var (
+	watch      bool
+	watchCache *lib.MemoryCache // Verdicts shared by every bisection of a --watch run
+)
+
+// watchInput bisects the input file, then bisects it again each time it
+// changes until interrupted. Candidates that were already tested keep their
+// verdicts, so unchanged prefixes cost nothing to re-check.
+func watchInput(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 || preset != "" {
+		return fmt.Errorf("%w: --watch needs an input file", errUsage)
+	}
+	if testCommand == "" && !usesOtherTest(cmd) {
+		return fmt.Errorf("%w: --watch needs --test or another test flag", errUsage)
+	}
+	watchCache = lib.NewMemoryCache()
+
+	for {
+		last, err := os.Stat(args[0])
+		if err != nil {
+			return err
+		}
+
+		// Keep watching through errors, e.g. a file caught half-regenerated
+		if err := bisectOnce(cmd, args); err != nil {
+			if errors.Is(err, lib.ErrInterrupted) {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Watching %s for changes...\n", args[0])
+		if err := waitForChange(cmd, args[0], last); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s changed, bisecting again\n\n", args[0])
+	}
+}
+
+// waitForChange polls path until its size or modification time differs from
+// last
+func waitForChange(cmd *cobra.Command, path string, last os.FileInfo) error {
+	ticker := time.NewTicker(watchPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cmd.Context().Done():
+			return fmt.Errorf("%w: %w", lib.ErrInterrupted, cmd.Context().Err())
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// The file may be mid-replacement; look again next tick
+			continue
+		}
+		if info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/knpwrs/bsct/lib"
+)
+
+var (
+	withinLine bool
+	fieldSep   string
+)
+
+// splitWithin turns the single input line into the parts --within-line
+// bisects: its fields between --field-sep, or its characters
+func splitWithin(lines []string) ([]string, lib.Source, error) {
+	if len(lines) != 1 {
+		return nil, nil, fmt.Errorf("%w: --within-line needs input with exactly one line, not %d; pick one with e.g. sed -n 5p", errUsage, len(lines))
+	}
+	parts := lib.SplitLine(lines[0], fieldSep)
+	if len(parts) < 2 {
+		return nil, nil, fmt.Errorf("%w: --within-line found only one part to bisect in the line", errUsage)
+	}
+	return parts, lib.Joined(parts, fieldSep), nil
+}
+
+// withinUnit names what --within-line bisects, in place of "line"
+func withinUnit() string {
+	if fieldSep != "" {
+		return "field"
+	}
+	return "character"
+}
+
+// displayColumn shows the bad character of a --within-line bisection among
+// the characters around it, with its byte offset in the line
+func displayColumn(w io.Writer, parts []string, badIdx int) {
+	const around = 30 // Characters shown on either side
+
+	offset := 0
+	for _, p := range parts[:badIdx] {
+		offset += len(p)
+	}
+	before := strings.Join(parts[max(badIdx-around, 0):badIdx], "")
+	after := strings.Join(parts[badIdx+1:min(badIdx+1+around, len(parts))], "")
+	fmt.Fprintf(w, "%sColumn %d, byte offset %d of the line%s\n\n", colors.Faded, badIdx+1, offset, colors.Reset)
+	fmt.Fprintf(w, "  %s%s%s", colors.Faded, displayLine(before), colors.Reset)
+	fmt.Fprintf(w, "%s%s%s%s", colors.Bold, colors.Red, displayLine(parts[badIdx]), colors.Reset)
+	fmt.Fprintf(w, "%s%s%s\n\n", colors.Faded, displayLine(after), colors.Reset)
+}
@@ -3,14 +3,19 @@ module github.com/knpwrs/bsct
 go 1.25.3
 
 require (
+	github.com/creack/pty v1.1.24
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/spf13/cobra v1.10.1
+	github.com/spf13/pflag v1.0.9
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/sys v0.36.0
+	golang.org/x/term v0.35.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.9 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,16 @@
+package lib
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when info's file was last read, if the platform says
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Atim.Unix()), true
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package lib
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns when info's file was last read, if the platform says.
+// Windows only updates access times lazily, if at all, so they aren't used.
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
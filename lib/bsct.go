@@ -2,43 +2,88 @@ package lib
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
-// Result contains the outcome of a bisection
+// Result contains the outcome of a bisection. With WithFindFirstGood, good and
+// bad swap places: the line found is in FirstGoodLine as well as in
+// BadLineNumber, BadLineContent and Verified, and LastGoodLineNumber is the
+// last line known to be bad before it.
 type Result struct {
-	BadLineNumber  int    // 1-indexed line number
-	BadLineContent string // Content of the bad line
-	StepsTaken     int    // Number of bisection steps
+	FirstGoodLine      int           // 1-indexed first good line with WithFindFirstGood, 0 otherwise
+	BadLineNumber      int           // 1-indexed line number
+	BadLineContent     string        // Content of the bad line
+	StepsTaken         int           // Number of bisection steps
+	LastGoodLineNumber int           // 1-indexed last line known to be good, 0 if none
+	RangeStart         int           // 1-indexed first line that may be the first bad line
+	RangeEnd           int           // 1-indexed last line that may be the first bad line
+	Verified           bool          // Whether the bad line was judged bad by a probe rather than assumed
+	Skipped            []int         // 1-indexed lines whose tests were skipped, in order
+	History            []Step        // Every probe in the order it was made
+	Duration           time.Duration // Time spent bisecting
+	Interrupted        bool          // Whether the bisection stopped early, leaving RangeStart-RangeEnd to search
 }
 
 // Bisector defines the interface for bisection strategies
 type Bisector interface {
 	Bisect() (*Result, error)
+	// BisectContext is like Bisect but stops early once ctx is done, returning
+	// the progress so far with Interrupted set and an error wrapping
+	// ErrInterrupted
+	BisectContext(ctx context.Context) (*Result, error)
+	// Save writes the progress made so far, e.g. after an interrupted bisection
+	Save(w io.Writer) error
+	// Load restores progress written by Save for the same input
+	Load(r io.Reader) error
 }
 
 // InteractiveBisector performs bisection with user prompts
 type InteractiveBisector struct {
-	lines    []string
-	goodIdx  int
-	badIdx   int
-	steps    int
+	search
 	reader   *bufio.Reader
 	ttyFile  *os.File
+	out      io.Writer
+	answered []undoState // Progress before each answer, for undo to go back to
+	context  int         // Lines shown before and after the line asked about
 }
 
-// NewInteractiveBisector creates a new interactive bisector
+// undoState is the progress of an interactive bisection before an answer
+type undoState struct {
+	goodIdx, badIdx int
+	steps           int
+	history         int // Length of the history
+	skipped         map[int]bool
+	c               *Candidate // Line the answer was about, if it was asked about
+}
+
+// NewInteractiveBisector creates a new interactive bisector.
+//
+// Deprecated: use New, which accepts options.
 func NewInteractiveBisector(lines []string, goodIdx, badIdx int, usingStdin bool) *InteractiveBisector {
+	cfg := config{goodIdx: goodIdx, badIdx: badIdx, useTTY: usingStdin, contextLines: 1}
+	return newInteractiveBisector(Lines(lines), cfg)
+}
+
+func newInteractiveBisector(src Source, cfg config) *InteractiveBisector {
 	var reader *bufio.Reader
 	var ttyFile *os.File
 
-	if usingStdin {
+	if cfg.input != nil {
+		reader = bufio.NewReader(cfg.input)
+	} else if cfg.useTTY {
 		// When stdin is used for data, open /dev/tty for interactive prompts
 		var err error
-		ttyFile, err = os.Open("/dev/tty")
+		ttyFile, err = openTTY()
 		if err != nil {
 			// Fallback to stdin if /dev/tty can't be opened
 			reader = bufio.NewReader(os.Stdin)
@@ -50,247 +95,809 @@ func NewInteractiveBisector(lines []string, goodIdx, badIdx int, usingStdin bool
 		reader = bufio.NewReader(os.Stdin)
 	}
 
-	return &InteractiveBisector{
-		lines:   lines,
-		goodIdx: goodIdx,
-		badIdx:  badIdx,
+	b := &InteractiveBisector{
+		search:  cfg.search(src),
 		reader:  reader,
 		ttyFile: ttyFile,
+		out:     cfg.output(),
+		context: cfg.contextLines,
 	}
+	b.search.out = b.out
+	return b
 }
 
 // Bisect performs interactive bisection
 func (b *InteractiveBisector) Bisect() (*Result, error) {
-	const (
-		colorReset = "\033[0m"
-		colorGreen = "\033[32m"
-		colorRed   = "\033[31m"
-		colorBlue  = "\033[34m"
-		colorBold  = "\033[1m"
-		separator  = "─────────────────────────────────────────────────────────────"
-	)
+	return b.BisectContext(context.Background())
+}
+
+// BisectContext performs interactive bisection. If ctx is done before the user
+// has answered every prompt, the progress so far is returned with an error
+// wrapping ErrInterrupted and ctx.Err().
+func (b *InteractiveBisector) BisectContext(ctx context.Context) (*Result, error) {
+	const separator = "─────────────────────────────────────────────────────────────"
 
 	// Ensure tty file is closed when we're done
 	if b.ttyFile != nil {
 		defer b.ttyFile.Close()
 	}
 
-	fmt.Printf("%s%sStarting bisection%s between lines %d and %d (%d lines total)\n",
-		colorBold, colorBlue, colorReset, b.goodIdx+1, b.badIdx+1, len(b.lines))
-	fmt.Println("Type 'g' or 'good' if the line is good, 'b' or 'bad' if the line is bad")
-	fmt.Println()
+	fmt.Fprintf(b.out, "%s%sStarting bisection%s between lines %d and %d (%d lines total)\n",
+		b.colors.Bold, b.colors.Blue, b.colors.Reset, b.goodIdx+1, b.badIdx+1, b.src.Len())
+	fmt.Fprintf(b.out, "Type %s if the line is %s, %s if the line is %s, 's' or 'skip' if it can't be judged\n",
+		b.spelled(Good), b.term(Good), b.spelled(Bad), b.term(Bad))
+	fmt.Fprintf(b.out, "Add lines to mark a whole range at once, e.g. '%s 1-250' or '%s 900-', and type 'u' or 'undo' to take back the last answer\n",
+		b.answerKey(Good), b.answerKey(Bad))
+	fmt.Fprintln(b.out, "Type '+' or '-' to show more or fewer lines of context")
+	fmt.Fprintln(b.out)
+
+	var spanned *spanAnswer // Range answer that decided the current line, if any
+	var pending *undoState  // Progress before the current line was asked about
+	evaluate := func(ctx context.Context, c Candidate) (Verdict, error) {
+		// Visual separator for each step
+		fmt.Fprintf(b.out, "%s%s%s\n", b.colors.Blue, separator, b.colors.Reset)
+		fmt.Fprintf(b.out, "%s%sStep %d:%s Testing line %d of %d\n", b.colors.Bold, b.colors.Blue, b.steps, b.colors.Reset, c.Index+1, b.src.Len())
+		v, answer, err := b.ask(ctx, c, true)
+		if err == nil {
+			// Taken after asking, as ask may have marked ranges already
+			state := b.snapshot(&c)
+			pending = &state
+		}
+		spanned = answer
+		return v, err
+	}
 
-	for b.badIdx-b.goodIdx > 1 {
-		midIdx := b.goodIdx + (b.badIdx-b.goodIdx)/2
-		b.steps++
+	report := func(c Candidate, v Verdict) {
+		// Verdicts reused from an earlier probe weren't answered, so undo
+		// passes over them
+		if pending != nil {
+			b.answered = append(b.answered, *pending)
+			pending = nil
+		}
+		marked := "as"
+		if spanned != nil {
+			// A range answer that decided c may reach past it
+			if spanned.step.Index != c.Index {
+				b.record(spanned.step.Index, spanned.step.Verdict)
+			}
+			marked = fmt.Sprintf("lines %d-%d as", spanned.first, spanned.last)
+		}
+		switch v {
+		case Good:
+			fmt.Fprintf(b.out, "%s✓ Marked %s %s%s. Searching lines %d-%d\n", b.colors.Green, marked, b.term(v), b.colors.Reset, b.goodIdx+1, b.badIdx+1)
+		case Skip:
+			fmt.Fprintf(b.out, "%s↷ Skipped line %d%s. Searching lines %d-%d around it\n", b.colors.Yellow, c.Index+1, b.colors.Reset, b.goodIdx+1, b.badIdx+1)
+		default:
+			fmt.Fprintf(b.out, "%s✗ Marked %s %s%s. Searching lines %d-%d\n", b.colors.Red, marked, b.term(v), b.colors.Reset, b.goodIdx+1, b.badIdx+1)
+		}
+		fmt.Fprintln(b.out)
+	}
 
-		// Visual separator for each step
-		fmt.Printf("%s%s%s\n", colorBlue, separator, colorReset)
-		fmt.Printf("%s%sStep %d:%s Testing line %d of %d\n", colorBold, colorBlue, b.steps, colorReset, midIdx+1, len(b.lines))
-		b.displayLineWithContext(midIdx)
-		fmt.Print("Is this line good or bad? [g/b]: ")
+	return b.run(ctx, evaluate, report)
+}
 
-		response, err := b.reader.ReadString('\n')
+// Evaluate shows the candidate line with context and prompts until the user
+// answers good, bad or skip, or their WithTerms, which makes InteractiveBisector usable as an Oracle
+func (b *InteractiveBisector) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	v, _, err := b.ask(ctx, c, false)
+	return v, err
+}
+
+// spanAnswer is an answer marking lines first through last (1-indexed), which
+// comes down to the verdict of step
+type spanAnswer struct {
+	first, last int
+	step        Step
+}
+
+// ask prompts for a verdict on c like Evaluate. When bisecting, answers like
+// "g 1-250" or "b 900-" mark whole ranges: one that decides c too is returned
+// along with its verdict, for the caller to record after it, and one that
+// doesn't narrows the search right away before asking about c again. "u"
+// goes back to before the last answer and returns errUndone, and "+" and "-"
+// show more or fewer lines around c.
+func (b *InteractiveBisector) ask(ctx context.Context, c Candidate, bisecting bool) (Verdict, *spanAnswer, error) {
+	if err := b.displayLineWithContext(c.src, c.Index); err != nil {
+		return Bad, nil, err
+	}
+
+	invalid := fmt.Sprintf("%s⚠ Invalid input%s. Please enter %s, %s or 's' (skip)", b.colors.Red, b.colors.Reset, b.choice(Good), b.choice(Bad))
+	for {
+		fmt.Fprintf(b.out, "Is this line %s or %s? [%s/%s/s]: ", b.term(Good), b.term(Bad), b.answerKey(Good), b.answerKey(Bad))
+
+		response, err := b.readResponse(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read input: %w", err)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return Bad, nil, ctxErr
+			}
+			return Bad, nil, fmt.Errorf("failed to read input: %w", err)
 		}
 
 		response = strings.TrimSpace(strings.ToLower(response))
-
-		switch response {
-		case "g", "good":
-			b.goodIdx = midIdx
-			fmt.Printf("%s✓ Marked as good%s. Searching lines %d-%d\n", colorGreen, colorReset, b.goodIdx+1, b.badIdx+1)
-		case "b", "bad":
-			b.badIdx = midIdx
-			fmt.Printf("%s✗ Marked as bad%s. Searching lines %d-%d\n", colorRed, colorReset, b.goodIdx+1, b.badIdx+1)
+		answer, span, hasSpan := strings.Cut(response, " ")
+
+		var v Verdict
+		switch answer {
+		case b.term(Good), b.answerKey(Good):
+			v = Good
+		case b.term(Bad), b.answerKey(Bad):
+			v = Bad
+		case "s", "skip":
+			v = Skip
+		case "+", "-":
+			if hasSpan {
+				fmt.Fprintln(b.out, invalid)
+				continue
+			}
+			if answer == "+" {
+				b.context++
+			} else {
+				b.context = max(b.context-1, 0)
+			}
+			if err := b.displayLineWithContext(c.src, c.Index); err != nil {
+				return Bad, nil, err
+			}
+			continue
+		case "u", "undo":
+			if !bisecting || hasSpan {
+				fmt.Fprintln(b.out, invalid)
+				continue
+			}
+			if len(b.answered) == 0 {
+				fmt.Fprintln(b.out, "Nothing to undo yet")
+				continue
+			}
+			if err := b.undo(); err != nil {
+				return Bad, nil, err
+			}
+			fmt.Fprintf(b.out, "%s↶ Undid the last answer%s. Searching lines %d-%d\n\n", b.colors.Yellow, b.colors.Reset, b.goodIdx+1, b.badIdx+1)
+			return Bad, nil, errUndone
 		default:
-			fmt.Printf("%s⚠ Invalid input%s. Please enter 'g' (good) or 'b' (bad)\n", colorRed, colorReset)
-			b.steps-- // Don't count invalid steps
+			fmt.Fprintln(b.out, invalid)
+			continue
+		}
+		if !hasSpan {
+			return v, nil, nil
+		}
+		if !bisecting || v == Skip {
+			fmt.Fprintln(b.out, invalid+" for this line only")
+			continue
+		}
+
+		first, last, err := parseSpan(strings.TrimSpace(span), b.src.Len())
+		if err != nil {
+			fmt.Fprintf(b.out, "%s⚠ Invalid range%s: %v. Try e.g. '%s 1-250' or '%s 900-'\n", b.colors.Red, b.colors.Reset, err, b.answerKey(Good), b.answerKey(Bad))
+			continue
+		}
+		// Lines like the first up to last mean every line before is like it
+		// too, and lines unlike it from first mean every line after is unlike
+		// it. Unless searching for the first good line, those are good and bad.
+		sv := b.searched(v)
+		step := Step{Index: last - 1, Verdict: v}
+		if sv == Bad {
+			step.Index = first - 1
+		}
+		switch {
+		case sv == Good && step.Index >= b.badIdx:
+			fmt.Fprintf(b.out, "%s⚠ Line %d is already known to be %s%s\n", b.colors.Red, b.badIdx+1, b.term(b.searched(Bad)), b.colors.Reset)
+			continue
+		case sv == Bad && step.Index <= b.goodIdx:
+			fmt.Fprintf(b.out, "%s⚠ Line %d is already known to be %s%s\n", b.colors.Red, b.goodIdx+1, b.term(b.searched(Good)), b.colors.Reset)
+			continue
+		case sv == Good && step.Index <= b.goodIdx, sv == Bad && step.Index >= b.badIdx:
+			fmt.Fprintf(b.out, "Lines %d-%d are already known to be %s. Searching lines %d-%d\n", first, last, b.term(v), b.goodIdx+1, b.badIdx+1)
+			continue
+		case sv == Good && step.Index >= c.Index, sv == Bad && step.Index <= c.Index:
+			return v, &spanAnswer{first: first, last: last, step: step}, nil
+		}
+
+		b.answered = append(b.answered, b.snapshot(nil))
+		b.record(step.Index, step.Verdict)
+		b.notifyRangeNarrowed()
+		if v == Good {
+			fmt.Fprintf(b.out, "%s✓ Marked lines %d-%d as %s%s. Searching lines %d-%d\n", b.colors.Green, first, last, b.term(v), b.colors.Reset, b.goodIdx+1, b.badIdx+1)
+		} else {
+			fmt.Fprintf(b.out, "%s✗ Marked lines %d-%d as %s%s. Searching lines %d-%d\n", b.colors.Red, first, last, b.term(v), b.colors.Reset, b.goodIdx+1, b.badIdx+1)
 		}
-		fmt.Println()
 	}
+}
 
-	return &Result{
-		BadLineNumber:  b.badIdx + 1, // Convert to 1-indexed
-		BadLineContent: b.lines[b.badIdx],
-		StepsTaken:     b.steps,
-	}, nil
+// answerKey returns the short answer for v at the prompt: the first letter of
+// its term, or the whole term when the first letters would be ambiguous
+func (b *InteractiveBisector) answerKey(v Verdict) string {
+	good, bad := b.term(Good), b.term(Bad)
+	g, _ := utf8.DecodeRuneInString(good)
+	r, _ := utf8.DecodeRuneInString(bad)
+	if g == r || strings.ContainsRune("su", g) || strings.ContainsRune("su", r) {
+		return b.term(v)
+	}
+	if v == Good {
+		return string(g)
+	}
+	return string(r)
 }
 
-// displayLineWithContext shows the line being tested with context lines above and below
-func (b *InteractiveBisector) displayLineWithContext(idx int) {
-	const (
-		// ANSI color codes
-		colorReset  = "\033[0m"
-		colorFaded  = "\033[2m"      // Faded/dim text
-		colorCyan   = "\033[36m"     // Cyan for line number being tested
-		colorBold   = "\033[1m"      // Bold for emphasis
-	)
+// spelled lists the answers for v, like 'g' or 'good'
+func (b *InteractiveBisector) spelled(v Verdict) string {
+	if key := b.answerKey(v); key != b.term(v) {
+		return fmt.Sprintf("'%s' or '%s'", key, b.term(v))
+	}
+	return fmt.Sprintf("'%s'", b.term(v))
+}
 
-	fmt.Println()
+// choice describes the short answer for v, like 'g' (good)
+func (b *InteractiveBisector) choice(v Verdict) string {
+	if key := b.answerKey(v); key != b.term(v) {
+		return fmt.Sprintf("'%s' (%s)", key, b.term(v))
+	}
+	return fmt.Sprintf("'%s'", b.term(v))
+}
 
-	// Show line before (if exists)
-	if idx > 0 {
-		lineNum := idx // 0-indexed
-		fmt.Printf("%s%4d | %s%s\n", colorFaded, lineNum, b.lines[idx-1], colorReset)
+// snapshot returns the progress so far, with the step being asked about not
+// taken yet, for an answer about c, if any
+func (b *InteractiveBisector) snapshot(c *Candidate) undoState {
+	return undoState{
+		goodIdx: b.goodIdx,
+		badIdx:  b.badIdx,
+		steps:   b.steps - 1,
+		history: len(b.history),
+		skipped: maps.Clone(b.skipped),
+		c:       c,
 	}
+}
 
-	// Show current line being tested (highlighted)
-	lineNum := idx + 1 // 1-indexed for display
-	fmt.Printf("%s%s%4d%s | %s%s\n", colorBold, colorCyan, lineNum, colorReset, b.lines[idx], colorReset)
+// undo goes back to the progress before the last answer, forgetting the
+// verdict it gave so its line is asked about again
+func (b *InteractiveBisector) undo() error {
+	state := b.answered[len(b.answered)-1]
+	b.answered = b.answered[:len(b.answered)-1]
+	b.goodIdx, b.badIdx, b.steps = state.goodIdx, state.badIdx, state.steps
+	b.history = b.history[:state.history]
+	b.skipped = state.skipped
+	if state.c != nil {
+		id, err := b.candidateID(*state.c)
+		if err != nil {
+			return err
+		}
+		delete(b.seen, id)
+	}
+	b.notifyRangeNarrowed()
+	return nil
+}
 
-	// Show line after (if exists)
-	if idx < len(b.lines)-1 {
-		lineNum := idx + 2 // 0-indexed + 2
-		fmt.Printf("%s%4d | %s%s\n", colorFaded, lineNum, b.lines[idx+1], colorReset)
+// parseSpan parses a 1-indexed inclusive range of n lines like "1-250",
+// "900-" (through the last line), "-250" (from the first) or a single "42"
+func parseSpan(s string, n int) (first, last int, err error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	first, last = 1, n
+	if from != "" {
+		if first, err = strconv.Atoi(from); err != nil {
+			return 0, 0, fmt.Errorf("%q is not a line number", from)
+		}
+	}
+	if to != "" {
+		if last, err = strconv.Atoi(to); err != nil {
+			return 0, 0, fmt.Errorf("%q is not a line number", to)
+		}
 	}
+	switch {
+	case from == "" && to == "":
+		return 0, 0, errors.New("no lines given")
+	case first < 1 || first > n || last > n:
+		return 0, 0, fmt.Errorf("lines are numbered 1-%d", n)
+	case first > last:
+		return 0, 0, fmt.Errorf("%d-%d ends before it starts", first, last)
+	}
+	return first, last, nil
+}
 
-	fmt.Println()
+// readResponse reads one line of user input, giving up as soon as ctx is done.
+// A read that is abandoned this way keeps running in the background until the
+// reader returns.
+func (b *InteractiveBisector) readResponse(ctx context.Context) (string, error) {
+	type readResult struct {
+		response string
+		err      error
+	}
+
+	done := make(chan readResult, 1)
+	go func() {
+		response, err := b.reader.ReadString('\n')
+		done <- readResult{response, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-done:
+		return r.response, r.err
+	}
+}
+
+// displayLineWithContext shows the line being tested with b.context lines
+// above and below, wrapping long lines under their line numbers to fit
+// WithDisplayWidth
+func (b *InteractiveBisector) displayLineWithContext(src Source, idx int) error {
+	fmt.Fprintln(b.out)
+	for i := max(idx-b.context, 0); i <= min(idx+b.context, src.Len()-1); i++ {
+		line, err := src.Line(i)
+		if err != nil {
+			return err
+		}
+		if i == idx {
+			// The line being tested is highlighted
+			writeNumbered(b.out, b.colors, i+1, b.colors.Bold+b.colors.Cyan, "", b.display(line), b.displayWidth)
+		} else {
+			writeNumbered(b.out, b.colors, i+1, b.colors.Faded, b.colors.Faded, b.display(line), b.displayWidth)
+		}
+	}
+	fmt.Fprintln(b.out)
+	return nil
 }
 
-// AutomaticBisector performs bisection using a test command
+// AutomaticBisector performs bisection by asking an Oracle, by default one that
+// runs a test command, about each step
 type AutomaticBisector struct {
-	lines         []string
-	goodIdx       int
-	badIdx        int
-	steps         int
+	search
+	oracle        Oracle
 	testCommand   string
 	beforeCommand string
 	afterCommand  string
+	runner        Runner
+	prewarm       bool
+	budget        *tempBudget
+	checkTest     bool
+	onExecError   Action
+	onCrash       Action
+	targetPath    string
+	target        *target // The open WithTarget file while bisecting
+	sync          bool
+	checkReads    bool        // Whether to warn about a test command that never reads the candidate
+	candidateRead atomic.Bool // Whether a test was seen reading its candidate
+	runs          runs
+	stream        io.Writer // Receives the output of tests as they run, if set
+	out           io.Writer
+	errOut        io.Writer
 }
 
-// NewAutomaticBisector creates a new automatic bisector
+// NewAutomaticBisector creates a new automatic bisector.
+//
+// Deprecated: use New with WithTestCommand.
 func NewAutomaticBisector(lines []string, goodIdx, badIdx int, testCommand, beforeCommand, afterCommand string) *AutomaticBisector {
-	return &AutomaticBisector{
-		lines:         lines,
+	cfg := config{
 		goodIdx:       goodIdx,
 		badIdx:        badIdx,
 		testCommand:   testCommand,
 		beforeCommand: beforeCommand,
 		afterCommand:  afterCommand,
+		onCrash:       ActionBad,
+	}
+	return newAutomaticBisector(Lines(lines), cfg)
+}
+
+func newAutomaticBisector(src Source, cfg config) *AutomaticBisector {
+	oracle := cfg.oracle
+	if oracle == nil {
+		oracle = &CommandOracle{Command: cfg.testCommand, Runner: cfg.commandRunner()}
+	}
+
+	b := &AutomaticBisector{
+		search:        cfg.search(src),
+		oracle:        oracle,
+		testCommand:   cfg.testCommand,
+		beforeCommand: cfg.beforeCommand,
+		afterCommand:  cfg.afterCommand,
+		runner:        cfg.commandRunner(),
+		prewarm:       cfg.prewarm && cfg.beforeCommand != "" && cfg.target == "",
+		budget:        cfg.tempBudget(),
+		checkTest:     cfg.checkTest,
+		onExecError:   cfg.onExecError,
+		onCrash:       cfg.onCrash,
+		targetPath:    cfg.target,
+		sync:          cfg.sync,
+		checkReads:    readsFile(cfg.testCommand),
+		out:           cfg.output(),
+		errOut:        cfg.errorOutput(),
+	}
+	b.runs.mode = cfg.candidateMode
+	b.runs.firstGood = cfg.firstGood
+	if cfg.streamOutput != nil {
+		b.stream = &syncWriter{w: cfg.streamOutput}
+	}
+	if b.prewarm {
+		// Prewarmed before commands write alongside the running test
+		b.out = &syncWriter{w: b.out}
+		b.errOut = &syncWriter{w: b.errOut}
 	}
+	b.search.out = b.out
+	return b
 }
 
 // Bisect performs automatic bisection using the test command
 func (b *AutomaticBisector) Bisect() (*Result, error) {
-	fmt.Printf("Starting automatic bisection between lines %d and %d (%d lines total)\n",
-		b.goodIdx+1, b.badIdx+1, len(b.lines))
-	fmt.Printf("Test command: %s\n", b.testCommand)
-	fmt.Println()
-
-	for b.badIdx-b.goodIdx > 1 {
-		midIdx := b.goodIdx + (b.badIdx-b.goodIdx)/2
-		b.steps++
+	return b.BisectContext(context.Background())
+}
 
-		fmt.Printf("Step %d: Testing line %d of %d\n", b.steps, midIdx+1, len(b.lines))
-		fmt.Printf("Line content: %s\n", b.lines[midIdx])
+// BisectContext performs automatic bisection using the test command. When ctx
+// is done, the running command is killed and the progress so far is returned
+// with an error wrapping ErrInterrupted.
+func (b *AutomaticBisector) BisectContext(ctx context.Context) (_ *Result, err error) {
+	fmt.Fprintf(b.out, "Starting automatic bisection between lines %d and %d (%d lines total)\n",
+		b.goodIdx+1, b.badIdx+1, b.src.Len())
+	if b.testCommand != "" {
+		fmt.Fprintf(b.out, "Test command: %s\n", b.testCommand)
+	}
+	fmt.Fprintln(b.out)
+	restore, err := b.useTarget()
+	if err != nil {
+		return nil, err
+	}
+	defer restore(&err)
+	if b.checkTest {
+		if err := b.checkBoundaries(ctx); err != nil {
+			return nil, err
+		}
+	}
 
-		// Create temporary file with content up to midIdx
-		tmpFile, err := os.CreateTemp("", "bsct-*.txt")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create temp file: %w", err)
+	// Every step updates the same candidate file, and prewarming needs a
+	// second one for the probe after it
+	file, err := newCandidateFile(b.budget)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { file.remove() }() // Prewarm hits swap it with spare
+	var spare *candidateFile
+	var warm *prewarm
+	if b.prewarm {
+		if spare, err = newCandidateFile(b.budget); err != nil {
+			return nil, err
 		}
-		tmpPath := tmpFile.Name()
-		defer os.Remove(tmpPath)
+		defer func() {
+			if warm != nil {
+				warm.cancel(errWrongGuess)
+				if st := <-warm.done; st != nil {
+					b.finish(ctx, st)
+				}
+			}
+			spare.remove()
+			if warm != nil {
+				warm.panic.raise()
+			}
+		}()
+	}
 
-		// Write lines from beginning through midIdx
-		for i := 0; i <= midIdx; i++ {
-			if _, err := tmpFile.WriteString(b.lines[i] + "\n"); err != nil {
-				tmpFile.Close()
-				return nil, fmt.Errorf("failed to write temp file: %w", err)
+	evaluate := func(ctx context.Context, c Candidate) (Verdict, error) {
+		fmt.Fprintf(b.out, "Step %d: Testing line %d of %d\n", b.steps, c.Index+1, b.src.Len())
+		fmt.Fprintf(b.out, "Line content: %s\n", b.display(c.Line))
+
+		var st *staged
+		if warm != nil {
+			if warm.index != c.Index {
+				warm.cancel(errWrongGuess)
+			}
+			prepared := <-warm.done
+			warm.cancel(nil)
+			if p := warm.panic; p != nil {
+				warm = nil // Already waited for
+				p.raise()
 			}
+			if prepared != nil && warm.index == c.Index {
+				st = prepared
+				file, spare = spare, file
+			} else if prepared != nil {
+				b.finish(ctx, prepared)
+			}
+			warm = nil
 		}
-		tmpFile.Close()
-
-		// Run before command if provided
-		if b.beforeCommand != "" {
-			beforeCmdStr := b.buildCommand(tmpPath, b.lines[midIdx], b.beforeCommand)
-			fmt.Printf("Running before command: %s\n", beforeCmdStr)
-			beforeCmd := b.createCommand(beforeCmdStr)
-			beforeCmd.Stdout = os.Stdout
-			beforeCmd.Stderr = os.Stderr
-			if err := beforeCmd.Run(); err != nil {
-				fmt.Printf("Warning: before command failed: %v\n", err)
+		if st == nil {
+			var err error
+			if st, err = b.prepare(ctx, c, file); err != nil {
+				return Bad, err
 			}
 		}
+		if b.prewarm {
+			warm = b.startPrewarm(ctx, c, spare)
+		}
+		run, err := b.test(ctx, st, fmt.Sprintf("step %d", b.steps))
+		if err != nil {
+			return Bad, err
+		}
+		if err := b.runs.check(run); err != nil {
+			return Bad, err
+		}
+		b.runs.add(run)
+		return run.step.Verdict, nil
+	}
+
+	report := func(c Candidate, v Verdict) {
+		switch v {
+		case Good:
+			fmt.Fprintf(b.out, "Test passed (%s). Searching lines %d-%d\n\n", b.term(v), b.goodIdx+1, b.badIdx+1)
+		case Bad:
+			fmt.Fprintf(b.out, "Test failed (%s). Searching lines %d-%d\n\n", b.term(v), b.goodIdx+1, b.badIdx+1)
+		default:
+			fmt.Fprintf(b.out, "Test skipped. Searching lines %d-%d\n\n", b.goodIdx+1, b.badIdx+1)
+		}
+	}
 
-		// Build and run test command with placeholder substitution
-		cmdStr := b.buildCommand(tmpPath, b.lines[midIdx], b.testCommand)
-		cmd := b.createCommand(cmdStr)
-		err = cmd.Run()
-
-		// Run after command if provided
-		if b.afterCommand != "" {
-			afterCmdStr := b.buildCommand(tmpPath, b.lines[midIdx], b.afterCommand)
-			fmt.Printf("Running after command: %s\n", afterCmdStr)
-			afterCmd := b.createCommand(afterCmdStr)
-			afterCmd.Stdout = os.Stdout
-			afterCmd.Stderr = os.Stderr
-			if afterErr := afterCmd.Run(); afterErr != nil {
-				fmt.Printf("Warning: after command failed: %v\n", afterErr)
+	result, err := b.run(ctx, evaluate, report)
+	if err == nil {
+		b.warnUnread()
+	}
+	return result, err
+}
+
+// checkBoundaries tests the known good and bad lines and warns if they get the
+// same verdict, which usually means the test command doesn't look at the
+// candidate or the boundaries are wrong
+func (b *AutomaticBisector) checkBoundaries(ctx context.Context) error {
+	fmt.Fprintf(b.out, "Checking the test command against lines %d and %d\n", b.goodIdx+1, b.badIdx+1)
+	file, err := newCandidateFile(b.budget)
+	if err != nil {
+		return err
+	}
+	defer file.remove()
+
+	var verdicts [2]Verdict
+	for i, idx := range []int{b.goodIdx, b.badIdx} {
+		c, err := b.candidate(idx)
+		if err != nil {
+			return err
+		}
+		run, err := b.probe(ctx, c, file, fmt.Sprintf("check %d", idx+1))
+		if err != nil {
+			if ctx.Err() != nil {
+				return interrupted(ctx)
 			}
+			return err
 		}
+		verdicts[i] = run.step.Verdict
+		b.runs.add(run)
+	}
+	if verdicts[0] == verdicts[1] && verdicts[0] != Skip {
+		fmt.Fprintf(b.errOut, "Warning: the known %s line %d and the known %s line %d were both %s. The test command may not depend on the candidate, or the boundaries are wrong.\n",
+			b.term(b.searched(Good)), b.goodIdx+1, b.term(b.searched(Bad)), b.badIdx+1, b.term(verdicts[0]))
+	}
+	fmt.Fprintln(b.out)
+	return nil
+}
 
-		if err == nil {
-			// Exit code 0 means good
-			b.goodIdx = midIdx
-			fmt.Printf("Test passed (good). Searching lines %d-%d\n\n", b.goodIdx+1, b.badIdx+1)
-		} else {
-			// Non-zero exit code means bad
-			b.badIdx = midIdx
-			fmt.Printf("Test failed (bad). Searching lines %d-%d\n\n", b.goodIdx+1, b.badIdx+1)
+// warnUnread warns when every probe got the same verdict and no test command
+// read its candidate file, which almost always means the command ignores it
+func (b *AutomaticBisector) warnUnread() {
+	if !b.checkReads || b.candidateRead.Load() || len(b.history) < 2 || b.history[0].Verdict == Skip {
+		return
+	}
+	for _, st := range b.history {
+		if st.Verdict != b.history[0].Verdict {
+			return
 		}
 	}
+	fmt.Fprintf(b.errOut, "Warning: every probe was %s and the test command never read the candidate file. Check that it uses {file}, or try --check-test.\n",
+		b.term(b.history[0].Verdict))
+}
+
+// readsFile reports whether command is given the candidate file, explicitly
+// or by having it appended for lack of placeholders
+func readsFile(command string) bool {
+	if command == "" {
+		return false
+	}
+	return strings.Contains(command, "{}") || strings.Contains(command, "{file}") || !strings.Contains(command, "{line}")
+}
 
-	return &Result{
-		BadLineNumber:  b.badIdx + 1, // Convert to 1-indexed
-		BadLineContent: b.lines[b.badIdx],
-		StepsTaken:     b.steps,
+// useTarget opens the WithTarget file, if any, for the probes to come and
+// returns the func that puts its original content back. That func sets *err
+// when restoring fails, unless it already holds an error, which is more
+// likely the cause; the restore error is then only printed.
+func (b *AutomaticBisector) useTarget() (func(err *error), error) {
+	if b.targetPath == "" {
+		return func(*error) {}, nil
+	}
+	t, err := openTarget(b.targetPath, b.errOut)
+	if err != nil {
+		return nil, err
+	}
+	t.sync = b.sync
+	b.target = t
+	return func(err *error) {
+		b.target = nil
+		restoreErr := t.restore()
+		switch {
+		case restoreErr == nil:
+		case *err == nil:
+			*err = restoreErr
+		default:
+			fmt.Fprintf(b.errOut, "Warning: %v\n", restoreErr)
+		}
 	}, nil
 }
 
-// createCommand creates an exec.Cmd that works cross-platform
-func (b *AutomaticBisector) createCommand(cmdStr string) *exec.Cmd {
-	// On Windows, use cmd.exe /c, on Unix use sh -c
-	if os.PathSeparator == '\\' {
-		// Windows
-		return exec.Command("cmd", "/c", cmdStr)
+// staged is a candidate ready for its test: its file is written and staged,
+// and the before command has run
+type staged struct {
+	c      Candidate      // With Path set to where the test finds the file
+	file   *candidateFile // File the candidate was written to
+	remote bool           // Whether Path is a staged copy to remove
+}
+
+// prewarm is the next probe being staged while the current one is tested
+type prewarm struct {
+	index  int
+	cancel context.CancelCauseFunc
+	done   chan *staged // Receives the staged probe, nil if staging failed
+	panic  *probePanic  // Set before done if staging panicked
+}
+
+// errWrongGuess cancels a prewarm, or a SpeculativeBisector's test, for a
+// probe that didn't come next
+var errWrongGuess = errors.New("prewarmed the wrong probe")
+
+// probe writes c to file and asks the oracle for a verdict between the before
+// and after commands
+func (b *AutomaticBisector) probe(ctx context.Context, c Candidate, file *candidateFile, label string) (testRun, error) {
+	st, err := b.prepare(ctx, c, file)
+	if err != nil {
+		return testRun{}, err
 	}
-	// Unix
-	return exec.Command("sh", "-c", cmdStr)
+	return b.test(ctx, st, label)
 }
 
-// buildCommand constructs the command string with placeholder substitutions
-// Supports:
-//   {} or {file} - replaced with the temp file path
-//   {line} - replaced with the current line content
-func (b *AutomaticBisector) buildCommand(filePath, lineContent, command string) string {
-	cmdStr := command
+// prepare writes c to file, stages a copy for runners that execute elsewhere
+// and runs the before command
+func (b *AutomaticBisector) prepare(ctx context.Context, c Candidate, file *candidateFile) (*staged, error) {
+	if err := file.write(c); err != nil {
+		return nil, err
+	}
+	if b.sync {
+		if err := syncFile(file.path); err != nil {
+			return nil, fmt.Errorf("failed to sync temp file: %w", err)
+		}
+	}
+	if b.target != nil {
+		if err := b.target.write(c); err != nil {
+			return nil, err
+		}
+	}
+	c.Path = file.path
+	st := &staged{c: c, file: file}
 
-	// Check if command contains placeholders
-	hasPlaceholder := strings.Contains(cmdStr, "{}")
-	hasFilePlaceholder := strings.Contains(cmdStr, "{file}")
-	hasLinePlaceholder := strings.Contains(cmdStr, "{line}")
+	if stager, ok := b.runner.(Stager); ok {
+		remote, err := stager.Stage(ctx, c.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stage candidate file: %w", err)
+		}
+		st.c.Path, st.remote = remote, true
+	}
 
-	// Replace {line} with the actual line content (properly quoted)
-	if hasLinePlaceholder {
-		quotedLine := strings.ReplaceAll(lineContent, "'", "'\\''")
-		cmdStr = strings.ReplaceAll(cmdStr, "{line}", fmt.Sprintf("'%s'", quotedLine))
+	if b.beforeCommand != "" {
+		b.runHook(ctx, "before", b.beforeCommand, st.c)
 	}
+	return st, nil
+}
+
+// test asks the oracle for a verdict on st and then finishes it. With
+// WithStreamOutput, the test's output is relayed with label in front.
+func (b *AutomaticBisector) test(ctx context.Context, st *staged, label string) (testRun, error) {
+	run, err := b.judge(ctx, b.oracle, st, label)
+	b.finish(ctx, st)
+	return run, err
+}
 
-	// Replace {} or {file} with the temp file path
-	if hasPlaceholder {
-		cmdStr = strings.ReplaceAll(cmdStr, "{}", filePath)
+// judge asks o for a verdict on st, which is left for the caller to finish
+func (b *AutomaticBisector) judge(ctx context.Context, o Oracle, st *staged, label string) (testRun, error) {
+	ctx, output, flush := withProbeOutput(ctx, b.stream, label)
+	ctx, reaper := withReaper(ctx)
+	ctx, crash := withCrashWatch(ctx)
+	start := time.Now()
+	verdict, err := o.Evaluate(ctx, st.c)
+	metricsFrom(ctx).Observe(MetricTestDuration, time.Since(start))
+	flush()
+	if ctx.Err() == nil {
+		verdict, err = b.settle(st.c, verdict, err, crash)
+	}
+	if reaper.reaped.Load() {
+		fmt.Fprintf(b.out, "Killed processes the test of line %d left running\n", st.c.Index+1)
 	}
-	if hasFilePlaceholder {
-		cmdStr = strings.ReplaceAll(cmdStr, "{file}", filePath)
+	if b.checkReads && !b.candidateRead.Load() {
+		// Unknown counts as read so filesystems without access times never warn
+		if read, known := st.file.read(); read || !known {
+			b.candidateRead.Store(true)
+		}
 	}
+	return testRun{step: Step{Index: st.c.Index, Verdict: verdict}, output: output.String()}, err
+}
 
-	// If no placeholders found, append file path as before (backward compatibility)
-	if !hasPlaceholder && !hasFilePlaceholder && !hasLinePlaceholder {
-		cmdStr = fmt.Sprintf("%s %s", cmdStr, filePath)
+// settle applies WithOnExecError to a test of c that couldn't run and
+// WithOnCrash to one that crashed
+func (b *AutomaticBisector) settle(c Candidate, verdict Verdict, err error, crash *crashWatch) (Verdict, error) {
+	if err != nil {
+		if b.onExecError == ActionAbort || !errors.Is(err, ErrTestCommandFailed) {
+			return verdict, err
+		}
+		if b.onExecError == ActionSkip {
+			fmt.Fprintf(b.errOut, "Warning: %v; skipping line %d\n", err, c.Index+1)
+			return Skip, nil
+		}
+		fmt.Fprintf(b.errOut, "Warning: %v; judging line %d %s\n", err, c.Index+1, b.term(Bad))
+		return Bad, nil
+	}
+
+	crashErr := crash.err()
+	if verdict != Bad || crashErr == nil {
+		return verdict, nil
+	}
+	switch b.onCrash {
+	case ActionAbort:
+		return Bad, crashErr
+	case ActionSkip:
+		fmt.Fprintf(b.errOut, "Warning: %v; skipping line %d\n", crashErr, c.Index+1)
+		return Skip, nil
+	}
+	return Bad, nil
+}
+
+// finish runs the after command and removes the staged copy. It cleans up
+// after the before command, so it runs even when the oracle failed or ctx was
+// canceled.
+func (b *AutomaticBisector) finish(ctx context.Context, st *staged) {
+	ctx = context.WithoutCancel(ctx)
+	if b.afterCommand != "" {
+		b.runHook(ctx, "after", b.afterCommand, st.c)
+	}
+	if st.remote {
+		if err := b.runner.(Stager).Unstage(ctx, st.c.Path); err != nil {
+			fmt.Fprintf(b.errOut, "Warning: failed to remove staged candidate file: %v\n", err)
+		}
+	}
+}
+
+// startPrewarm stages the probe that follows c if its verdict repeats the
+// last one, into file, or returns nil if c may be the last probe
+func (b *AutomaticBisector) startPrewarm(ctx context.Context, c Candidate, file *candidateFile) *prewarm {
+	guess := Good
+	if len(b.history) > 0 {
+		guess = b.history[len(b.history)-1].Verdict
+	}
+	next, ok := b.newProbe(c, 0).Next(guess)
+	if !ok {
+		return nil
 	}
 
-	return cmdStr
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &prewarm{index: next.Index, cancel: cancel, done: make(chan *staged, 1)}
+	fmt.Fprintf(b.out, "Prewarming line %d\n", next.Index+1)
+	go func() {
+		var st *staged
+		defer func() { w.done <- st }()
+		defer catchPanic(&w.panic, nil)
+
+		st, err := b.prepare(ctx, next, file)
+		if err != nil {
+			fmt.Fprintf(b.errOut, "Warning: failed to prewarm line %d: %v\n", next.Index+1, err)
+		}
+	}()
+	return w
+}
+
+// runHook runs a before or after command for c. Hook failures are reported as
+// warnings and never affect the verdict.
+func (b *AutomaticBisector) runHook(ctx context.Context, name, command string, c Candidate) {
+	cmdStr := buildCommand(c.Path, c.Line, command, quoteFor(b.runner))
+	fmt.Fprintf(b.out, "Running %s command: %s\n", name, cmdStr)
+	b.log().Debug("running hook", "hook", name, "command", cmdStr)
+	code, err := b.runner.Run(ctx, cmdStr, b.out, b.errOut)
+	if context.Cause(ctx) == errWrongGuess {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(b.errOut, "Warning: %s command failed: %v\n", name, err)
+		b.log().Warn("hook failed", "hook", name, "command", cmdStr, "error", err)
+	} else if code != 0 {
+		fmt.Fprintf(b.errOut, "Warning: %s command failed: exit status %d\n", name, code)
+		b.log().Warn("hook failed", "hook", name, "command", cmdStr, "exit_code", code)
+	}
 }
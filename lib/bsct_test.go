@@ -2,12 +2,18 @@ package lib
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -495,6 +501,436 @@ exit 0`
 	assert.Contains(t, trackStr, "AFTER:")
 }
 
+func TestInteractiveBisector_ContextCanceled(t *testing.T) {
+	lines := []string{"good1", "good2", "bad"}
+	bisector := NewInteractiveBisector(lines, 0, 2, false)
+
+	// A pipe that never receives input blocks the prompt until ctx is canceled
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	bisector.reader = bufio.NewReader(pr)
+	var out bytes.Buffer
+	bisector.out = &out
+
+	// Cancel only once the prompt is waiting for an answer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	result, err := bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, 0, result.StepsTaken, "the unanswered step doesn't count")
+	assert.Equal(t, 2, result.RangeStart)
+	assert.Equal(t, 3, result.RangeEnd)
+	assert.Contains(t, out.String(), "Is this line good or bad?")
+}
+
+func TestAutomaticBisector_ContextCanceled(t *testing.T) {
+	lines := []string{"line1", "line2", "line3", "line4"}
+
+	var scriptLogic string
+	if runtime.GOOS == "windows" {
+		scriptLogic = "exit /b 0"
+	} else {
+		scriptLogic = "exit 0"
+	}
+
+	scriptPath, cleanup, err := createTestScript(scriptLogic)
+	require.NoError(t, err)
+	defer cleanup()
+
+	bisector := NewAutomaticBisector(lines, 0, 3, scriptPath, "", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, 2, result.RangeStart)
+	assert.Equal(t, 4, result.RangeEnd)
+	assert.Equal(t, 0, bisector.steps)
+}
+
+func TestAutomaticBisector_ContextDeadlineKillsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available in cmd.exe")
+	}
+
+	lines := []string{"line1", "line2", "line3"}
+	bisector := NewAutomaticBisector(lines, 0, 2, "sleep 10 #", "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, result)
+	assert.True(t, result.Interrupted)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestAutomaticBisector_InterruptedResult(t *testing.T) {
+	lines := make([]string, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if calls++; calls == 4 {
+			cancel()
+			return Bad, ctx.Err()
+		}
+		if c.Index >= 60 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	require.NotNil(t, result)
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Len(t, result.History, 3)
+	assert.LessOrEqual(t, result.RangeStart, 61)
+	assert.GreaterOrEqual(t, result.RangeEnd, 61)
+	assert.Less(t, result.RangeEnd-result.RangeStart, 100/8)
+}
+
+func TestAutomaticBisector_ReusesCandidateFile(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	var paths []string
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		paths = append(paths, c.Path)
+		data, err := os.ReadFile(c.Path)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join(lines[:c.Index+1], "\n")+"\n", string(data))
+		if c.Index >= 5 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.BadLineNumber)
+
+	require.Greater(t, len(paths), 1)
+	for _, path := range paths {
+		assert.Equal(t, paths[0], path)
+	}
+	assert.NoFileExists(t, paths[0])
+}
+
+func TestAutomaticBisector_Prewarm(t *testing.T) {
+	lines := make([]string, 32)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line%d", i)
+	}
+	track := filepath.Join(t.TempDir(), "track.txt")
+
+	var mu sync.Mutex
+	var tested []int
+	paths := map[string]bool{}
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		// The before command for c ran on the file the test gets
+		data, err := os.ReadFile(c.Path)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join(lines[:c.Index+1], "\n")+"\n", string(data))
+		mu.Lock()
+		tested = append(tested, c.Index)
+		paths[c.Path] = true
+		mu.Unlock()
+		if c.Index >= 29 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines,
+		WithOracle(oracle),
+		WithBeforeCommand("echo before {line}>> "+track),
+		WithAfterCommand("echo after {line}>> "+track),
+		WithPrewarm(),
+		WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 30, result.BadLineNumber)
+
+	data, err := os.ReadFile(track)
+	require.NoError(t, err)
+	befores := strings.Count(string(data), "before")
+	// Every test had its before command, and every before command was
+	// cleaned up, including prewarms for probes that never came. A canceled
+	// prewarm may be killed before it writes anything.
+	assert.GreaterOrEqual(t, befores, len(tested))
+	assert.GreaterOrEqual(t, strings.Count(string(data), "after"), befores)
+	for _, idx := range tested {
+		assert.Contains(t, string(data), "before "+lines[idx])
+	}
+	// Probes that were prewarmed ran on the spare candidate file
+	assert.Len(t, paths, 2)
+	for path := range paths {
+		assert.NoFileExists(t, path)
+	}
+}
+
+func TestAutomaticBisector_SkipsDuplicateCandidates(t *testing.T) {
+	lines := make([]string, 16)
+	for i := range lines {
+		lines[i] = "ok"
+		if i >= 12 {
+			lines[i] = "bad"
+		}
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprint(concurrency), func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				if c.Line == "bad" {
+					return Bad, nil
+				}
+				return Good, nil
+			})
+			metrics := &PrometheusMetrics{}
+
+			bisector, err := New(lines, WithOracle(oracle), WithCandidateMode(CandidateLine),
+				WithConcurrency(concurrency), WithMetrics(metrics), WithOutput(io.Discard))
+			require.NoError(t, err)
+			result, err := bisector.Bisect()
+			require.NoError(t, err)
+			assert.Equal(t, 13, result.BadLineNumber)
+
+			// Only the first "ok" and the first "bad" candidate ran the test
+			assert.LessOrEqual(t, calls, 2*concurrency)
+			assert.Less(t, calls, result.StepsTaken)
+			assert.Equal(t, int64(result.StepsTaken-calls), metrics.values[MetricDuplicates])
+		})
+	}
+}
+
+func TestResult_Details(t *testing.T) {
+	lines := []string{"good1", "good2", "bad1", "bad2", "bad3"}
+	bisector := NewInteractiveBisector(lines, 0, 4, false)
+	bisector.reader = bufio.NewReader(strings.NewReader("g\nb\n"))
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 3, result.LastGoodLineNumber)
+	assert.Equal(t, 4, result.RangeStart)
+	assert.Equal(t, 4, result.RangeEnd)
+	assert.True(t, result.Verified)
+	assert.Equal(t, []Step{{Index: 2, Verdict: Good}, {Index: 3, Verdict: Bad}}, result.History)
+	assert.Greater(t, result.Duration, time.Duration(0))
+}
+
+func TestResult_Unverified(t *testing.T) {
+	lines := []string{"good1", "good2", "good3"}
+	bisector := NewInteractiveBisector(lines, 0, 2, false)
+	bisector.reader = bufio.NewReader(strings.NewReader("g\n"))
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	// The last line was only ever assumed bad
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.False(t, result.Verified)
+}
+
+func TestAutomaticBisector_WarnsUnreadCandidate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("access times aren't checked on Windows")
+	}
+	lines := []string{"a", "b", "c", "d", "e", "f"}
+
+	tests := map[string]struct {
+		command string
+		terms   []string
+		warns   string
+	}{
+		"ignores the file":   {command: "true", warns: "every probe was good"},
+		"renamed terms":      {command: "true", terms: []string{"old", "new"}, warns: "every probe was old"},
+		"only uses the line": {command: "true {line}"},
+		"verdicts differ":    {command: "! grep -q e"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var errOut bytes.Buffer
+			opts := []Option{WithTestCommand(tt.command), WithOutput(io.Discard), WithErrorOutput(&errOut)}
+			if tt.terms != nil {
+				opts = append(opts, WithTerms(tt.terms[0], tt.terms[1]))
+			}
+			bisector, err := New(lines, opts...)
+			require.NoError(t, err)
+			_, err = bisector.Bisect()
+			require.NoError(t, err)
+			if tt.warns != "" {
+				assert.Contains(t, errOut.String(), tt.warns)
+				assert.Contains(t, errOut.String(), "never read the candidate file")
+			} else {
+				assert.Empty(t, errOut.String())
+			}
+		})
+	}
+}
+
+func TestAutomaticBisector_TestCheck(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f"}
+	oracles := map[string]struct {
+		oracle Oracle
+		warns  bool
+	}{
+		"constant":  {oracle: OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) { return Good, nil }), warns: true},
+		"threshold": {oracle: &thresholdOracle{firstBad: 3}, warns: false},
+	}
+	for name, tt := range oracles {
+		t.Run(name, func(t *testing.T) {
+			var errOut bytes.Buffer
+			bisector, err := New(lines, WithOracle(tt.oracle), WithTestCheck(), WithOutput(io.Discard), WithErrorOutput(&errOut))
+			require.NoError(t, err)
+			_, err = bisector.Bisect()
+			require.NoError(t, err)
+			if tt.warns {
+				assert.Contains(t, errOut.String(), "the known good line 1 and the known bad line 6 were both good")
+			} else {
+				assert.Empty(t, errOut.String())
+			}
+		})
+	}
+}
+
+func TestAutomaticBisector_Contradiction(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell syntax differs on Windows")
+	}
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	// Only line 4 passes, so the known good line 1 fails its check and line 4
+	// contradicts it
+	bisector, err := New(lines,
+		WithTestCommand("echo testing {line}; [ {line} = d ]"),
+		WithTestCheck(),
+		WithOutput(io.Discard),
+		WithErrorOutput(io.Discard))
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	var contradiction *ContradictionError
+	require.ErrorAs(t, err, &contradiction)
+	assert.Equal(t, Step{Index: 0, Verdict: Bad}, contradiction.Earlier)
+	assert.Equal(t, Step{Index: 3, Verdict: Good}, contradiction.Later)
+	assert.Equal(t, "testing a\n", contradiction.EarlierOutput)
+	assert.Equal(t, "testing d\n", contradiction.LaterOutput)
+}
+
+func TestAutomaticBisector_OnExecError(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	// Lines 4 through 6 can't be built, and the problem starts at line 5
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Index >= 3 && c.Index <= 5 {
+			return Bad, &TestCommandError{Command: "make", ExitCode: 127, Err: ErrCommandNotFound}
+		}
+		if c.Index >= 4 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithOutput(io.Discard), WithErrorOutput(io.Discard))
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+
+	var errOut bytes.Buffer
+	bisector, err = New(lines, WithOracle(oracle), WithOnExecError(ActionSkip), WithOutput(io.Discard), WithErrorOutput(&errOut))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.BadLineNumber)
+	assert.Equal(t, 4, result.RangeStart)
+	assert.Equal(t, 7, result.RangeEnd)
+	assert.Equal(t, []int{4, 5, 6}, result.Skipped)
+	assert.Contains(t, errOut.String(), "command not found; skipping line 5")
+
+	bisector, err = New(lines, WithOracle(oracle), WithOnExecError(ActionBad), WithOutput(io.Discard), WithErrorOutput(io.Discard))
+	require.NoError(t, err)
+	result, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Empty(t, result.Skipped)
+}
+
+func TestAutomaticBisector_OnCrash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell syntax differs on Windows")
+	}
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	// The test of line 4 kills itself with SIGSEGV, and line 5 fails properly
+	test := "case {line} in a|b|c) exit 0;; d) kill -SEGV $$;; esac; exit 1"
+	bisect := func(opts ...Option) (*Result, error) {
+		bisector, err := New(lines, append(opts, WithTestCommand(test), WithOutput(io.Discard), WithErrorOutput(io.Discard))...)
+		require.NoError(t, err)
+		return bisector.Bisect()
+	}
+
+	// Crashes count as bad by default
+	result, err := bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+
+	result, err = bisect(WithOnCrash(ActionSkip))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, []int{4}, result.Skipped)
+
+	_, err = bisect(WithOnCrash(ActionAbort))
+	assert.ErrorIs(t, err, ErrTestCrashed)
+	assert.ErrorIs(t, err, ErrTestCommandFailed)
+}
+
+func TestAutomaticBisector_ReapsLeftoverProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes aren't reaped on Windows")
+	}
+	pids := filepath.Join(t.TempDir(), "pids")
+	lines := []string{"a", "b", "c", "d"}
+
+	var out bytes.Buffer
+	bisector, err := New(lines,
+		// A server left running with the test's output still open
+		WithTestCommand("sleep 60 & echo $! >> "+pids+"; [ {line} = a ]"),
+		WithOutput(&out))
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 30*time.Second)
+	assert.Contains(t, out.String(), "Killed processes the test of line 2 left running")
+
+	data, err := os.ReadFile(pids)
+	require.NoError(t, err)
+	for _, pid := range strings.Fields(string(data)) {
+		// Killed processes linger until init reaps them
+		assert.Eventually(t, func() bool {
+			return exec.Command("kill", "-0", pid).Run() != nil
+		}, 5*time.Second, 10*time.Millisecond, "process %s is still running", pid)
+	}
+}
+
 // TestMain ensures test scripts are executable
 func TestMain(m *testing.M) {
 	// Check if we can execute shell scripts/commands
@@ -511,3 +947,199 @@ func TestMain(m *testing.M) {
 
 	os.Exit(m.Run())
 }
+
+func TestInteractiveBisector_RangeAnswers(t *testing.T) {
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+
+	testCases := []struct {
+		name   string
+		input  string
+		bad    int
+		output []string
+	}{
+		{
+			// Good through 700 decides line 500 and reaches past it, bad from
+			// 900 doesn't decide line 850, which is asked about again
+			name:   "ranges narrow the search",
+			input:  "g 1-700\nb 900-\ng\ng\ng\ng\ng\ng\ng\n",
+			bad:    900,
+			output: []string{"Marked lines 1-700 as good", "Marked lines 900-1000 as bad", "Searching lines 700-900"},
+		},
+		{
+			name:   "a single line",
+			input:  "b 501\ng\n",
+			bad:    501,
+			output: []string{"Marked lines 501-501 as bad", "Searching lines 1-501"},
+		},
+		{
+			name:  "invalid ranges are asked again",
+			input: "g 10-5\ng 0-3\nb 2000-\ng abc\ng -\ng 1-1000\n" + strings.Repeat("b\n", 9),
+			bad:   2,
+			output: []string{
+				"10-5 ends before it starts", "lines are numbered 1-1000", "\"abc\" is not a line number",
+				"no lines given", "Line 1000 is already known to be bad",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			bisector, err := New(lines, WithBoundaries(0, 999), WithInput(strings.NewReader(tc.input)), WithOutput(&out))
+			require.NoError(t, err)
+			result, err := bisector.Bisect()
+			require.NoError(t, err, out.String())
+			assert.Equal(t, tc.bad, result.BadLineNumber)
+			for _, want := range tc.output {
+				assert.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
+
+func TestInteractiveBisector_EvaluateRejectsRanges(t *testing.T) {
+	bisector, err := New([]string{"a", "b", "c"}, WithInput(strings.NewReader("g 1-2\nb\n")), WithOutput(io.Discard))
+	require.NoError(t, err)
+	v, err := bisector.(*InteractiveBisector).Evaluate(context.Background(), Candidate{Index: 1, Line: "b", src: Lines{"a", "b", "c"}})
+	require.NoError(t, err)
+	assert.Equal(t, Bad, v)
+}
+
+func TestInteractiveBisector_Skip(t *testing.T) {
+	lines := []string{"good1", "good2", "unbuildable", "bad1", "bad2"}
+	var out strings.Builder
+	bisector, err := New(lines, WithInput(strings.NewReader("s\ng\nskip\n")), WithOutput(&out))
+	require.NoError(t, err)
+
+	// Line 3 is skipped for its neighbour line 2, then line 4 is skipped and
+	// line 5 is all that is left
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 4}, result.Skipped)
+	assert.Equal(t, 3, result.RangeStart)
+	assert.Equal(t, 5, result.RangeEnd)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Contains(t, out.String(), "Skipped line 3")
+}
+
+func TestInteractiveBisector_Undo(t *testing.T) {
+	lines := []string{"good1", "good2", "good3", "bad1", "bad2", "bad3", "bad4", "bad5", "bad6", "bad7"}
+	var out strings.Builder
+	// Line 5 is answered good by mistake and taken back, then asked again
+	bisector, err := New(lines, WithInput(strings.NewReader("u\ng\nu\nb\ng\nb\n")), WithOutput(&out))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Equal(t, []Step{{Index: 4, Verdict: Bad}, {Index: 2, Verdict: Good}, {Index: 3, Verdict: Bad}}, result.History)
+	assert.Contains(t, out.String(), "Nothing to undo yet")
+	assert.Contains(t, out.String(), "Undid the last answer\x1b[0m. Searching lines 1-10")
+	assert.Equal(t, 2, strings.Count(out.String(), "Step 1:"))
+}
+
+func TestInteractiveBisector_UndoRange(t *testing.T) {
+	lines := []string{"good1", "good2", "good3", "bad1", "bad2", "bad3", "bad4", "bad5", "bad6", "bad7"}
+	// A range marked while line 5 was asked about is taken back before answering
+	bisector, err := New(lines, WithInput(strings.NewReader("g 1-3\nu\nb\ng\nb\n")), WithOutput(io.Discard))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Equal(t, Step{Index: 4, Verdict: Bad}, result.History[0])
+}
+
+func TestInteractiveBisector_ContextLines(t *testing.T) {
+	lines := []string{"good1", "good2", "good3", "bad1", "bad2", "bad3", "bad4", "bad5", "bad6", "bad7"}
+	var out strings.Builder
+	// Line 5 is shown with 2 lines of context, then 3, 2, 1 and 0, which one
+	// more - can't go below and the next step keeps
+	bisector, err := New(lines, WithContextLines(2), WithInput(strings.NewReader("+\n-\n-\n-\n-\nb\ng\nb\n")), WithOutput(&out))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+
+	var shown []int
+	for _, prompt := range strings.Split(out.String(), "Is this line")[:7] {
+		shown = append(shown, strings.Count(prompt, " | "))
+	}
+	assert.Equal(t, []int{5, 7, 5, 3, 1, 1, 1}, shown)
+}
+
+func TestWithContextLines_Negative(t *testing.T) {
+	_, err := New([]string{"a", "b", "c"}, WithContextLines(-1))
+	assert.Error(t, err)
+}
+
+func TestFindFirstGood(t *testing.T) {
+	lines := []string{"down", "down", "down", "down", "down", "up", "up", "up", "up", "up"}
+	judge := WithOracle(OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Line == "up" {
+			return Good, nil
+		}
+		return Bad, nil
+	}))
+
+	bisector, err := New(lines, judge, WithFindFirstGood(), WithCandidateMode(CandidateLine), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.FirstGoodLine)
+	assert.Equal(t, 6, result.BadLineNumber)
+	assert.Equal(t, 5, result.LastGoodLineNumber)
+	assert.True(t, result.Verified)
+	assert.Equal(t, Step{Index: 4, Verdict: Bad}, result.History[0])
+
+	// Interactive answers keep their meaning too, ranges included
+	var out strings.Builder
+	bisector, err = New(lines, WithFindFirstGood(), WithInput(strings.NewReader("g 7-\nb\ng\n")), WithOutput(&out))
+	require.NoError(t, err)
+	result, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.FirstGoodLine)
+	assert.Contains(t, out.String(), "Marked lines 7-10 as good")
+
+	_, err = New(lines, WithFindFirstGood(), WithBoundaries(2, 7))
+	assert.ErrorIs(t, err, ErrGoodBeforeBad)
+}
+
+func TestInteractiveBisector_Terms(t *testing.T) {
+	lines := []string{"v1", "v2", "v3", "v4", "v5"}
+	var out strings.Builder
+	bisector, err := New(lines, WithTerms("Old", "New"), WithInput(strings.NewReader("good\no\nnew\n")), WithOutput(&out))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Contains(t, out.String(), "Is this line old or new? [o/n/s]: ")
+	assert.Contains(t, out.String(), "Please enter 'o' (old), 'n' (new) or 's' (skip)")
+	assert.Contains(t, out.String(), "Marked as old")
+	assert.Contains(t, out.String(), "Marked as new")
+
+	// Both terms are typed in full when their first letters clash with
+	// another answer
+	out.Reset()
+	bisector, err = New(lines, WithTerms("fast", "slow"), WithInput(strings.NewReader("s\nfast\nslow\n")), WithOutput(&out))
+	require.NoError(t, err)
+	result, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, result.Skipped)
+	assert.Contains(t, out.String(), "[fast/slow/s]")
+}
+
+func TestWithTerms_Invalid(t *testing.T) {
+	for _, terms := range [][2]string{{"same", "Same"}, {"skip", "bad"}, {"good", "not good"}, {"", "bad"}} {
+		_, err := New([]string{"a", "b"}, WithTerms(terms[0], terms[1]))
+		assert.Error(t, err, terms)
+	}
+}
@@ -0,0 +1,224 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores verdicts by candidate key for CachedOracle. Implementations
+// must be safe for concurrent use.
+type Cache interface {
+	// Get returns the verdict stored for key and whether there was one
+	Get(key string) (Verdict, bool, error)
+	// Put stores v for key
+	Put(key string, v Verdict) error
+}
+
+// CachedOracle wraps inner so that candidates whose content was already judged
+// get the stored verdict instead of being evaluated again. Errors from inner
+// are never cached.
+func CachedOracle(inner Oracle, store Cache) Oracle {
+	return &cachedOracle{inner: inner, store: store}
+}
+
+type cachedOracle struct {
+	inner Oracle
+	store Cache
+}
+
+// Evaluate looks c up in the store before asking the inner oracle
+func (o *cachedOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	key, err := CandidateKey(c)
+	if err != nil {
+		return Bad, err
+	}
+
+	if v, ok, err := o.store.Get(key); err != nil {
+		return Bad, fmt.Errorf("failed to read cache: %w", err)
+	} else if ok {
+		metricsFrom(ctx).Add(MetricCacheHits, 1)
+		return v, nil
+	}
+	metricsFrom(ctx).Add(MetricCacheMisses, 1)
+
+	v, err := o.inner.Evaluate(ctx, c)
+	if err != nil {
+		return v, err
+	}
+	if err := o.store.Put(key, v); err != nil {
+		return v, fmt.Errorf("failed to write cache: %w", err)
+	}
+	return v, nil
+}
+
+// CandidateKey returns the hex SHA-256 of the candidate content, so identical
+// candidates share a key no matter which line or bisection produced them
+func CandidateKey(c Candidate) (string, error) {
+	h := sha256.New()
+	if _, err := c.WriteTo(h); err != nil {
+		return "", fmt.Errorf("failed to hash candidate: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ScopedCache returns a Cache that keeps its entries in store apart from those
+// of other scopes, e.g. so that bisections with different test commands can
+// share a DiskCache without reusing each other's verdicts
+func ScopedCache(store Cache, scope string) Cache {
+	return scopedCache{store: store, scope: scope}
+}
+
+type scopedCache struct {
+	store Cache
+	scope string
+}
+
+// key combines key with the scope into a key of the same hex SHA-256 form
+func (c scopedCache) key(key string) string {
+	sum := sha256.Sum256([]byte(c.scope + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the verdict stored for key in the scope
+func (c scopedCache) Get(key string) (Verdict, bool, error) { return c.store.Get(c.key(key)) }
+
+// Put stores v for key in the scope
+func (c scopedCache) Put(key string, v Verdict) error { return c.store.Put(c.key(key), v) }
+
+// MemoryCache is a Cache that lives as long as the process
+type MemoryCache struct {
+	mu       sync.Mutex
+	verdicts map[string]Verdict
+}
+
+// NewMemoryCache creates an empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{verdicts: make(map[string]Verdict)}
+}
+
+// Get returns the verdict stored for key
+func (m *MemoryCache) Get(key string) (Verdict, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.verdicts[key]
+	return v, ok, nil
+}
+
+// Put stores v for key
+func (m *MemoryCache) Put(key string, v Verdict) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verdicts[key] = v
+	return nil
+}
+
+// DiskCache is a Cache that keeps one small file per key in a directory, so
+// verdicts survive across runs. With MaxBytes set, the least recently used
+// entries are evicted once the directory grows past it.
+type DiskCache struct {
+	Dir      string
+	MaxBytes int64 // Limit on the size of all entries, 0 for none
+}
+
+// Get reads the verdict stored for key
+func (d DiskCache) Get(key string) (Verdict, bool, error) {
+	data, err := os.ReadFile(filepath.Join(d.Dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Bad, false, nil
+	}
+	if err != nil {
+		return Bad, false, err
+	}
+
+	var v Verdict
+	switch strings.TrimSpace(string(data)) {
+	case Good.String():
+		v = Good
+	case Bad.String():
+		v = Bad
+	default:
+		// Treat unreadable entries as missing so they get re-evaluated
+		return Bad, false, nil
+	}
+	if d.MaxBytes > 0 {
+		// Mark the entry as recently used so eviction spares it
+		now := time.Now()
+		os.Chtimes(filepath.Join(d.Dir, key), now, now)
+	}
+	return v, true, nil
+}
+
+// Put writes v for key, creating Dir if needed
+func (d DiskCache) Put(key string, v Verdict) error {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so concurrent readers never see partial entries
+	tmp, err := os.CreateTemp(d.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(v.String() + "\n"); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(d.Dir, key)); err != nil {
+		return err
+	}
+	return d.evict()
+}
+
+// evict removes the least recently used entries until the directory fits in
+// MaxBytes
+func (d DiskCache) evict() error {
+	if d.MaxBytes <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		return err
+	}
+
+	var infos []fs.FileInfo
+	var total int64
+	for _, e := range entries {
+		// Skip entries still being written by a concurrent Put
+		if !e.Type().IsRegular() || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // Evicted concurrently
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+	for _, info := range infos {
+		if total <= d.MaxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.Dir, info.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= info.Size()
+	}
+	return nil
+}
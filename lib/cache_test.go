@@ -0,0 +1,134 @@
+package lib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingOracle judges lines containing "bad" as bad and counts evaluations
+type countingOracle struct {
+	calls int
+}
+
+func (o *countingOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	o.calls++
+	if c.Line == "bad" {
+		return Bad, nil
+	}
+	return Good, nil
+}
+
+func TestCachedOracle(t *testing.T) {
+	lines := Lines{"good", "good", "bad"}
+	stores := map[string]Cache{
+		"memory": NewMemoryCache(),
+		"disk":   DiskCache{Dir: filepath.Join(t.TempDir(), "cache")},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			inner := &countingOracle{}
+			oracle := CachedOracle(inner, store)
+
+			for range 3 {
+				v, err := oracle.Evaluate(context.Background(), Candidate{Index: 1, Line: "good", src: lines})
+				require.NoError(t, err)
+				assert.Equal(t, Good, v)
+			}
+			assert.Equal(t, 1, inner.calls)
+
+			// Same line, different content
+			v, err := oracle.Evaluate(context.Background(), Candidate{Index: 2, Line: "bad", src: lines})
+			require.NoError(t, err)
+			assert.Equal(t, Bad, v)
+			assert.Equal(t, 2, inner.calls)
+		})
+	}
+}
+
+func TestCachedOracle_ErrorsNotCached(t *testing.T) {
+	calls := 0
+	inner := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		calls++
+		return Bad, assert.AnError
+	})
+	oracle := CachedOracle(inner, NewMemoryCache())
+	c := Candidate{Index: 0, Line: "a", src: Lines{"a"}}
+
+	for range 2 {
+		_, err := oracle.Evaluate(context.Background(), c)
+		assert.ErrorIs(t, err, assert.AnError)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestCandidateKey(t *testing.T) {
+	// Identical content from different sources shares a key
+	a, err := CandidateKey(Candidate{Index: 1, src: Lines{"x", "y"}})
+	require.NoError(t, err)
+	b, err := CandidateKey(Candidate{Index: 1, src: Lines{"x", "y", "z"}})
+	require.NoError(t, err)
+	c, err := CandidateKey(Candidate{Index: 1, src: Lines{"x", "y"}, mode: CandidateLine})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestDiskCache_CorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "k"), []byte("maybe\n"), 0644))
+
+	_, ok, err := DiskCache{Dir: dir}.Get("k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDiskCache_MaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	// Each entry is "good\n" or "bad\n"
+	store := DiskCache{Dir: dir, MaxBytes: 10}
+	old := time.Now().Add(-time.Hour)
+
+	require.NoError(t, store.Put("a", Good))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a"), old, old))
+	require.NoError(t, store.Put("b", Good))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "b"), old.Add(time.Minute), old.Add(time.Minute)))
+
+	// Reading a makes b the least recently used
+	_, ok, err := store.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, store.Put("c", Bad))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		_, ok, err := store.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, key)
+	}
+}
+
+func TestScopedCache(t *testing.T) {
+	store := DiskCache{Dir: t.TempDir()}
+	first, second := ScopedCache(store, "make test"), ScopedCache(store, "make lint")
+
+	require.NoError(t, first.Put("key", Bad))
+	v, ok, err := first.Get("key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Bad, v)
+
+	// Other scopes and the store itself don't see the entry
+	_, ok, err = second.Get("key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = store.Get("key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,208 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// candidateFile is the temp file candidates are written to. Prefix candidates
+// are updated in place: lines are appended when the probe moves right and cut
+// off when it moves left, so each step only writes the lines in between
+// rather than the whole prefix again.
+type candidateFile struct {
+	path   string
+	index  int         // Last line in the file, -1 if it has to be rewritten whole
+	size   int64       // Bytes written so far
+	info   os.FileInfo // The file as last written, to notice commands changing it
+	budget *tempBudget // Shared limit on candidate bytes, nil if unlimited
+}
+
+// newCandidateFile creates an empty temp file for candidates to be written to,
+// counting against budget
+func newCandidateFile(budget *tempBudget) (*candidateFile, error) {
+	f, err := os.CreateTemp("", "bsct-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	f.Close()
+	return &candidateFile{path: f.Name(), index: -1, budget: budget}, nil
+}
+
+// remove deletes the file and returns its bytes to the budget
+func (f *candidateFile) remove() {
+	os.Remove(f.path)
+	f.resize(0)
+}
+
+// resize records that the file now holds size bytes
+func (f *candidateFile) resize(size int64) {
+	f.budget.release(f.size - size)
+	f.size = size
+}
+
+// write makes the file hold c's content
+func (f *candidateFile) write(c Candidate) error {
+	if err := f.update(c); err != nil {
+		f.index = -1
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		f.index = -1
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	f.info = info
+	return nil
+}
+
+func (f *candidateFile) update(c Candidate) error {
+	if !f.reusable(c) {
+		// Reopened by name, since a command that edits the file in place may
+		// have replaced it with a new one
+		file, err := os.Create(f.path)
+		if err != nil {
+			return err
+		}
+		f.resize(0)
+		_, err = c.WriteTo(f.limit(file))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		f.index = c.Index
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch {
+	case c.Index > f.index:
+		if _, err := file.Seek(f.size, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := c.src.WriteLines(f.limit(file), f.index+1, c.Index); err != nil {
+			return err
+		}
+	case c.Index < f.index:
+		// Measure the lines being cut off without writing them anywhere
+		n, err := c.src.WriteLines(io.Discard, c.Index+1, f.index)
+		if err != nil {
+			return err
+		}
+		if err := file.Truncate(f.size - n); err != nil {
+			return err
+		}
+		f.resize(f.size - n)
+	}
+	f.index = c.Index
+	return file.Close()
+}
+
+// limit wraps w so every byte written grows the file's size and is taken from
+// the budget, failing once the budget is spent
+func (f *candidateFile) limit(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		if err := f.budget.reserve(int64(len(p))); err != nil {
+			return 0, err
+		}
+		n, err := w.Write(p)
+		f.size += int64(n)
+		f.budget.release(int64(len(p) - n))
+		return n, err
+	})
+}
+
+// read reports whether the file was read since it was last written, and
+// whether that can be told at all. Access times only tell on filesystems that
+// record them and aren't mounted with noatime.
+func (f *candidateFile) read() (read, known bool) {
+	info, err := os.Stat(f.path)
+	if err != nil || f.info == nil {
+		return false, false
+	}
+	if !os.SameFile(info, f.info) {
+		// Replaced by a command that edited it
+		return true, true
+	}
+	atime, ok := accessTime(info)
+	if !ok {
+		return false, false
+	}
+	return atime.After(f.info.ModTime()), true
+}
+
+// reusable reports whether the file can be updated in place to hold c
+func (f *candidateFile) reusable(c Candidate) bool {
+	if f.index < 0 || f.info == nil || c.mode != CandidatePrefix {
+		return false
+	}
+	if !appendable(c.src) {
+		return false
+	}
+	info, err := os.Stat(f.path)
+	return err == nil && os.SameFile(info, f.info) && info.Size() == f.size && info.ModTime().Equal(f.info.ModTime())
+}
+
+// appendable reports whether the prefix candidates of src can be grown by
+// writing the lines in between. Framed and joined content can't be split
+// between writes.
+func appendable(src Source) bool {
+	switch s := src.(type) {
+	case framed, joined:
+		return false
+	case *SectionSource:
+		return appendable(s.src)
+	}
+	return true
+}
+
+// tempBudget caps the bytes held by all candidate files of a bisection at
+// once, so a huge input fails with a clear error before it fills the disk. A
+// nil budget is unlimited.
+type tempBudget struct {
+	max     int64
+	metrics Metrics
+
+	mu   sync.Mutex
+	used int64
+}
+
+// reserve takes n bytes from the budget or fails with ErrTempLimit
+func (b *tempBudget) reserve(n int64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used+n > b.max {
+		return fmt.Errorf("%w: candidate files would need more than %d bytes", ErrTempLimit, b.max)
+	}
+	b.used += n
+	if b.metrics != nil {
+		b.metrics.Add(MetricTempBytes, n)
+	}
+	return nil
+}
+
+// release returns n bytes to the budget
+func (b *tempBudget) release(n int64) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	if b.metrics != nil {
+		b.metrics.Add(MetricTempBytes, -n)
+	}
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
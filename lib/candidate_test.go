@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandidateFile(t *testing.T) {
+	lines := Lines{"a", "bb", "ccc", "dddd", "eeeee", "f"}
+	sources := map[string]Source{
+		"lines":  lines,
+		"framed": Framed(lines, "[\n", "]\n"),
+	}
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			file, err := newCandidateFile(nil)
+			require.NoError(t, err)
+			defer os.Remove(file.path)
+
+			for _, idx := range []int{2, 4, 3, 5, 0, 5, 5} {
+				c := Candidate{Index: idx, src: src}
+				require.NoError(t, file.write(c))
+
+				var want strings.Builder
+				_, err := c.WriteTo(&want)
+				require.NoError(t, err)
+				got, err := os.ReadFile(file.path)
+				require.NoError(t, err)
+				assert.Equal(t, want.String(), string(got), "index %d", idx)
+			}
+		})
+	}
+}
+
+func TestCandidateFile_ChangedByCommand(t *testing.T) {
+	lines := Lines{"a", "b", "c", "d"}
+	file, err := newCandidateFile(nil)
+	require.NoError(t, err)
+	defer os.Remove(file.path)
+
+	require.NoError(t, file.write(Candidate{Index: 1, src: lines}))
+	// A test that edits the file in place, e.g. with sed -i
+	require.NoError(t, os.Remove(file.path))
+	require.NoError(t, os.WriteFile(file.path, []byte("edited\n"), 0644))
+
+	require.NoError(t, file.write(Candidate{Index: 2, src: lines}))
+	got, err := os.ReadFile(file.path)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\n", string(got))
+}
+
+func TestCandidateFile_Budget(t *testing.T) {
+	lines := Lines{"a", "bb", "ccc", "dddd", "eeeee", "f"}
+	budget := &tempBudget{max: 12}
+	file, err := newCandidateFile(budget)
+	require.NoError(t, err)
+	defer file.remove()
+
+	for _, idx := range []int{1, 2, 0, 2} {
+		require.NoError(t, file.write(Candidate{Index: idx, src: lines}))
+		assert.Equal(t, file.size, budget.used, "index %d", idx)
+	}
+
+	// a through eeeee needs 20 bytes
+	err = file.write(Candidate{Index: 4, src: lines})
+	assert.ErrorIs(t, err, ErrTempLimit)
+	assert.LessOrEqual(t, budget.used, budget.max)
+
+	// A failed write leaves the file to be rewritten whole
+	require.NoError(t, file.write(Candidate{Index: 1, src: lines}))
+	assert.Equal(t, int64(5), budget.used)
+	file.remove()
+	assert.Zero(t, budget.used)
+}
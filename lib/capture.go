@@ -0,0 +1,167 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxRunOutput is how much of the end of a test's output is kept to explain
+// a contradiction
+const maxRunOutput = 4 << 10
+
+type outputKey struct{}
+
+// probeOutput is where a probe's test output goes besides the oracle's own
+// writers
+type probeOutput struct {
+	tail           *tailBuffer // Kept to explain a contradiction
+	stdout, stderr io.Writer   // Relay the output as it arrives, if set
+}
+
+// withProbeOutput returns a copy of ctx that makes command oracles copy their
+// output into the returned buffer, which keeps only its end, and relay it to
+// stream with every line prefixed by label, if stream is set. flush writes
+// out a final line left without a newline.
+func withProbeOutput(ctx context.Context, stream io.Writer, label string) (_ context.Context, tail *tailBuffer, flush func()) {
+	out := probeOutput{tail: &tailBuffer{max: maxRunOutput}}
+	flush = func() {}
+	if stream != nil {
+		// Separate writers keep stdout and stderr lines whole
+		prefix := fmt.Sprintf("[%s] ", label)
+		stdout := &prefixWriter{w: stream, prefix: prefix}
+		stderr := &prefixWriter{w: stream, prefix: prefix}
+		out.stdout, out.stderr = stdout, stderr
+		flush = func() {
+			stdout.flush()
+			stderr.flush()
+		}
+	}
+	return context.WithValue(ctx, outputKey{}, out), out.tail, flush
+}
+
+// captureOutput returns stdout and stderr teed into the writers carried by
+// ctx, if any. Nil writers discard.
+func captureOutput(ctx context.Context, stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	out, ok := ctx.Value(outputKey{}).(probeOutput)
+	if !ok {
+		return stdout, stderr
+	}
+	tee := func(w, stream io.Writer) io.Writer {
+		ws := []io.Writer{out.tail}
+		if w != nil {
+			ws = append(ws, w)
+		}
+		if stream != nil {
+			ws = append(ws, stream)
+		}
+		return io.MultiWriter(ws...)
+	}
+	return tee(stdout, out.stdout), tee(stderr, out.stderr)
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write appends p, dropping the oldest bytes beyond max
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String returns what was kept
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// headTailBuffer keeps the first and last max/2 bytes written to it, so the
+// output of a test that prints gigabytes takes bounded memory while still
+// showing how it started and ended
+type headTailBuffer struct {
+	max     int
+	head    []byte
+	tail    tailBuffer
+	written int64
+}
+
+// newHeadTailBuffer returns a headTailBuffer keeping max bytes in total
+func newHeadTailBuffer(max int) *headTailBuffer {
+	return &headTailBuffer{max: max / 2, tail: tailBuffer{max: max - max/2}}
+}
+
+// Write keeps p in the head while it has room and in the tail after that
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.written += int64(n)
+	if room := b.max - len(b.head); room > 0 {
+		take := min(room, len(p))
+		b.head = append(b.head, p[:take]...)
+		p = p[take:]
+	}
+	if len(p) > 0 {
+		b.tail.Write(p)
+	}
+	return n, nil
+}
+
+// Bytes returns the head and tail, with a note of how much was dropped
+// between them if anything was
+func (b *headTailBuffer) Bytes() []byte {
+	tail := b.tail.String()
+	out := append([]byte(nil), b.head...)
+	if omitted := b.written - int64(len(b.head)+len(tail)); omitted > 0 {
+		out = fmt.Appendf(out, "\n[... %d bytes omitted ...]\n", omitted)
+	}
+	return append(out, tail...)
+}
+
+// prefixWriter writes whole lines to w with prefix in front of each, so the
+// output of concurrent probes doesn't interleave mid-line
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Write writes every line p completes and keeps the rest for later
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.partial[:i]); err != nil {
+			return len(b), err
+		}
+		p.partial = p.partial[i+1:]
+	}
+}
+
+// flush writes out a final line left without a newline
+func (p *prefixWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.partial) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.partial)
+		p.partial = nil
+	}
+}
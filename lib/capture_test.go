@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 8}
+	b.Write([]byte("hello "))
+	b.Write([]byte("world"))
+	assert.Equal(t, "lo world", b.String())
+
+	b.Write([]byte(strings.Repeat("x", 20)))
+	assert.Equal(t, "xxxxxxxx", b.String())
+}
+
+func TestHeadTailBuffer(t *testing.T) {
+	b := newHeadTailBuffer(8)
+	b.Write([]byte("ab"))
+	assert.Equal(t, "ab", string(b.Bytes()))
+	b.Write([]byte("cdef"))
+	assert.Equal(t, "abcdef", string(b.Bytes()))
+
+	b.Write([]byte(strings.Repeat("x", 10) + "wxyz"))
+	assert.Equal(t, "abcd\n[... 12 bytes omitted ...]\nwxyz", string(b.Bytes()))
+}
+
+func TestCaptureOutput_Stream(t *testing.T) {
+	var stream, own bytes.Buffer
+	ctx, tail, flush := withProbeOutput(context.Background(), &stream, "step 2")
+	stdout, stderr := captureOutput(ctx, &own, nil)
+
+	stdout.Write([]byte("compiling\nlinking"))
+	stderr.Write([]byte("warning: slow\n"))
+	stdout.Write([]byte("...\ndone"))
+	flush()
+
+	assert.Equal(t, "[step 2] compiling\n[step 2] warning: slow\n[step 2] linking...\n[step 2] done\n", stream.String())
+	assert.Equal(t, "compiling\nlinking...\ndone", own.String())
+	assert.Equal(t, "compiling\nlinkingwarning: slow\n...\ndone", tail.String())
+}
@@ -0,0 +1,61 @@
+package lib
+
+import "context"
+
+// AllOf judges a candidate good only when every oracle does. Oracles are asked
+// in order and the first bad verdict stops the rest. Without one, a skip from
+// any oracle skips the candidate.
+func AllOf(oracles ...Oracle) Oracle {
+	return OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		verdict := Good
+		for _, o := range oracles {
+			v, err := o.Evaluate(ctx, c)
+			if err != nil || v == Bad {
+				return Bad, err
+			}
+			if v == Skip {
+				verdict = Skip
+			}
+		}
+		return verdict, nil
+	})
+}
+
+// AnyOf judges a candidate good when at least one oracle does. Oracles are
+// asked in order and the first good verdict stops the rest. Without one, a
+// skip from any oracle skips the candidate.
+func AnyOf(oracles ...Oracle) Oracle {
+	return OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		verdict := Bad
+		for _, o := range oracles {
+			v, err := o.Evaluate(ctx, c)
+			if err != nil {
+				return Bad, err
+			}
+			if v == Good {
+				return Good, nil
+			}
+			if v == Skip {
+				verdict = Skip
+			}
+		}
+		return verdict, nil
+	})
+}
+
+// Not inverts the verdicts of o, e.g. to bisect for when a problem went away.
+// Skips and errors are passed through unchanged.
+func Not(o Oracle) Oracle {
+	return OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		v, err := o.Evaluate(ctx, c)
+		switch {
+		case err != nil:
+			return Bad, err
+		case v == Skip:
+			return Skip, nil
+		case v == Good:
+			return Bad, nil
+		}
+		return Good, nil
+	})
+}
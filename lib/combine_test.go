@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombinators(t *testing.T) {
+	good := &scriptedOracle{results: []any{Good}}
+	bad := &scriptedOracle{results: []any{Bad}}
+	skip := &scriptedOracle{results: []any{Skip}}
+
+	testCases := []struct {
+		name   string
+		oracle Oracle
+		want   Verdict
+	}{
+		{"all good", AllOf(good, good), Good},
+		{"all with a bad", AllOf(good, bad), Bad},
+		{"all of none", AllOf(), Good},
+		{"any good", AnyOf(bad, good), Good},
+		{"any all bad", AnyOf(bad, bad), Bad},
+		{"any of none", AnyOf(), Bad},
+		{"not good", Not(good), Bad},
+		{"not bad", Not(bad), Good},
+		{"nested", AllOf(good, Not(AnyOf(bad, bad))), Good},
+		{"all with a skip", AllOf(skip, good), Skip},
+		{"bad beats skip for all", AllOf(skip, bad), Bad},
+		{"any with a skip", AnyOf(bad, skip), Skip},
+		{"good beats skip for any", AnyOf(skip, good), Good},
+		{"not skip", Not(skip), Skip},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := tc.oracle.Evaluate(context.Background(), Candidate{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+}
+
+func TestCombinators_ShortCircuit(t *testing.T) {
+	good := &scriptedOracle{results: []any{Good}}
+	bad := &scriptedOracle{results: []any{Bad}}
+	unused := &scriptedOracle{results: []any{Good}}
+
+	_, err := AllOf(bad, unused).Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	_, err = AnyOf(good, unused).Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, unused.calls)
+}
+
+func TestCombinators_Error(t *testing.T) {
+	failing := &scriptedOracle{results: []any{assert.AnError}}
+	good := &scriptedOracle{results: []any{Good}}
+
+	for _, oracle := range []Oracle{AllOf(good, failing), AnyOf(failing, good), Not(failing)} {
+		_, err := oracle.Evaluate(context.Background(), Candidate{})
+		assert.ErrorIs(t, err, assert.AnError)
+	}
+}
@@ -0,0 +1,63 @@
+package lib
+
+import "sync"
+
+// testRun is a verdict a test gave, kept to spot later verdicts contradicting
+// it
+type testRun struct {
+	step   Step
+	output string // The end of the test's stdout and stderr, if it printed any
+}
+
+// runs tracks the verdicts automatic probes got. Since a prefix that is bad
+// stays bad when it grows, a good verdict at or after a bad one, or a bad
+// verdict at or before a good one, means the test isn't reliable and the
+// result can't be trusted. Single line candidates only have to agree with
+// themselves.
+type runs struct {
+	mode      CandidateMode
+	firstGood bool // Whether good prefixes stay good instead
+
+	mu   sync.Mutex
+	seen []testRun
+}
+
+// check returns a ContradictionError if run conflicts with a recorded run
+func (r *runs) check(run testRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, earlier := range r.seen {
+		if r.conflict(earlier.step, run.step) {
+			e, l := earlier.step, run.step
+			return &ContradictionError{
+				Earlier:       e,
+				Later:         l,
+				EarlierOutput: earlier.output,
+				LaterOutput:   run.output,
+			}
+		}
+	}
+	return nil
+}
+
+// conflict reports whether verdicts e and l can't both be right. Skipped lines
+// never conflict with anything.
+func (r *runs) conflict(e, l Step) bool {
+	switch {
+	case e.Verdict == l.Verdict, e.Verdict == Skip, l.Verdict == Skip:
+		return false
+	case r.mode == CandidateLine:
+		return e.Index == l.Index
+	case (e.Verdict == Bad) != r.firstGood:
+		return l.Index >= e.Index
+	default:
+		return l.Index <= e.Index
+	}
+}
+
+// add records runs for later checks
+func (r *runs) add(runs ...testRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, runs...)
+}
@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuns_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     CandidateMode
+		earlier  Step
+		later    Step
+		conflict bool
+	}{
+		{"good after bad", CandidatePrefix, Step{3, Bad}, Step{5, Good}, true},
+		{"good at a bad line", CandidatePrefix, Step{3, Bad}, Step{3, Good}, true},
+		{"good before bad", CandidatePrefix, Step{3, Bad}, Step{1, Good}, false},
+		{"bad before good", CandidatePrefix, Step{3, Good}, Step{1, Bad}, true},
+		{"bad after good", CandidatePrefix, Step{3, Good}, Step{5, Bad}, false},
+		{"same verdict", CandidatePrefix, Step{3, Bad}, Step{5, Bad}, false},
+		{"other line", CandidateLine, Step{3, Bad}, Step{5, Good}, false},
+		{"same line", CandidateLine, Step{3, Bad}, Step{3, Good}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &runs{mode: tt.mode}
+			r.add(testRun{step: tt.earlier, output: "earlier"})
+			err := r.check(testRun{step: tt.later, output: "later"})
+			if !tt.conflict {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrContradiction)
+			assert.Equal(t, &ContradictionError{
+				Earlier:       tt.earlier,
+				Later:         tt.later,
+				EarlierOutput: "earlier",
+				LaterOutput:   "later",
+			}, err)
+		})
+	}
+}
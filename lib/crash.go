@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type crashKey struct{}
+
+// crashWatch remembers whether the last test command of a probe was killed by
+// a signal, so WithOnCrash can treat a crash differently from a failing test
+type crashWatch struct {
+	mu      sync.Mutex
+	command string // Last command that crashed, empty if the last one didn't
+	code    int    // Its exit code
+}
+
+// withCrashWatch returns a copy of ctx whose test commands report to the
+// returned crashWatch
+func withCrashWatch(ctx context.Context) (context.Context, *crashWatch) {
+	w := &crashWatch{}
+	return context.WithValue(ctx, crashKey{}, w), w
+}
+
+// noteExit tells the crashWatch carried by ctx, if any, how cmdStr exited
+func noteExit(ctx context.Context, cmdStr string, code int) {
+	w, _ := ctx.Value(crashKey{}).(*crashWatch)
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.command, w.code = "", 0
+	if crashed(code) {
+		w.command, w.code = cmdStr, code
+	}
+}
+
+// err returns a TestCommandError wrapping ErrTestCrashed if the last command
+// crashed
+func (w *crashWatch) err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.command == "" {
+		return nil
+	}
+	return &TestCommandError{Command: w.command, ExitCode: w.code, Err: fmt.Errorf("%w (%s)", ErrTestCrashed, describeCrash(w.code))}
+}
+
+// crashed reports whether a command with exit code exited because of a crash
+// rather than on its own: killed by a signal outright (-1), by one a shell
+// reported as 128+N, or with a Windows NTSTATUS error such as an access
+// violation
+func crashed(code int) bool {
+	switch code {
+	case -1,
+		128 + 4,  // SIGILL
+		128 + 6,  // SIGABRT
+		128 + 7,  // SIGBUS on Linux
+		128 + 8,  // SIGFPE
+		128 + 9,  // SIGKILL, as sent by the OOM killer
+		128 + 11: // SIGSEGV
+		return true
+	}
+	return uint32(code)&0xC0000000 == 0xC0000000
+}
+
+// describeCrash says how a command with exit code crashed
+func describeCrash(code int) string {
+	switch {
+	case code == -1:
+		return "killed by a signal"
+	case code > 128 && code < 256:
+		return fmt.Sprintf("killed by signal %d", code-128)
+	default:
+		return fmt.Sprintf("exit status 0x%X", uint32(code))
+	}
+}
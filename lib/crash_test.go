@@ -0,0 +1,20 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrashed(t *testing.T) {
+	for _, code := range []int{-1, 134, 137, 139, int(int32(-1073741819))} {
+		assert.True(t, crashed(code), "exit code %d", code)
+	}
+	for _, code := range []int{0, 1, 2, 125, 126, 127, 128, 130, 143, 255} {
+		assert.False(t, crashed(code), "exit code %d", code)
+	}
+
+	assert.Equal(t, "killed by a signal", describeCrash(-1))
+	assert.Equal(t, "killed by signal 11", describeCrash(139))
+	assert.Equal(t, "exit status 0xC0000005", describeCrash(int(int32(-1073741819))))
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// EscapeControl makes terminal escape sequences and other control characters
+// in s visible as Go-style escapes like \x1b, so a line printed to a terminal
+// can't recolor or move the cursor over what surrounds it, or hide its own
+// content behind a carriage return or bidirectional override. Tabs and
+// everything else are left alone.
+func EscapeControl(s string) string {
+	i := strings.IndexFunc(s, needsEscape)
+	if i < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		switch {
+		case !needsEscape(r):
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
+
+// needsEscape reports whether EscapeControl escapes r
+func needsEscape(r rune) bool {
+	return r != '\t' && (unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r))
+}
+
+// display prepares line for the progress output, escaping control characters
+// unless WithRawDisplay asked for lines as they are
+func (s *search) display(line string) string {
+	if s.rawDisplay {
+		return line
+	}
+	return EscapeControl(line)
+}
+
+// gutterWidth is the width of the line number column in front of displayed
+// lines, as in "  42 | "
+const gutterWidth = 7
+
+// WrapLine splits line into pieces that each take up at most width terminal
+// columns, measuring wide characters such as CJK and emoji as two columns and
+// keeping combining characters with the one they modify. A width below 1
+// leaves line whole.
+func WrapLine(line string, width int) []string {
+	if width < 1 || runewidth.StringWidth(line) <= width {
+		return []string{line}
+	}
+
+	var pieces []string
+	for line != "" {
+		piece := runewidth.Truncate(line, width, "")
+		if piece == "" {
+			// A character wider than the whole width gets a piece of its own
+			_, size := utf8.DecodeRuneInString(line)
+			piece = line[:size]
+		}
+		pieces = append(pieces, piece)
+		line = line[len(piece):]
+	}
+	return pieces
+}
+
+// writeNumbered writes line after a line number gutter, wrapped to fit into
+// width columns with the continuation pieces under an empty gutter.
+// numStyle and lineStyle are codes from colors for the number and the text.
+func writeNumbered(w io.Writer, colors Palette, num int, numStyle, lineStyle, line string, width int) {
+	for i, piece := range WrapLine(line, width-gutterWidth) {
+		if i == 0 {
+			fmt.Fprintf(w, "%s%4d%s | %s%s%s\n", numStyle, num, colors.Reset, lineStyle, piece, colors.Reset)
+		} else {
+			fmt.Fprintf(w, "%s    %s | %s%s%s\n", numStyle, colors.Reset, lineStyle, piece, colors.Reset)
+		}
+	}
+}
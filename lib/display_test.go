@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeControl(t *testing.T) {
+	testCases := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"tabs\tstay", "tabs\tstay"},
+		{"日本語 ✓", "日本語 ✓"},
+		{"\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`},
+		{"secret\rshown", `secret\x0dshown`},
+		{"c1 \u009b csi", `c1 \x9b csi`},
+		{"evil\u202eexe.txt", `evil\u202eexe.txt`},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, EscapeControl(tc.in))
+	}
+}
+
+func TestInteractiveBisector_EscapesDisplay(t *testing.T) {
+	lines := []string{"good", "\x1b[2Jcleared", "\x1b[31mbad"}
+
+	var out bytes.Buffer
+	bisector, err := New(lines, WithInput(strings.NewReader("g\n")), WithOutput(&out))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `\x1b[2Jcleared`)
+	assert.NotContains(t, out.String(), "\x1b[2J")
+	assert.Equal(t, "\x1b[31mbad", result.BadLineContent, "results keep the raw line")
+
+	out.Reset()
+	bisector, err = New(lines, WithInput(strings.NewReader("g\n")), WithOutput(&out), WithRawDisplay())
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "\x1b[2Jcleared")
+}
+
+func TestInteractiveBisector_WithoutColor(t *testing.T) {
+	lines := []string{"good1", "good2", "bad1", "bad2"}
+
+	var out bytes.Buffer
+	bisector, err := New(lines, WithInput(strings.NewReader("x\ng\nb\n")), WithOutput(&out), WithoutColor())
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.NotContains(t, out.String(), "\x1b[")
+	assert.Contains(t, out.String(), "   2 | good2\n")
+	assert.Contains(t, out.String(), "⚠ Invalid input. Please enter")
+}
+
+func TestWrapLine(t *testing.T) {
+	testCases := []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"no width given", 0, []string{"no width given"}},
+		{"abcdefgh", 3, []string{"abc", "def", "gh"}},
+		// Wide characters take two columns and aren't split
+		{"日本語のテキスト", 5, []string{"日本", "語の", "テキ", "スト"}},
+		{"ab🎉cd", 3, []string{"ab", "🎉c", "d"}},
+		// Combining accents stay with their letter
+		{"cafe\u0301s", 4, []string{"cafe\u0301", "s"}},
+		{"日", 1, []string{"日"}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, WrapLine(tc.line, tc.width), "%q in %d columns", tc.line, tc.width)
+	}
+}
+
+func TestInteractiveBisector_WrapsDisplay(t *testing.T) {
+	lines := []string{"good", "日本語のテキスト", "bad"}
+
+	var out bytes.Buffer
+	bisector, err := New(lines, WithInput(strings.NewReader("g\n")), WithOutput(&out), WithDisplayWidth(gutterWidth+6))
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	plain := regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAllString(out.String(), "")
+	assert.Contains(t, plain, "   1 | good\n   2 | 日本語\n     | のテキ\n     | スト\n   3 | bad\n")
+}
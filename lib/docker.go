@@ -0,0 +1,85 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// candidateMountDir is where DockerRunner and KubernetesRunner mount candidate
+// files
+const candidateMountDir = "/bsct"
+
+// DockerRunner runs every command in a fresh container of Image with the
+// docker CLI, for hermetic probes of environment-sensitive failures. Staged
+// candidate files are mounted read-only under /bsct.
+type DockerRunner struct {
+	Image  string   // Image to run
+	Mounts []string // Extra volumes in docker -v syntax, e.g. ./src:/src:ro
+	Args   []string // Extra docker run options, e.g. --network=host
+	Shell  string   // Shell in the image that runs commands, sh if empty
+
+	mu     sync.Mutex
+	staged map[string]string // Container path to host path
+}
+
+// Run runs command in a new container that is removed afterwards
+func (r *DockerRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	return runProcess(exec.CommandContext(ctx, "docker", r.args(command)...), stdout, stderr)
+}
+
+// args returns the docker arguments that run command
+func (r *DockerRunner) args(command string) []string {
+	args := []string{"run", "--rm", "-i"}
+
+	r.mu.Lock()
+	var mounts []string
+	for inside, host := range r.staged {
+		mounts = append(mounts, host+":"+inside+":ro")
+	}
+	r.mu.Unlock()
+	sort.Strings(mounts)
+
+	for _, m := range append(mounts, r.Mounts...) {
+		args = append(args, "-v", m)
+	}
+	args = append(args, r.Args...)
+
+	shell := r.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	return append(args, r.Image, shell, "-c", command)
+}
+
+// Stage mounts localPath into the containers started from now on
+func (r *DockerRunner) Stage(ctx context.Context, localPath string) (string, error) {
+	host, err := filepath.Abs(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", localPath, err)
+	}
+	inside := path.Join(candidateMountDir, filepath.Base(localPath))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.staged == nil {
+		r.staged = make(map[string]string)
+	}
+	r.staged[inside] = host
+	return inside, nil
+}
+
+// Unstage stops mounting a staged file
+func (r *DockerRunner) Unstage(ctx context.Context, inside string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.staged, inside)
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerRunner_Args(t *testing.T) {
+	runner := &DockerRunner{Image: "alpine:3", Mounts: []string{"/src:/src:ro"}, Args: []string{"--network=none"}}
+	local := filepath.Join(t.TempDir(), "bsct-123.txt")
+
+	inside, err := runner.Stage(context.Background(), local)
+	require.NoError(t, err)
+	assert.Equal(t, "/bsct/bsct-123.txt", inside)
+
+	assert.Equal(t, []string{
+		"run", "--rm", "-i",
+		"-v", local + ":/bsct/bsct-123.txt:ro",
+		"-v", "/src:/src:ro",
+		"--network=none",
+		"alpine:3", "sh", "-c", "./check /bsct/bsct-123.txt",
+	}, runner.args("./check /bsct/bsct-123.txt"))
+
+	require.NoError(t, runner.Unstage(context.Background(), inside))
+	assert.Equal(t, []string{
+		"run", "--rm", "-i", "-v", "/src:/src:ro", "--network=none", "alpine:3", "sh", "-c", "true",
+	}, runner.args("true"))
+}
@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// syncFile flushes the file at path and the directory entry naming it to
+// stable storage, so a process elsewhere, like a container or an NFS client,
+// sees the whole file as soon as a test starts. The file is opened for writing
+// since Windows refuses to flush a read-only handle.
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes the entries of dir to stable storage. Windows can't sync
+// directories and commits their entries on its own.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
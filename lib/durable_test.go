@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "candidate.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\n"), 0644))
+	assert.NoError(t, syncFile(path))
+	assert.ErrorIs(t, syncFile(path+".missing"), os.ErrNotExist)
+}
+
+func TestAutomaticBisector_Sync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0644))
+
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		if strings.Contains(string(data), "bad") {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+	bisector, err := New([]string{"a", "b", "bad", "d"}, WithOracle(oracle), WithTarget(path), WithSync(), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+}
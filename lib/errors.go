@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoInput is returned when there are no lines to bisect
+	ErrNoInput = errors.New("no input lines provided")
+	// ErrBadBeforeGood is returned when the known bad line doesn't come after
+	// the known good line
+	ErrBadBeforeGood = errors.New("good line must come before bad line")
+	// ErrGoodBeforeBad is returned with WithFindFirstGood when the known good
+	// line doesn't come after the known bad line
+	ErrGoodBeforeBad = errors.New("bad line must come before good line when finding the first good line")
+	// ErrPatternNotFound is returned when a --good or --bad pattern matches no line
+	ErrPatternNotFound = errors.New("pattern not found in input")
+	// ErrTestCommandFailed matches every *TestCommandError
+	ErrTestCommandFailed = errors.New("test command failed")
+	// ErrCommandNotFound is wrapped by a TestCommandError when the shell
+	// couldn't find the test command (exit code 127)
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrNotExecutable is wrapped by a TestCommandError when the shell found
+	// the test command but couldn't execute it (exit code 126)
+	ErrNotExecutable = errors.New("command not executable")
+	// ErrTestCrashed is wrapped by a TestCommandError when a test command was
+	// killed by a signal such as SIGSEGV and WithOnCrash aborts on crashes
+	ErrTestCrashed = errors.New("crashed")
+	// ErrProbeTimeout is returned by TimeoutOracle when a probe runs too long
+	ErrProbeTimeout = errors.New("probe timed out")
+	// ErrInterrupted is returned when a bisection stops because its context is
+	// done. The context's own error is wrapped alongside it, and the progress
+	// so far is returned with it in a Result with Interrupted set.
+	ErrInterrupted = errors.New("bisection interrupted")
+	// ErrTempLimit is returned when candidate files would take up more disk
+	// than WithMaxTempBytes allows
+	ErrTempLimit = errors.New("temp disk limit reached")
+	// ErrTargetBackup is returned when the WithTarget file can't be backed up,
+	// in which case bisecting doesn't start
+	ErrTargetBackup = errors.New("failed to back up target file")
+	// ErrTargetRestore is returned when the WithTarget file couldn't be put
+	// back as it was. Its backup is kept.
+	ErrTargetRestore = errors.New("failed to restore target file")
+	// ErrContradiction matches every *ContradictionError
+	ErrContradiction = errors.New("contradicting verdicts")
+	// ErrStateMismatch is returned by Load when saved state doesn't fit the
+	// bisector loading it, e.g. because the input changed
+	ErrStateMismatch = errors.New("saved state does not match this bisection")
+)
+
+// TestCommandError reports a command that could not produce a verdict, as
+// opposed to one that ran and exited non-zero
+type TestCommandError struct {
+	Command  string // Command after placeholder substitution
+	ExitCode int    // Exit code, or -1 if the command never exited normally
+	Err      error  // Underlying error
+}
+
+// Error describes the failure
+func (e *TestCommandError) Error() string {
+	if e.ExitCode >= 0 {
+		return fmt.Sprintf("test command %q failed with exit code %d: %v", e.Command, e.ExitCode, e.Err)
+	}
+	return fmt.Sprintf("test command %q failed: %v", e.Command, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *TestCommandError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrTestCommandFailed) match any TestCommandError
+func (e *TestCommandError) Is(target error) bool { return target == ErrTestCommandFailed }
+
+// ContradictionError reports a verdict that conflicts with an earlier one,
+// such as a line judged good after an earlier line was judged bad. It means
+// the test is flaky or depends on more than the candidate, so a bisection
+// stops rather than narrowing on inconsistent verdicts.
+type ContradictionError struct {
+	Earlier, Later             Step
+	EarlierOutput, LaterOutput string // The end of each test's output
+}
+
+// Error describes the conflict
+func (e *ContradictionError) Error() string {
+	return fmt.Sprintf("line %d was judged %s, contradicting line %d judged %s earlier",
+		e.Later.Index+1, e.Later.Verdict, e.Earlier.Index+1, e.Earlier.Verdict)
+}
+
+// Is makes errors.Is(err, ErrContradiction) match any ContradictionError
+func (e *ContradictionError) Is(target error) bool { return target == ErrContradiction }
+
+// interrupted wraps the error of a done ctx in ErrInterrupted
+func interrupted(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrInterrupted, ctx.Err())
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestCommandError(t *testing.T) {
+	cause := errors.New("exec: \"sh\": executable file not found")
+	var err error = &TestCommandError{Command: "./check.sh", ExitCode: -1, Err: cause}
+
+	assert.ErrorIs(t, err, ErrTestCommandFailed)
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.Error(), "./check.sh")
+
+	var cmdErr *TestCommandError
+	require.ErrorAs(t, err, &cmdErr)
+	assert.Equal(t, -1, cmdErr.ExitCode)
+
+	err = &TestCommandError{Command: "x", ExitCode: 3, Err: cause}
+	assert.Contains(t, err.Error(), "exit code 3")
+}
+
+func TestErrInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bisector, err := New([]string{"a", "b", "c"}, WithInput(strings.NewReader("")), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	_, err = bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNew_SentinelErrors(t *testing.T) {
+	_, err := New(nil)
+	assert.ErrorIs(t, err, ErrNoInput)
+
+	_, err = New([]string{"a", "b"}, WithBoundaries(1, 0))
+	assert.ErrorIs(t, err, ErrBadBeforeGood)
+}
+
+func TestFindBoundaries(t *testing.T) {
+	src := Lines{"boot", "SUCCESS", "work", "FATAL", "FATAL again"}
+
+	goodIdx, badIdx, err := FindBoundaries(src, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, goodIdx)
+	assert.Equal(t, 4, badIdx)
+
+	goodIdx, badIdx, err = FindBoundaries(src, "SUCCESS", "FATAL")
+	require.NoError(t, err)
+	assert.Equal(t, 1, goodIdx)
+	assert.Equal(t, 3, badIdx)
+
+	_, _, err = FindBoundaries(src, "missing", "")
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+	assert.Contains(t, err.Error(), `good pattern not found in input: "missing"`)
+
+	_, _, err = FindBoundaries(src, "", "missing")
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+
+	_, _, err = FindBoundaries(src, "FATAL", "SUCCESS")
+	assert.ErrorIs(t, err, ErrBadBeforeGood)
+
+	_, _, err = FindBoundaries(Lines{}, "", "")
+	assert.ErrorIs(t, err, ErrNoInput)
+}
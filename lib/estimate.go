@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Estimate projects how long a bisection will take from the duration of a few
+// probes timed up front
+type Estimate struct {
+	ProbeDuration time.Duration // Mean duration of a timed probe, hooks included
+	Probes        int           // Number of probes timed
+	Lines         int           // Lines that may be the first bad line
+	Concurrency   int           // Probes run at a time
+	Steps         int           // Most probes the bisection will make
+	Rounds        int           // Rounds of probes run one after another
+	Duration      time.Duration // Projected time for all rounds
+}
+
+// At returns the estimate for running n probes at a time, as WithConcurrency
+// does, from the same timed probes
+func (e Estimate) At(n int) Estimate {
+	n = max(n, 1)
+	e.Concurrency, e.Steps, e.Rounds = n, 0, 0
+	// Each round splits the gaps between the known good and bad lines into
+	// as many parts as it probes lines, plus one
+	for width := e.Lines; width > 1; e.Rounds++ {
+		points := min(n, width-1)
+		e.Steps += points
+		width = (width + points) / (points + 1)
+	}
+	e.Duration = time.Duration(e.Rounds) * e.ProbeDuration
+	return e
+}
+
+// estimate times up to probes representative probes, the first midpoint and
+// the midpoint of the half above it, without recording their verdicts, and
+// projects the bisection at concurrency from them
+func (b *AutomaticBisector) estimate(ctx context.Context, probes, concurrency int) (_ *Estimate, err error) {
+	file, err := newCandidateFile(b.budget)
+	if err != nil {
+		return nil, err
+	}
+	defer file.remove()
+	restore, err := b.useTarget()
+	if err != nil {
+		return nil, err
+	}
+	defer restore(&err)
+
+	e := Estimate{Lines: b.badIdx - b.goodIdx}
+	var total time.Duration
+	goodIdx := b.goodIdx
+	for e.Probes < probes {
+		idx := goodIdx + (b.badIdx-goodIdx)/2
+		if idx <= goodIdx {
+			break
+		}
+		c, err := b.candidate(idx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(b.out, "Timing a probe of line %d\n", idx+1)
+		start := time.Now()
+		if _, err := b.probe(ctx, c, file, fmt.Sprintf("estimate %d", idx+1)); err != nil {
+			if ctx.Err() != nil {
+				return nil, interrupted(ctx)
+			}
+			return nil, err
+		}
+		total += time.Since(start)
+		e.Probes++
+		goodIdx = idx
+	}
+	if e.Probes > 0 {
+		e.ProbeDuration = total / time.Duration(e.Probes)
+	}
+
+	e = e.At(concurrency)
+	return &e, nil
+}
+
+// Estimate times up to probes probes and projects how long bisecting the
+// range will take. The probes run their hooks like any other but their
+// verdicts aren't used, so Bisect afterwards starts from scratch.
+func (b *AutomaticBisector) Estimate(ctx context.Context, probes int) (*Estimate, error) {
+	return b.estimate(ctx, probes, 1)
+}
+
+// Estimate is like AutomaticBisector.Estimate, projecting rounds of probes
+// run concurrently
+func (b *ParallelBisector) Estimate(ctx context.Context, probes int) (*Estimate, error) {
+	return b.auto.estimate(ctx, probes, b.concurrency)
+}
+
+// Estimate is like AutomaticBisector.Estimate, projecting the two steps that
+// each test's duration covers
+func (b *SpeculativeBisector) Estimate(ctx context.Context, probes int) (*Estimate, error) {
+	e, err := b.auto.estimate(ctx, probes, 1)
+	if err != nil {
+		return nil, err
+	}
+	e.Concurrency = 3
+	e.Rounds = (e.Steps + 1) / 2
+	e.Duration = time.Duration(e.Rounds) * e.ProbeDuration
+	return e, nil
+}
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate_At(t *testing.T) {
+	testCases := []struct {
+		lines, concurrency int
+		steps, rounds      int
+	}{
+		{1, 1, 0, 0},
+		{2, 1, 1, 1},
+		{8, 1, 3, 3},
+		{7, 1, 3, 3},
+		{1000, 1, 10, 10},
+		{1000, 3, 15, 5},
+		{5, 8, 4, 1},
+	}
+	for _, tc := range testCases {
+		e := Estimate{ProbeDuration: time.Second, Lines: tc.lines}.At(tc.concurrency)
+		assert.Equal(t, tc.steps, e.Steps, "%d lines at %d", tc.lines, tc.concurrency)
+		assert.Equal(t, tc.rounds, e.Rounds, "%d lines at %d", tc.lines, tc.concurrency)
+		assert.Equal(t, time.Duration(tc.rounds)*time.Second, e.Duration)
+	}
+}
+
+func TestAutomaticBisector_Estimate(t *testing.T) {
+	lines := make([]string, 100)
+	var probed []int
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		probed = append(probed, c.Index)
+		time.Sleep(10 * time.Millisecond)
+		if c.Index >= 30 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	auto := bisector.(*AutomaticBisector)
+
+	e, err := auto.Estimate(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{49, 74}, probed)
+	assert.Equal(t, 2, e.Probes)
+	assert.GreaterOrEqual(t, e.ProbeDuration, 10*time.Millisecond)
+	assert.Equal(t, 7, e.Steps)
+	assert.Equal(t, 7*e.ProbeDuration, e.Duration)
+
+	// The timed probes don't count towards the bisection
+	result, err := auto.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 31, result.BadLineNumber)
+	assert.Equal(t, len(probed)-2, result.StepsTaken)
+
+	parallel, err := New(lines, WithOracle(oracle), WithConcurrency(3), WithOutput(io.Discard))
+	require.NoError(t, err)
+	e, err = parallel.(*ParallelBisector).Estimate(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, e.Concurrency)
+	assert.Equal(t, 4, e.Rounds)
+}
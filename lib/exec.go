@@ -0,0 +1,145 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runShell runs command with shell, the platform shell if empty, and returns
+// its exit code
+func runShell(ctx context.Context, shell, command string, stdout, stderr io.Writer) (int, error) {
+	cmd := createCommand(ctx, shell, command)
+	r := reaperFrom(ctx)
+	if r == nil {
+		return runProcess(cmd, stdout, stderr)
+	}
+	isolate(cmd, true)
+	return runReaped(cmd, stdout, stderr, r)
+}
+
+// runReaped runs cmd like runProcess and kills what is left of its process
+// group as soon as it exits. The output is copied through pipes of its own,
+// since exec would wait for leftover processes holding them to exit first.
+func runReaped(cmd *exec.Cmd, stdout, stderr io.Writer, r *reaper) (int, error) {
+	var copies sync.WaitGroup
+	var readers, writers []*os.File
+	defer func() {
+		for _, f := range append(readers, writers...) {
+			f.Close()
+		}
+	}()
+	pipe := func(w io.Writer) (io.Writer, error) {
+		if _, ok := w.(*os.File); ok || w == nil {
+			return w, nil
+		}
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		readers, writers = append(readers, pr), append(writers, pw)
+		copies.Add(1)
+		go func() {
+			defer copies.Done()
+			io.Copy(w, pr)
+		}()
+		return pw, nil
+	}
+	var err error
+	if cmd.Stdout, err = pipe(stdout); err != nil {
+		return -1, err
+	}
+	if cmd.Stderr, err = pipe(stderr); err != nil {
+		return -1, err
+	}
+
+	err = cmd.Start()
+	// Only the child holds the write ends from here on
+	for _, f := range writers {
+		f.Close()
+	}
+	if err == nil {
+		err = cmd.Wait()
+		if killGroup(cmd) {
+			r.reaped.Store(true)
+		}
+	}
+
+	// Children that escaped the group can still hold the pipes open
+	copied := make(chan struct{})
+	go func() {
+		copies.Wait()
+		close(copied)
+	}()
+	select {
+	case <-copied:
+	case <-time.After(waitDelay):
+		for _, f := range readers {
+			f.Close()
+		}
+		<-copied
+	}
+	return exitCode(err)
+}
+
+// runProcess runs cmd with the given output and returns its exit code. The
+// error is only non-nil when cmd could not be run at all.
+func runProcess(cmd *exec.Cmd, stdout, stderr io.Writer) (int, error) {
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return exitCode(cmd.Run())
+}
+
+// exitCode turns the error from running a command into its exit code, with
+// a non-nil error only if it could not be run at all
+func exitCode(err error) (int, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// -1 when killed by a signal, which is still a failed run
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		return -1, err
+	}
+	// A command that exited 0 but left a child such as a daemon holding its
+	// output open still passed
+	return 0, nil
+}
+
+// waitDelay is how long a killed command's children may keep its output
+// open before bsct stops waiting for them
+const waitDelay = time.Second
+
+// createCommand creates an exec.Cmd that runs cmdStr with shell as described
+// by WithShell, or in the platform shell if it is empty: cmd.exe on Windows,
+// sh elsewhere
+func createCommand(ctx context.Context, shell, cmdStr string) *exec.Cmd {
+	var cmd *exec.Cmd
+	fields := strings.Fields(shell)
+	switch name := shellName(shell); {
+	case len(fields) == 0:
+		cmd = shellCommand(ctx, cmdStr)
+	case len(fields) > 1:
+		cmd = exec.CommandContext(ctx, fields[0], append(fields[1:], cmdStr)...)
+	case name == "cmd":
+		cmd = cmdCommand(ctx, fields[0], cmdStr)
+	case name == "pwsh" || name == "powershell":
+		cmd = exec.CommandContext(ctx, fields[0], "-NoProfile", "-NonInteractive", "-Command", cmdStr)
+	default:
+		cmd = exec.CommandContext(ctx, fields[0], "-c", cmdStr)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// openTTY opens the controlling terminal for interactive prompts
+func openTTY() (*os.File, error) {
+	return os.Open("/dev/tty")
+}
@@ -0,0 +1,18 @@
+//go:build !windows && !js && !wasip1
+
+package lib
+
+import (
+	"context"
+	"os/exec"
+)
+
+// shellCommand runs cmdStr with sh -c
+func shellCommand(ctx context.Context, cmdStr string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", cmdStr)
+}
+
+// cmdCommand runs cmdStr with the cmd.exe at program, e.g. under Wine
+func cmdCommand(ctx context.Context, program, cmdStr string) *exec.Cmd {
+	return exec.CommandContext(ctx, program, "/d", "/s", "/c", cmdStr)
+}
@@ -0,0 +1,26 @@
+//go:build js || wasip1
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+)
+
+// errNoProcesses is returned where a native build would start a process or
+// open a terminal
+var errNoProcesses = errors.New("not supported on " + runtime.GOOS + "/" + runtime.GOARCH)
+
+// runShell fails: WebAssembly has no shell to run commands in. Use an Oracle
+// or a custom Runner instead.
+func runShell(ctx context.Context, shell, command string, stdout, stderr io.Writer) (int, error) {
+	return -1, errNoProcesses
+}
+
+// openTTY fails so interactive prompts fall back to stdin or WithInput
+func openTTY() (*os.File, error) {
+	return nil, errNoProcesses
+}
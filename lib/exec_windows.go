@@ -0,0 +1,23 @@
+package lib
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs cmdStr with cmd /c
+func shellCommand(ctx context.Context, cmdStr string) *exec.Cmd {
+	return cmdCommand(ctx, "cmd", cmdStr)
+}
+
+// cmdCommand runs cmdStr with the cmd.exe at program. cmd.exe doesn't split
+// its command line the way Go escapes arguments for other programs, so it gets
+// the command as written instead: /s strips just the outer quotes, leaving
+// quotes and ^ escapes in cmdStr for cmd.exe to interpret, and /d skips AutoRun
+// commands.
+func cmdCommand(ctx context.Context, program, cmdStr string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, program)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: program + ` /d /s /c "` + cmdStr + `"`}
+	return cmd
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ExistsOracle judges a candidate good when a path matching Pattern exists
+// after the before command has run, e.g. a build artifact only emitted on
+// success
+type ExistsOracle struct {
+	Pattern  string // Path or filepath.Match glob; supports {file} and {line} placeholders
+	NonEmpty bool   // Require a non-empty file, or a directory with entries
+}
+
+// Evaluate globs Pattern for c
+func (o *ExistsOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	pattern := substitutePlaceholders(o.Pattern, c.Path, c.Line)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return Bad, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	for _, path := range matches {
+		if !o.NonEmpty {
+			return Good, nil
+		}
+		if nonEmpty(path) {
+			return Good, nil
+		}
+	}
+	return Bad, nil
+}
+
+// nonEmpty reports whether path is a file with content or a directory with
+// at least one entry
+func nonEmpty(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return info.Size() > 0
+	}
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) > 0
+}
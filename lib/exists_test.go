@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExistsOracle(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app-1.2.tar"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.log"), nil, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "out"), 0755))
+
+	testCases := []struct {
+		name   string
+		oracle ExistsOracle
+		line   string
+		want   Verdict
+	}{
+		{"path exists", ExistsOracle{Pattern: filepath.Join(dir, "app-1.2.tar")}, "", Good},
+		{"path missing", ExistsOracle{Pattern: filepath.Join(dir, "app-1.3.tar")}, "", Bad},
+		{"glob", ExistsOracle{Pattern: filepath.Join(dir, "app-*.tar")}, "", Good},
+		{"line placeholder", ExistsOracle{Pattern: filepath.Join(dir, "app-{line}.tar")}, "1.2", Good},
+		{"empty file", ExistsOracle{Pattern: filepath.Join(dir, "empty.log"), NonEmpty: true}, "", Bad},
+		{"non-empty file", ExistsOracle{Pattern: filepath.Join(dir, "*.tar"), NonEmpty: true}, "", Good},
+		{"empty dir", ExistsOracle{Pattern: filepath.Join(dir, "out"), NonEmpty: true}, "", Bad},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := tc.oracle.Evaluate(context.Background(), Candidate{Line: tc.line})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+
+	_, err := (&ExistsOracle{Pattern: "["}).Evaluate(context.Background(), Candidate{})
+	assert.Error(t, err)
+}
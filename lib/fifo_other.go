@@ -0,0 +1,10 @@
+//go:build !unix
+
+package lib
+
+import "errors"
+
+// mkfifo fails where named pipes can't be created with a path
+func mkfifo(path string) error {
+	return errors.New("named pipes aren't supported on this platform")
+}
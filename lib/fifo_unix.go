@@ -0,0 +1,18 @@
+//go:build unix
+
+package lib
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// mkfifo creates a named pipe at path, or leaves the one that is there
+func mkfifo(path string) error {
+	err := syscall.Mkfifo(path, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// HTTPOracle judges candidates by an HTTP response, for when the system under
+// test is a service that picks up the candidate itself, e.g. from a before
+// command that installs it as its config
+type HTTPOracle struct {
+	URL          string         // Supports {file} and {line} placeholders, query-escaped
+	Method       string         // GET if empty, or POST when Upload is set
+	Upload       bool           // Send the candidate content as the request body
+	ExpectStatus int            // Status code meaning good, 200 if zero
+	BodyPattern  *regexp.Regexp // If set, the body must also match for good
+	Client       *http.Client   // http.DefaultClient if nil
+}
+
+// Evaluate sends the request for c. A request that fails outright, e.g.
+// because the service is down, is judged bad.
+func (o *HTTPOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	target := strings.NewReplacer(
+		"{file}", url.QueryEscape(c.Path),
+		"{line}", url.QueryEscape(c.Line),
+	).Replace(o.URL)
+
+	method := o.Method
+	var body io.Reader
+	if o.Upload {
+		var buf bytes.Buffer
+		if _, err := c.WriteTo(&buf); err != nil {
+			return Bad, fmt.Errorf("failed to read candidate: %w", err)
+		}
+		body = &buf
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return Bad, fmt.Errorf("invalid HTTP request: %w", err)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Bad, ctxErr
+	}
+	if err != nil {
+		return Bad, nil
+	}
+	defer resp.Body.Close()
+
+	want := o.ExpectStatus
+	if want == 0 {
+		want = http.StatusOK
+	}
+	if resp.StatusCode != want {
+		return Bad, nil
+	}
+
+	if o.BodyPattern != nil {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return Bad, fmt.Errorf("failed to read response: %w", err)
+		}
+		if !o.BodyPattern.Match(data) {
+			return Bad, nil
+		}
+	}
+	return Good, nil
+}
@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPOracle(t *testing.T) {
+	// The service reports 500 once the uploaded config contains "broken"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(data), "broken") || r.URL.Query().Get("line") == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "status: ok")
+	}))
+	defer server.Close()
+
+	lines := Lines{"a", "b", "broken"}
+	testCases := []struct {
+		name   string
+		oracle *HTTPOracle
+		index  int
+		want   Verdict
+	}{
+		{"upload good", &HTTPOracle{URL: server.URL, Upload: true}, 1, Good},
+		{"upload bad", &HTTPOracle{URL: server.URL, Upload: true}, 2, Bad},
+		{"placeholder bad", &HTTPOracle{URL: server.URL + "?line={line}"}, 2, Bad},
+		{"expected status", &HTTPOracle{URL: server.URL, Upload: true, ExpectStatus: 500}, 2, Good},
+		{"body matches", &HTTPOracle{URL: server.URL, BodyPattern: regexp.MustCompile(`status: ok`)}, 1, Good},
+		{"body doesn't match", &HTTPOracle{URL: server.URL, BodyPattern: regexp.MustCompile(`ready`)}, 1, Bad},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			line, _ := lines.Line(tc.index)
+			v, err := tc.oracle.Evaluate(context.Background(), Candidate{Index: tc.index, Line: line, src: lines})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+}
+
+func TestHTTPOracle_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	v, err := (&HTTPOracle{URL: url}).Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Bad, v)
+}
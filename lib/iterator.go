@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"time"
+)
+
+// Probe is a candidate an Iterator wants a verdict for
+type Probe struct {
+	Candidate
+	Step int // 1-indexed step number
+
+	goodIdx, badIdx int          // Range the probe splits
+	skipped         map[int]bool // Indices skipped before the probe
+	sequential      bool         // Whether the range alone decides the next probe
+}
+
+// Next returns the candidate that will be probed after p if it gets verdict
+// v, e.g. to prepare its content while p is still being judged. It returns
+// false if v would end the bisection or the next probe isn't known ahead, as
+// with a ParallelBisector.
+func (p Probe) Next(v Verdict) (Candidate, bool) {
+	if !p.sequential {
+		return Candidate{}, false
+	}
+	s := search{src: p.src, mode: p.mode, goodIdx: p.goodIdx, badIdx: p.badIdx, skipped: maps.Clone(p.skipped)}
+	s.record(p.Index, v)
+	idx, ok := s.next()
+	if !ok {
+		return Candidate{}, false
+	}
+	c, err := s.candidate(idx)
+	return c, err == nil
+}
+
+// Iterator lets callers drive a bisection one probe at a time instead of
+// blocking inside Bisect, e.g. from a GUI or web handler. Only boundary and
+// candidate options apply; commands, oracles and I/O are the caller's job.
+type Iterator struct {
+	s       search
+	pending bool
+	err     error
+}
+
+// NewIterator creates an Iterator for lines
+func NewIterator(lines []string, opts ...Option) (*Iterator, error) {
+	return NewIteratorFromSource(Lines(lines), opts...)
+}
+
+// NewIteratorFromSource creates an Iterator that reads lines from src
+func NewIteratorFromSource(src Source, opts ...Option) (*Iterator, error) {
+	cfg, err := newConfig(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	s := cfg.search(src)
+	s.started = time.Now()
+	return &Iterator{s: s}, nil
+}
+
+// Next returns the probe awaiting a verdict, or false once the first bad line
+// is known or a line can't be read from the Source; Err tells the two apart.
+// Calling Next again before Report returns the same probe.
+func (it *Iterator) Next() (Probe, bool) {
+	if it.err != nil {
+		return Probe{}, false
+	}
+	idx, ok := it.s.next()
+	if !ok {
+		return Probe{}, false
+	}
+	c, err := it.s.candidate(idx)
+	if err != nil {
+		it.err = err
+		return Probe{}, false
+	}
+	it.pending = true
+	return it.s.newProbe(c, it.s.steps+1), true
+}
+
+// Err returns the error that stopped Next, or nil if the bisection can go on or
+// finished normally
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Report records the verdict for the probe last returned by Next
+func (it *Iterator) Report(v Verdict) error {
+	if !it.pending {
+		return fmt.Errorf("no probe awaiting a verdict")
+	}
+	idx, _ := it.s.next()
+	it.pending = false
+	it.s.steps++
+	it.s.record(idx, v)
+	return nil
+}
+
+// Done reports whether the first bad line is known
+func (it *Iterator) Done() bool {
+	_, ok := it.s.next()
+	return !ok
+}
+
+// Range returns the 0-indexed last known good and first known bad lines
+func (it *Iterator) Range() (goodIdx, badIdx int) {
+	return it.s.goodIdx, it.s.badIdx
+}
+
+// Result returns the outcome once Done reports true
+func (it *Iterator) Result() (*Result, error) {
+	if !it.Done() {
+		return nil, fmt.Errorf("bisection not finished: lines %d-%d remain", it.s.goodIdx+1, it.s.badIdx+1)
+	}
+	return it.s.result()
+}
+
+// Save writes the progress made so far
+func (it *Iterator) Save(w io.Writer) error { return it.s.Save(w) }
+
+// Load restores progress written by Save, dropping any probe awaiting a
+// verdict
+func (it *Iterator) Load(r io.Reader) error {
+	if err := it.s.Load(r); err != nil {
+		return err
+	}
+	it.pending = false
+	it.err = nil
+	return nil
+}
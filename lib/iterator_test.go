@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator(t *testing.T) {
+	lines := []string{"good", "good", "good", "bad", "bad", "bad", "bad"}
+
+	it, err := NewIterator(lines)
+	require.NoError(t, err)
+
+	steps := 0
+	for {
+		probe, ok := it.Next()
+		if !ok {
+			break
+		}
+		steps++
+		assert.Equal(t, steps, probe.Step)
+
+		// Next is idempotent until Report
+		again, _ := it.Next()
+		assert.Equal(t, probe.Index, again.Index)
+
+		if probe.Line == "bad" {
+			require.NoError(t, it.Report(Bad))
+		} else {
+			require.NoError(t, it.Report(Good))
+		}
+	}
+
+	assert.True(t, it.Done())
+	goodIdx, badIdx := it.Range()
+	assert.Equal(t, 2, goodIdx)
+	assert.Equal(t, 3, badIdx)
+
+	result, err := it.Result()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, steps, result.StepsTaken)
+}
+
+func TestProbe_Next(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	it, err := NewIterator(lines)
+	require.NoError(t, err)
+	for step := 0; !it.Done(); step++ {
+		probe, ok := it.Next()
+		require.True(t, ok)
+		v := Good
+		if step%2 == 1 {
+			v = Bad
+		}
+		next, hasNext := probe.Next(v)
+
+		require.NoError(t, it.Report(v))
+		actual, ok := it.Next()
+		require.Equal(t, ok, hasNext)
+		if ok {
+			assert.Equal(t, actual.Index, next.Index)
+			assert.Equal(t, actual.Line, next.Line)
+		}
+	}
+
+	parallel := Probe{Candidate: Candidate{Index: 3, src: Lines(lines)}}
+	_, ok := parallel.Next(Good)
+	assert.False(t, ok)
+}
+
+func TestIterator_Skip(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	it, err := NewIterator(lines)
+	require.NoError(t, err)
+
+	// Line 5 can't be tested, so its lower neighbour is probed instead
+	probe, ok := it.Next()
+	require.True(t, ok)
+	assert.Equal(t, 4, probe.Index)
+	next, ok := probe.Next(Skip)
+	require.True(t, ok)
+	require.NoError(t, it.Report(Skip))
+	probe, ok = it.Next()
+	require.True(t, ok)
+	assert.Equal(t, 3, probe.Index)
+	assert.Equal(t, next.Index, probe.Index)
+
+	// The skipped line 5 leaves it and line 6 as the first bad line
+	require.NoError(t, it.Report(Good))
+	probe, ok = it.Next()
+	require.True(t, ok)
+	assert.Equal(t, 5, probe.Index)
+	require.NoError(t, it.Report(Bad))
+
+	assert.True(t, it.Done())
+	result, err := it.Result()
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.BadLineNumber)
+	assert.Equal(t, 5, result.RangeStart)
+	assert.Equal(t, 6, result.RangeEnd)
+	assert.Equal(t, []int{5}, result.Skipped)
+}
+
+func TestIterator_ReportWithoutProbe(t *testing.T) {
+	it, err := NewIterator([]string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Error(t, it.Report(Good))
+
+	_, err = it.Result()
+	assert.Error(t, err)
+}
+
+func TestIterator_Boundaries(t *testing.T) {
+	it, err := NewIterator([]string{"a", "b", "c", "d", "e"}, WithBoundaries(3, 4))
+	require.NoError(t, err)
+
+	_, ok := it.Next()
+	assert.False(t, ok)
+
+	result, err := it.Result()
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, 0, result.StepsTaken)
+}
+
+// errReaderAt fails every read
+type errReaderAt struct{}
+
+func (errReaderAt) ReadAt([]byte, int64) (int, error) { return 0, assert.AnError }
+
+func TestIterator_SourceError(t *testing.T) {
+	input := "a\nb\nc\n"
+	idx, err := NewLineIndex(strings.NewReader(input), int64(len(input)))
+	require.NoError(t, err)
+	idx.r = errReaderAt{} // Indexed fine, but lines can no longer be read
+
+	it, err := NewIteratorFromSource(idx)
+	require.NoError(t, err)
+
+	_, ok := it.Next()
+	assert.False(t, ok)
+	assert.ErrorIs(t, it.Err(), assert.AnError)
+	assert.False(t, it.Done())
+}
@@ -0,0 +1,206 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KubernetesRunner runs every command as a Kubernetes Job with the kubectl
+// CLI, for probes that need cluster-only resources. Staged candidate files
+// are delivered as ConfigMaps mounted under /bsct. The Job's container exit
+// code becomes the command's exit code.
+type KubernetesRunner struct {
+	Image     string        // Image the Job runs
+	Namespace string        // Namespace for Jobs and ConfigMaps, kubectl's default if empty
+	Args      []string      // Extra kubectl options, e.g. --context=staging
+	Shell     string        // Shell in the image that runs commands, sh if empty
+	Poll      time.Duration // How often to check on a running Job, 2s if zero
+
+	mu     sync.Mutex
+	staged map[string]string // ConfigMap name to the file name inside it
+}
+
+// Run submits command as a Job, waits for it to finish, copies its logs to
+// stdout and deletes it
+func (r *KubernetesRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	name, err := randomName("bsct-job-")
+	if err != nil {
+		return -1, err
+	}
+
+	manifest, err := json.Marshal(r.job(name, command))
+	if err != nil {
+		return -1, err
+	}
+	if err := r.kubectl(ctx, bytes.NewReader(manifest), nil, "apply", "-f", "-"); err != nil {
+		return -1, fmt.Errorf("failed to create job: %w", err)
+	}
+	defer r.kubectl(context.WithoutCancel(ctx), nil, nil, "delete", "job", name, "--ignore-not-found", "--wait=false", "--cascade=background")
+
+	if err := r.wait(ctx, name); err != nil {
+		return -1, err
+	}
+	if stdout != nil {
+		r.kubectl(ctx, nil, stdout, "logs", "job/"+name)
+	}
+
+	var code bytes.Buffer
+	err = r.kubectl(ctx, nil, &code, "get", "pods", "-l", "job-name="+name,
+		"-o", "jsonpath={.items[0].status.containerStatuses[0].state.terminated.exitCode}")
+	if err != nil {
+		return -1, fmt.Errorf("failed to read job exit code: %w", err)
+	}
+	exitCode, err := strconv.Atoi(strings.TrimSpace(code.String()))
+	if err != nil {
+		return -1, fmt.Errorf("job %s has no exit code: %q", name, code.String())
+	}
+	return exitCode, nil
+}
+
+// wait polls the Job until it has succeeded or failed
+func (r *KubernetesRunner) wait(ctx context.Context, name string) error {
+	poll := r.Poll
+	if poll <= 0 {
+		poll = 2 * time.Second
+	}
+
+	for {
+		var status bytes.Buffer
+		if err := r.kubectl(ctx, nil, &status, "get", "job", name, "-o", "jsonpath={.status.succeeded},{.status.failed}"); err != nil {
+			return fmt.Errorf("failed to check job: %w", err)
+		}
+		succeeded, failed, _ := strings.Cut(strings.TrimSpace(status.String()), ",")
+		if succeeded != "" && succeeded != "0" || failed != "" && failed != "0" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// job returns the manifest of a Job running command with every staged
+// ConfigMap mounted
+func (r *KubernetesRunner) job(name, command string) map[string]any {
+	r.mu.Lock()
+	var configMaps []string
+	for cm := range r.staged {
+		configMaps = append(configMaps, cm)
+	}
+	r.mu.Unlock()
+	sort.Strings(configMaps)
+
+	var volumes, mounts []map[string]any
+	for _, cm := range configMaps {
+		volumes = append(volumes, map[string]any{"name": cm, "configMap": map[string]any{"name": cm}})
+		mounts = append(mounts, map[string]any{"name": cm, "mountPath": path.Join(candidateMountDir, cm), "readOnly": true})
+	}
+
+	shell := r.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	metadata := map[string]any{"name": name, "labels": map[string]any{"app.kubernetes.io/managed-by": "bsct"}}
+	if r.Namespace != "" {
+		metadata["namespace"] = r.Namespace
+	}
+
+	return map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   metadata,
+		"spec": map[string]any{
+			"backoffLimit": 0,
+			"template": map[string]any{
+				"spec": map[string]any{
+					"restartPolicy": "Never",
+					"volumes":       volumes,
+					"containers": []map[string]any{{
+						"name":         "probe",
+						"image":        r.Image,
+						"command":      []string{shell, "-c", command},
+						"volumeMounts": mounts,
+					}},
+				},
+			},
+		},
+	}
+}
+
+// Stage uploads localPath as a ConfigMap that Jobs started from now on mount
+func (r *KubernetesRunner) Stage(ctx context.Context, localPath string) (string, error) {
+	cm, err := randomName("bsct-candidate-")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Base(localPath)
+	if err := r.kubectl(ctx, nil, nil, "create", "configmap", cm, "--from-file="+file+"="+localPath); err != nil {
+		return "", fmt.Errorf("failed to create configmap: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.staged == nil {
+		r.staged = make(map[string]string)
+	}
+	r.staged[cm] = file
+	return path.Join(candidateMountDir, cm, file), nil
+}
+
+// Unstage deletes the ConfigMap behind a staged file
+func (r *KubernetesRunner) Unstage(ctx context.Context, staged string) error {
+	cm := path.Base(path.Dir(staged))
+
+	r.mu.Lock()
+	delete(r.staged, cm)
+	r.mu.Unlock()
+	return r.kubectl(ctx, nil, nil, "delete", "configmap", cm, "--ignore-not-found")
+}
+
+// kubectl runs kubectl with the configured options
+func (r *KubernetesRunner) kubectl(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	all := append([]string{}, r.Args...)
+	if r.Namespace != "" {
+		all = append(all, "--namespace", r.Namespace)
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", append(all, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// randomName returns prefix followed by random hex, valid as a Kubernetes name
+func randomName(prefix string) (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,68 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubectl puts a kubectl stand-in on PATH whose Jobs finish at once with
+// the given exit code, logging every invocation to the returned path
+func fakeKubectl(t *testing.T, exitCode string) string {
+	t.Helper()
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls.log")
+
+	script := `#!/bin/sh
+echo "kubectl $*" >> ` + log + `
+case "$*" in
+  *apply*) cat >> ` + log + ` ;;
+  *"get job"*) echo "1," ;;
+  *"get pods"*) echo "` + exitCode + `" ;;
+  *logs*) echo "probe output" ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestKubernetesRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl is a shell script")
+	}
+
+	log := fakeKubectl(t, "3")
+	runner := &KubernetesRunner{Image: "busybox", Namespace: "ci", Args: []string{"--context=test"}, Poll: time.Millisecond}
+
+	local := filepath.Join(t.TempDir(), "bsct-1.txt")
+	require.NoError(t, os.WriteFile(local, []byte("a\n"), 0644))
+	staged, err := runner.Stage(context.Background(), local)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/bsct/bsct-candidate-[0-9a-f]{8}/bsct-1.txt$`, staged)
+
+	var out bytes.Buffer
+	code, err := runner.Run(context.Background(), "./check "+staged, &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "probe output\n", out.String())
+
+	require.NoError(t, runner.Unstage(context.Background(), staged))
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(calls), "kubectl --context=test --namespace ci create configmap bsct-candidate-")
+	assert.Contains(t, string(calls), `"command":["sh","-c","./check `+staged+`"]`)
+	assert.Contains(t, string(calls), `"mountPath":"`+filepath.Dir(staged)+`"`)
+	assert.Contains(t, string(calls), "delete job bsct-job-")
+	assert.Contains(t, string(calls), "delete configmap bsct-candidate-")
+}
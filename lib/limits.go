@@ -0,0 +1,73 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Limits caps the resources of every command run by a LimitedRunner, so a
+// runaway test can't take down the machine running the bisection. Zero
+// fields set no limit.
+type Limits struct {
+	CPU    time.Duration // CPU time, rounded up to whole seconds
+	Memory int64         // Bytes of virtual memory
+	Nice   int           // Scheduling priority adjustment, e.g. 10 to yield to other work
+}
+
+// LimitedRunner wraps inner, a ShellRunner if nil, so the commands it runs are
+// held to l with ulimit and nice. Commands must run in a POSIX shell, so it
+// doesn't work with cmd.exe on Windows. A command whose limits can't be set
+// exits with 126 instead of running unlimited.
+func LimitedRunner(inner Runner, l Limits) Runner {
+	if inner == nil {
+		inner = ShellRunner{}
+	}
+	r := limitedRunner{inner: inner, limits: l}
+	if stager, ok := inner.(Stager); ok {
+		return limitedStager{r, stager}
+	}
+	return r
+}
+
+type limitedRunner struct {
+	inner  Runner
+	limits Limits
+}
+
+// Run runs command under the limits
+func (r limitedRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	return r.inner.Run(ctx, r.limits.wrap(command), stdout, stderr)
+}
+
+// limitedStager keeps staging working for runners that execute elsewhere
+type limitedStager struct {
+	limitedRunner
+	Stager
+}
+
+// wrap returns command prefixed with the shell commands that apply l
+func (l Limits) wrap(command string) string {
+	var ulimits []string
+	if l.CPU > 0 {
+		secs := (l.CPU + time.Second - 1) / time.Second
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -t %d", secs))
+	}
+	if l.Memory > 0 {
+		kib := (l.Memory + 1023) / 1024
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -v %d", kib))
+	}
+
+	var b strings.Builder
+	if len(ulimits) > 0 {
+		fmt.Fprintf(&b, "{ %s; } || exit 126; ", strings.Join(ulimits, " && "))
+	}
+	if l.Nice != 0 {
+		fmt.Fprintf(&b, "exec nice -n %d sh -c %s", l.Nice, shellQuote(command))
+	} else {
+		b.WriteString(command)
+	}
+	return b.String()
+}
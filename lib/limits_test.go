@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ulimit and nice need a POSIX shell")
+	}
+
+	var out bytes.Buffer
+	r := LimitedRunner(nil, Limits{CPU: 1500 * time.Millisecond, Nice: 5})
+	code, err := r.Run(context.Background(), "ulimit -t; nice", &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "2\n5\n", out.String())
+}
+
+func TestLimits_Wrap(t *testing.T) {
+	l := Limits{CPU: time.Minute, Memory: 1 << 30, Nice: 10}
+	assert.Equal(t, `{ ulimit -t 60 && ulimit -v 1048576; } || exit 126; exec nice -n 10 sh -c 'make test'`, l.wrap("make test"))
+	assert.Equal(t, "make test", Limits{}.wrap("make test"))
+}
+
+func TestLimitedRunner_CPU(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ulimit needs a POSIX shell")
+	}
+
+	r := LimitedRunner(nil, Limits{CPU: time.Second})
+	code, err := r.Run(context.Background(), "while :; do :; done", nil, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, code)
+}
+
+// fakeStager stages files under a fixed remote directory
+type fakeStager struct{ fakeRunner }
+
+func (fakeStager) Stage(ctx context.Context, localPath string) (string, error) {
+	return "/remote/" + filepath.Base(localPath), nil
+}
+
+func (fakeStager) Unstage(ctx context.Context, path string) error { return nil }
+
+func TestLimitedRunner_KeepsStager(t *testing.T) {
+	r := LimitedRunner(&fakeStager{}, Limits{Nice: 1})
+	stager, ok := r.(Stager)
+	require.True(t, ok)
+	path, err := stager.Stage(context.Background(), "/tmp/bsct-1.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "/remote/bsct-1.txt", path)
+}
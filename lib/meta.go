@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// fileMeta is what a file carries besides its content that the program
+// reading it may care about: its permissions, owner and extended attributes,
+// which include SELinux labels on Linux
+type fileMeta struct {
+	mode     os.FileMode
+	uid, gid int               // -1 where owners aren't supported
+	xattrs   map[string][]byte // Nil where extended attributes aren't supported
+}
+
+// statMeta reads the metadata of the file at path
+func statMeta(path string) (fileMeta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileMeta{}, err
+	}
+	m := fileMeta{mode: info.Mode(), uid: -1, gid: -1}
+	if uid, gid, ok := fileOwner(info); ok {
+		m.uid, m.gid = uid, gid
+	}
+	if m.xattrs, err = listXattrs(path); err != nil {
+		return fileMeta{}, err
+	}
+	return m, nil
+}
+
+// apply gives the file at path the metadata in m where it differs. Changing
+// the owner or a security label can take privileges bsct doesn't have, so
+// it tries everything and joins the errors.
+func (m fileMeta) apply(path string) error {
+	current, err := statMeta(path)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if current.mode.Perm() != m.mode.Perm() {
+		if err := os.Chmod(path, m.mode.Perm()); err != nil {
+			errs = append(errs, fmt.Errorf("mode: %w", err))
+		}
+	}
+	if m.uid >= 0 && (current.uid != m.uid || current.gid != m.gid) {
+		if err := os.Lchown(path, m.uid, m.gid); err != nil {
+			errs = append(errs, fmt.Errorf("owner: %w", err))
+		}
+	}
+	for name, value := range m.xattrs {
+		if v, ok := current.xattrs[name]; ok && bytes.Equal(v, value) {
+			continue
+		}
+		if err := setXattr(path, name, value); err != nil {
+			errs = append(errs, fmt.Errorf("attribute %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the metrics reported to Metrics
+const (
+	MetricSteps         = "bsct_steps_total"            // Probes evaluated
+	MetricProbeDuration = "bsct_probe_duration_seconds" // Time to evaluate one probe
+	MetricTestDuration  = "bsct_test_duration_seconds"  // Time the oracle took for one probe, hooks left out
+	MetricCacheHits     = "bsct_cache_hits_total"       // CachedOracle lookups that found a verdict
+	MetricCacheMisses   = "bsct_cache_misses_total"     // CachedOracle lookups that didn't
+	MetricRetries       = "bsct_retries_total"          // Extra attempts made by RetryOracle
+	MetricSessions      = "bsct_sessions_in_progress"   // Server sessions not yet finished or deleted
+	MetricDuplicates    = "bsct_duplicate_probes_total" // Probes given the verdict of an earlier one with the same candidate
+	MetricTempBytes     = "bsct_temp_bytes"             // Bytes currently held by candidate files, if limited
+)
+
+// Metrics receives counters and durations from a bisection, e.g. to export
+// them through expvar or a Prometheus registry. Implementations must be safe
+// for concurrent use.
+type Metrics interface {
+	// Add increments the counter name by delta
+	Add(name string, delta int64)
+	// Observe records one duration for the histogram name
+	Observe(name string, d time.Duration)
+}
+
+type metricsKey struct{}
+
+// ContextWithMetrics returns a copy of ctx carrying m. Bisectors configured
+// WithMetrics do this for the context handed to their oracle, so decorators
+// like CachedOracle and RetryOracle report to the same Metrics.
+func ContextWithMetrics(ctx context.Context, m Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// metricsFrom returns the Metrics carried by ctx, or one that drops everything
+func metricsFrom(ctx context.Context) Metrics {
+	if m, ok := ctx.Value(metricsKey{}).(Metrics); ok && m != nil {
+		return m
+	}
+	return noMetrics{}
+}
+
+// noMetrics drops everything
+type noMetrics struct{}
+
+func (noMetrics) Add(string, int64)             {}
+func (noMetrics) Observe(string, time.Duration) {}
+
+// ExpvarMetrics publishes metrics into an expvar.Map. Counters become
+// expvar.Int values; durations become a "_count" Int and a "_sum" Float of
+// seconds.
+type ExpvarMetrics struct {
+	Map *expvar.Map
+}
+
+// Add increments the counter name by delta
+func (m ExpvarMetrics) Add(name string, delta int64) {
+	m.Map.Add(name, delta)
+}
+
+// Observe adds d to the count and sum for name
+func (m ExpvarMetrics) Observe(name string, d time.Duration) {
+	m.Map.Add(name+"_count", 1)
+	m.Map.AddFloat(name+"_sum", d.Seconds())
+}
+
+// PrometheusMetrics keeps metrics in memory and serves them in the Prometheus
+// text exposition format, e.g. on /metrics. Names ending in _total are
+// counters and other names passed to Add are gauges; durations become
+// histograms of seconds with the buckets in DurationBuckets. The zero value is
+// ready to use.
+type PrometheusMetrics struct {
+	mu        sync.Mutex
+	values    map[string]int64
+	durations map[string]*durationHistogram
+}
+
+// DurationBuckets are the upper bounds, in seconds, of the histogram buckets
+// PrometheusMetrics sorts durations into. They span quick scripted checks
+// through test suites that take an hour.
+var DurationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600}
+
+type durationHistogram struct {
+	buckets []int64 // Durations within each of DurationBuckets, not cumulative
+	count   int64
+	sum     float64
+}
+
+// Add increments the counter or gauge name by delta
+func (m *PrometheusMetrics) Add(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values == nil {
+		m.values = make(map[string]int64)
+	}
+	m.values[name] += delta
+}
+
+// Observe adds d to the histogram for name
+func (m *PrometheusMetrics) Observe(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.durations == nil {
+		m.durations = make(map[string]*durationHistogram)
+	}
+	h, ok := m.durations[name]
+	if !ok {
+		h = &durationHistogram{buckets: make([]int64, len(DurationBuckets))}
+		m.durations[name] = h
+	}
+	secs := d.Seconds()
+	if i := sort.SearchFloat64s(DurationBuckets, secs); i < len(h.buckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += secs
+}
+
+// ServeHTTP writes every metric in the text exposition format. The metrics
+// are copied first so a slow client doesn't hold up the bisection.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	values := make(map[string]int64, len(m.values))
+	for name, v := range m.values {
+		values[name] = v
+	}
+	durations := make(map[string]durationHistogram, len(m.durations))
+	for name, h := range m.durations {
+		durations[name] = durationHistogram{buckets: append([]int64(nil), h.buckets...), count: h.count, sum: h.sum}
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, name := range sortedKeys(values) {
+		kind := "gauge"
+		if strings.HasSuffix(name, "_total") {
+			kind = "counter"
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n%s %d\n", name, kind, name, values[name])
+	}
+	for _, name := range sortedKeys(durations) {
+		h := durations[name]
+		fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+		var cumulative int64
+		for i, le := range DurationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, le, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", name, h.count, name, h.sum, name, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_WithMetrics(t *testing.T) {
+	lines := make([]string, 16)
+	for i := range lines {
+		lines[i] = "good"
+		if i >= 9 {
+			lines[i] = "bad"
+		}
+	}
+
+	flaky := &scriptedOracle{results: []any{assert.AnError, Good}}
+	judge := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if _, err := flaky.Evaluate(ctx, c); err != nil {
+			return Bad, err
+		}
+		if c.Line == "bad" {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	m := ExpvarMetrics{Map: new(expvar.Map)}
+	oracle := CachedOracle(RetryOracle(judge, 2, RetryStrategy{}), NewMemoryCache())
+	bisector, err := New(lines, WithOracle(oracle), WithMetrics(m), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+
+	steps := int64(result.StepsTaken)
+	assert.Equal(t, steps, m.Map.Get(MetricSteps).(*expvar.Int).Value())
+	assert.Equal(t, steps, m.Map.Get(MetricProbeDuration+"_count").(*expvar.Int).Value())
+	assert.NotNil(t, m.Map.Get(MetricProbeDuration+"_sum"))
+	assert.Equal(t, steps, m.Map.Get(MetricTestDuration+"_count").(*expvar.Int).Value())
+	assert.Equal(t, steps, m.Map.Get(MetricCacheMisses).(*expvar.Int).Value())
+	assert.Equal(t, steps, m.Map.Get(MetricRetries).(*expvar.Int).Value())
+}
+
+func TestCachedOracle_HitMetric(t *testing.T) {
+	m := ExpvarMetrics{Map: new(expvar.Map)}
+	ctx := ContextWithMetrics(context.Background(), m)
+	oracle := CachedOracle(&countingOracle{}, NewMemoryCache())
+	c := Candidate{Index: 0, Line: "good", src: Lines{"good"}}
+
+	for range 3 {
+		_, err := oracle.Evaluate(ctx, c)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), m.Map.Get(MetricCacheHits).(*expvar.Int).Value())
+	assert.Equal(t, int64(1), m.Map.Get(MetricCacheMisses).(*expvar.Int).Value())
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	var m PrometheusMetrics
+	m.Add(MetricSteps, 2)
+	m.Add(MetricSessions, 1)
+	m.Observe(MetricProbeDuration, 1500*time.Millisecond)
+	m.Observe(MetricProbeDuration, 500*time.Millisecond)
+	m.Observe(MetricProbeDuration, 2*time.Hour)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, `# TYPE bsct_sessions_in_progress gauge
+bsct_sessions_in_progress 1
+# TYPE bsct_steps_total counter
+bsct_steps_total 2
+# TYPE bsct_probe_duration_seconds histogram
+bsct_probe_duration_seconds_bucket{le="0.1"} 0
+bsct_probe_duration_seconds_bucket{le="0.5"} 1
+bsct_probe_duration_seconds_bucket{le="1"} 1
+bsct_probe_duration_seconds_bucket{le="5"} 2
+bsct_probe_duration_seconds_bucket{le="10"} 2
+bsct_probe_duration_seconds_bucket{le="30"} 2
+bsct_probe_duration_seconds_bucket{le="60"} 2
+bsct_probe_duration_seconds_bucket{le="300"} 2
+bsct_probe_duration_seconds_bucket{le="900"} 2
+bsct_probe_duration_seconds_bucket{le="1800"} 2
+bsct_probe_duration_seconds_bucket{le="3600"} 2
+bsct_probe_duration_seconds_bucket{le="+Inf"} 3
+bsct_probe_duration_seconds_sum 7202
+bsct_probe_duration_seconds_count 3
+`, rec.Body.String())
+}
@@ -0,0 +1,232 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Property is one of the tests a MultiBisector finds the first bad line for
+type Property struct {
+	Name   string // Names the property in output and results
+	Oracle Oracle
+}
+
+// PropertyResult is the first bad line found for one Property
+type PropertyResult struct {
+	Name string
+	*Result
+}
+
+// MultiResult holds the results of a MultiBisector, in the order its
+// properties were given
+type MultiResult struct {
+	Properties []PropertyResult
+	Probes     int // Candidates written and set up, each tested for one or more properties
+}
+
+// MultiBisector finds the first bad line for each of several properties in
+// one session. Every probe is written and set up by the before command once
+// and then tested for each property whose range still contains it, so an
+// expensive setup is shared rather than repeated in separate sessions. New and
+// NewFromSource return one when given WithProperties.
+type MultiBisector struct {
+	engine   *AutomaticBisector // Writes candidates and runs the hooks
+	props    []Property
+	searches []*search
+}
+
+func newMultiBisector(src Source, cfg config) *MultiBisector {
+	m := &MultiBisector{engine: newAutomaticBisector(src, cfg), props: cfg.properties}
+	for range cfg.properties {
+		s := cfg.search(src)
+		m.searches = append(m.searches, &s)
+	}
+	return m
+}
+
+// Bisect finds the first bad line of every property and returns the earliest
+// of them. BisectAll returns them all.
+func (m *MultiBisector) Bisect() (*Result, error) {
+	return m.BisectContext(context.Background())
+}
+
+// BisectContext is like Bisect but stops when ctx is done, returning the
+// progress of the property with the earliest bad line so far
+func (m *MultiBisector) BisectContext(ctx context.Context) (*Result, error) {
+	all, err := m.BisectAll(ctx)
+	if all == nil {
+		return nil, err
+	}
+	first := all.Properties[0].Result
+	for _, p := range all.Properties[1:] {
+		if p.BadLineNumber < first.BadLineNumber {
+			first = p.Result
+		}
+	}
+	return first, err
+}
+
+// BisectAll finds the first bad line of every property. When ctx is done, the
+// progress of each is returned with an error wrapping ErrInterrupted.
+func (m *MultiBisector) BisectAll(ctx context.Context) (_ *MultiResult, err error) {
+	b := m.engine
+	fmt.Fprintf(b.out, "Starting automatic bisection of %d properties between lines %d and %d (%d lines total)\n",
+		len(m.props), b.goodIdx+1, b.badIdx+1, b.src.Len())
+	fmt.Fprintln(b.out)
+	restore, err := b.useTarget()
+	if err != nil {
+		return nil, err
+	}
+	defer restore(&err)
+	file, err := newCandidateFile(b.budget)
+	if err != nil {
+		return nil, err
+	}
+	defer file.remove()
+
+	ctx = b.withMetrics(ctx)
+	for _, s := range m.searches {
+		s.started = time.Now()
+	}
+	probes := 0
+	for {
+		idx, ok := m.next()
+		if !ok {
+			break
+		}
+		if ctx.Err() != nil {
+			return m.partial(probes), interrupted(ctx)
+		}
+
+		probes++
+		c, err := b.candidate(idx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(b.out, "Probe %d: Testing line %d of %d\n", probes, idx+1, b.src.Len())
+		fmt.Fprintf(b.out, "Line content: %s\n", b.display(c.Line))
+		st, err := b.prepare(ctx, c, file)
+		if err != nil {
+			return nil, err
+		}
+		err = m.testAll(ctx, st)
+		b.finish(ctx, st)
+		if err != nil {
+			if ctx.Err() != nil {
+				return m.partial(probes), interrupted(ctx)
+			}
+			return nil, err
+		}
+		fmt.Fprintln(b.out)
+	}
+
+	result := &MultiResult{Probes: probes}
+	for i, s := range m.searches {
+		r, err := s.result()
+		if err != nil {
+			return nil, err
+		}
+		result.Properties = append(result.Properties, PropertyResult{Name: m.props[i].Name, Result: r})
+	}
+	return result, nil
+}
+
+// partial returns the progress of every property after an interruption, or
+// nil if it can't be read
+func (m *MultiBisector) partial(probes int) *MultiResult {
+	result := &MultiResult{Probes: probes}
+	for i, s := range m.searches {
+		r := s.partial()
+		if r == nil {
+			return nil
+		}
+		result.Properties = append(result.Properties, PropertyResult{Name: m.props[i].Name, Result: r})
+	}
+	return result
+}
+
+// next returns the midpoint of the widest range still being searched, or
+// false once every property's first bad line is known
+func (m *MultiBisector) next() (int, bool) {
+	best, width := 0, 0
+	for _, s := range m.searches {
+		idx, ok := s.next()
+		if ok && s.badIdx-s.goodIdx > width {
+			best, width = idx, s.badIdx-s.goodIdx
+		}
+	}
+	return best, width > 0
+}
+
+// testAll tests st for every property whose range contains it
+func (m *MultiBisector) testAll(ctx context.Context, st *staged) error {
+	b, idx := m.engine, st.c.Index
+	for i, p := range m.props {
+		s := m.searches[i]
+		if idx <= s.goodIdx || idx >= s.badIdx || s.skipped[idx] {
+			continue
+		}
+		s.steps++
+		probe := s.newProbe(st.c, s.steps)
+		s.notifyStep(probe)
+		start := time.Now()
+		run, err := b.judge(ctx, p.Oracle, st, fmt.Sprintf("%s %d", p.Name, s.steps))
+		s.observeProbe(start)
+		if err != nil {
+			s.steps-- // Never finished
+			return fmt.Errorf("property %s: %w", p.Name, err)
+		}
+
+		v := run.step.Verdict
+		s.record(idx, v)
+		if v == Skip {
+			fmt.Fprintf(b.out, "%s: skipped. Searching lines %d-%d\n", p.Name, s.goodIdx+1, s.badIdx+1)
+		} else {
+			fmt.Fprintf(b.out, "%s: %s. Searching lines %d-%d\n", p.Name, s.term(v), s.goodIdx+1, s.badIdx+1)
+		}
+		s.notifyVerdict(probe, v)
+		s.notifyRangeNarrowed()
+	}
+	return nil
+}
+
+// Save writes the progress made so far for every property, keyed by name, so
+// the session can be resumed with Load
+func (m *MultiBisector) Save(w io.Writer) error {
+	states := make(map[string]json.RawMessage, len(m.props))
+	for i, p := range m.props {
+		var buf bytes.Buffer
+		if err := m.searches[i].Save(&buf); err != nil {
+			return err
+		}
+		states[p.Name] = buf.Bytes()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"properties": states})
+}
+
+// Load restores progress written by Save. Every property must have been
+// saved under its name.
+func (m *MultiBisector) Load(r io.Reader) error {
+	var saved struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return fmt.Errorf("failed to read saved state: %w", err)
+	}
+	for i, p := range m.props {
+		state, ok := saved.Properties[p.Name]
+		if !ok {
+			return fmt.Errorf("%w: no progress saved for property %s", ErrStateMismatch, p.Name)
+		}
+		if err := m.searches[i].Load(bytes.NewReader(state)); err != nil {
+			return fmt.Errorf("property %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// firstBad is an oracle for prefixes of 0-indexed lines that turn bad at
+// line bad, counting its calls
+func firstBad(bad int, calls *int) Oracle {
+	return OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		*calls++
+		if c.Index >= bad {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+}
+
+func TestMultiBisector(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	var buildCalls, lintCalls int
+	var before bytes.Buffer
+	bisector, err := New(lines,
+		WithProperties(
+			Property{Name: "build", Oracle: firstBad(70, &buildCalls)},
+			Property{Name: "lint", Oracle: firstBad(20, &lintCalls)},
+		),
+		WithBeforeCommand("echo setup"),
+		WithOutput(&before),
+	)
+	require.NoError(t, err)
+	multi, ok := bisector.(*MultiBisector)
+	require.True(t, ok)
+
+	result, err := multi.BisectAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.Properties, 2)
+	assert.Equal(t, "build", result.Properties[0].Name)
+	assert.Equal(t, 71, result.Properties[0].BadLineNumber)
+	assert.Equal(t, "lint", result.Properties[1].Name)
+	assert.Equal(t, 21, result.Properties[1].BadLineNumber)
+
+	// Shared probes set up fewer candidates than two sessions would test
+	assert.Equal(t, buildCalls, result.Properties[0].StepsTaken)
+	assert.Equal(t, lintCalls, result.Properties[1].StepsTaken)
+	assert.Less(t, result.Probes, buildCalls+lintCalls)
+	assert.Equal(t, result.Probes, bytes.Count(before.Bytes(), []byte("Running before command")))
+}
+
+func TestMultiBisector_Earliest(t *testing.T) {
+	var calls int
+	bisector, err := New([]string{"a", "b", "c", "d", "e", "f"},
+		WithProperties(
+			Property{Name: "late", Oracle: firstBad(4, &calls)},
+			Property{Name: "early", Oracle: firstBad(2, &calls)},
+		),
+		WithOutput(io.Discard),
+	)
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+}
+
+func TestMultiBisector_SaveLoad(t *testing.T) {
+	var calls int
+	props := []Property{
+		{Name: "a", Oracle: firstBad(5, &calls)},
+		{Name: "b", Oracle: firstBad(2, &calls)},
+	}
+	lines := []string{"0", "1", "2", "3", "4", "5", "6", "7"}
+	bisector, err := New(lines, WithProperties(props...), WithOutput(io.Discard))
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	var state bytes.Buffer
+	require.NoError(t, bisector.Save(&state))
+
+	// A loaded session has nothing left to test
+	calls = 0
+	resumed, err := New(lines, WithProperties(props...), WithOutput(io.Discard))
+	require.NoError(t, err)
+	require.NoError(t, resumed.Load(bytes.NewReader(state.Bytes())))
+	result, err := resumed.(*MultiBisector).BisectAll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, calls)
+	assert.Equal(t, 6, result.Properties[0].BadLineNumber)
+	assert.Equal(t, 3, result.Properties[1].BadLineNumber)
+
+	other, err := New(lines, WithProperties(Property{Name: "c", Oracle: firstBad(1, &calls)}), WithOutput(io.Discard))
+	require.NoError(t, err)
+	assert.ErrorIs(t, other.Load(bytes.NewReader(state.Bytes())), ErrStateMismatch)
+}
+
+func TestWithProperties_Invalid(t *testing.T) {
+	var calls int
+	o := firstBad(1, &calls)
+	_, err := New([]string{"a", "b"}, WithProperties(Property{Oracle: o}))
+	assert.Error(t, err)
+	_, err = New([]string{"a", "b"}, WithProperties(Property{Name: "x", Oracle: o}, Property{Name: "x", Oracle: o}))
+	assert.Error(t, err)
+	_, err = New([]string{"a", "b"}, WithProperties(Property{Name: "x", Oracle: o}), WithConcurrency(2))
+	assert.Error(t, err)
+}
@@ -0,0 +1,587 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// CandidateMode selects what the candidate file handed to commands contains
+type CandidateMode int
+
+const (
+	// CandidatePrefix writes lines 1 through the line being tested (the default)
+	CandidatePrefix CandidateMode = iota
+	// CandidateLine writes only the line being tested
+	CandidateLine
+)
+
+// Action says what a bisection does with a probe whose test went wrong in a
+// way that may have nothing to do with the candidate
+type Action int
+
+const (
+	// ActionAbort stops the bisection with the error
+	ActionAbort Action = iota
+	// ActionSkip marks the line skipped and probes a neighbouring one instead
+	ActionSkip
+	// ActionBad judges the line bad
+	ActionBad
+)
+
+// String returns the lowercase name of the action
+func (a Action) String() string {
+	switch a {
+	case ActionAbort:
+		return "abort"
+	case ActionSkip:
+		return "skip"
+	case ActionBad:
+		return "bad"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// UnmarshalText decodes an action name: abort, skip or bad
+func (a *Action) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "abort":
+		*a = ActionAbort
+	case "skip":
+		*a = ActionSkip
+	case "bad":
+		*a = ActionBad
+	default:
+		return fmt.Errorf("invalid action %q, must be abort, skip or bad", text)
+	}
+	return nil
+}
+
+// Option configures a Bisector created by New
+type Option func(*config)
+
+// config collects everything an Option can set
+type config struct {
+	goodIdx       int
+	badIdx        int
+	boundaries    bool // Whether WithBoundaries set goodIdx and badIdx
+	firstGood     bool
+	termGood      string
+	termBad       string
+	testCommand   string
+	beforeCommand string
+	afterCommand  string
+	input         io.Reader
+	out           io.Writer
+	errOut        io.Writer
+	useTTY        bool
+	candidateMode CandidateMode
+	oracle        Oracle
+	properties    []Property
+	observers     []Observer
+	concurrency   int
+	prewarm       bool
+	speculate     bool
+	maxTempBytes  int64
+	checkTest     bool
+	streamOutput  io.Writer
+	onExecError   Action
+	onCrash       Action
+	rawDisplay    bool
+	noColor       bool
+	displayWidth  int
+	contextLines  int
+	target        string
+	sync          bool
+	runner        Runner
+	shell         string
+	logger        *slog.Logger
+	metrics       Metrics
+}
+
+// WithBoundaries sets the 0-indexed known good and known bad lines. By default
+// the first line is assumed good and the last line bad.
+func WithBoundaries(goodIdx, badIdx int) Option {
+	return func(c *config) {
+		c.goodIdx = goodIdx
+		c.badIdx = badIdx
+		c.boundaries = true
+	}
+}
+
+// WithFindFirstGood searches for the first good line after bad ones instead of
+// the first bad line, e.g. the log line where a service recovered. Verdicts
+// keep their meaning; the known bad line must come before the known good one,
+// which by default are the first and the last line. The Result holds the line
+// found in FirstGoodLine.
+func WithFindFirstGood() Option {
+	return func(c *config) { c.firstGood = true }
+}
+
+// WithTerms names good and bad lines good and bad instead, like git bisect
+// terms, e.g. "old" and "new" or "fast" and "slow" when bisecting for a change
+// rather than a failure. Prompts accept the terms and, when they start with
+// different letters, their first letters, and output uses them throughout.
+func WithTerms(good, bad string) Option {
+	return func(c *config) {
+		c.termGood, c.termBad = strings.ToLower(good), strings.ToLower(bad)
+	}
+}
+
+// WithTestCommand runs command for every step instead of prompting the user
+// (exit 0 = good, non-zero = bad). Supports {file}, {} and {line} placeholders.
+func WithTestCommand(command string) Option {
+	return func(c *config) { c.testCommand = command }
+}
+
+// WithBeforeCommand runs command before each test
+func WithBeforeCommand(command string) Option {
+	return func(c *config) { c.beforeCommand = command }
+}
+
+// WithAfterCommand runs command after each test
+func WithAfterCommand(command string) Option {
+	return func(c *config) { c.afterCommand = command }
+}
+
+// WithOracle bisects automatically, asking o for every verdict instead of
+// running a test command. Before and after commands still run around o.
+func WithOracle(o Oracle) Option {
+	return func(c *config) { c.oracle = o }
+}
+
+// WithProperties bisects for several properties at once instead of a single
+// test command or oracle, finding the first bad line of each. See
+// MultiBisector.
+func WithProperties(props ...Property) Option {
+	return func(c *config) { c.properties = append(c.properties, props...) }
+}
+
+// WithObserver registers o for progress callbacks. It may be given more than
+// once; observers are called in the order they were added.
+func WithObserver(o Observer) Option {
+	return func(c *config) { c.observers = append(c.observers, o) }
+}
+
+// WithConcurrency evaluates up to n probes at once in automatic mode using a
+// ParallelBisector. See Oracle for the concurrency requirements.
+func WithConcurrency(n int) Option {
+	return func(c *config) { c.concurrency = n }
+}
+
+// WithPrewarm runs the before command for the likeliest next probe while the
+// current one is tested, so an expensive setup like restoring a database
+// snapshot overlaps with the test instead of following it. The guess is that
+// the verdict repeats the last one; a wrong guess is canceled and cleaned up
+// with the after command. Hooks must tolerate running alongside a test of
+// another candidate. It has no effect on a ParallelBisector.
+func WithPrewarm() Option {
+	return func(c *config) { c.prewarm = true }
+}
+
+// WithSpeculation tests both lines that may be probed after the midpoint
+// while it is tested, using a SpeculativeBisector. See Oracle for the
+// concurrency requirements.
+func WithSpeculation() Option {
+	return func(c *config) { c.speculate = true }
+}
+
+// WithMaxTempBytes caps the bytes candidate files may take up on disk at once.
+// A probe whose candidate would go over fails with ErrTempLimit before the
+// disk fills up mid-write.
+func WithMaxTempBytes(n int64) Option {
+	return func(c *config) { c.maxTempBytes = n }
+}
+
+// WithTestCheck tests the known good and bad lines before bisecting and warns
+// if they get the same verdict, which catches a test command that doesn't
+// depend on the candidate before a whole bisection is spent on it
+func WithTestCheck() Option {
+	return func(c *config) { c.checkTest = true }
+}
+
+// WithOnExecError sets what happens when a test command can't be run at all,
+// which a CommandOracle or OutputOracle reports as a TestCommandError: a
+// missing shell, or exit code 127 or 126 for a command that wasn't found or
+// isn't executable. By default the bisection aborts, since such a line tells
+// nothing about the problem being bisected.
+func WithOnExecError(a Action) Option {
+	return func(c *config) { c.onExecError = a }
+}
+
+// WithOnCrash sets what happens when a CommandOracle or OutputOracle test
+// command crashes instead of failing: it was killed by a signal such as
+// SIGSEGV or, as the OOM killer does, SIGKILL, or a shell reported one as exit
+// code 128+N. A crashing harness is often a different problem from the one
+// being bisected. By default a crash counts as bad like any other failure;
+// ActionAbort stops with a TestCommandError wrapping ErrTestCrashed.
+func WithOnCrash(a Action) Option {
+	return func(c *config) { c.onCrash = a }
+}
+
+// WithRawDisplay prints lines in prompts and progress output as they are. By
+// default control characters are shown escaped (see EscapeControl), so ANSI
+// codes in a log line can't garble the display. Candidates always hold the
+// raw lines.
+func WithRawDisplay() Option {
+	return func(c *config) { c.rawDisplay = true }
+}
+
+// WithoutColor prints the prompts and lines of an InteractiveBisector without
+// ANSI colors, e.g. when its output isn't a terminal
+func WithoutColor() Option {
+	return func(c *config) { c.noColor = true }
+}
+
+// WithDisplayWidth wraps the lines an InteractiveBisector shows to fit a
+// terminal n columns wide, continuing under the line number so wide and
+// combining characters never push text into the gutter. By default lines
+// aren't wrapped and the terminal wraps them itself.
+func WithDisplayWidth(n int) Option {
+	return func(c *config) { c.displayWidth = n }
+}
+
+// WithContextLines shows n lines before and after the line an
+// InteractiveBisector asks about, instead of one. Pressing + or - at the
+// prompt widens or narrows the window from there.
+func WithContextLines(n int) Option {
+	return func(c *config) { c.contextLines = n }
+}
+
+// WithTarget puts every candidate in place of the file at path before the
+// before command runs, for tests of a program that reads a fixed path, like a
+// daemon's config file, and puts the original content back once the
+// bisection ends. The file keeps its mode, owner and extended attributes. It
+// can't be combined with WithConcurrency, and WithPrewarm has no effect with
+// it since the next candidate can't be put in place during a test.
+func WithTarget(path string) Option {
+	return func(c *config) { c.target = path }
+}
+
+// WithSync flushes every candidate file and its directory to stable storage
+// before the before command runs. It helps when the test reads the candidate
+// from another process, container or NFS mount that could otherwise see a
+// stale or partial file.
+func WithSync() Option {
+	return func(c *config) { c.sync = true }
+}
+
+// WithStreamOutput relays what test commands print to w while they run, each
+// line prefixed with the step it belongs to like "[step 3] ", instead of
+// discarding it. Long tests then show their progress.
+func WithStreamOutput(w io.Writer) Option {
+	return func(c *config) { c.streamOutput = w }
+}
+
+// WithRunner runs the test, before and after commands with r instead of the
+// local shell
+func WithRunner(r Runner) Option {
+	return func(c *config) { c.runner = r }
+}
+
+// WithShell runs the test, before and after commands locally with shell
+// instead of sh, or cmd.exe on Windows. shell is a program name or path, like
+// bash, zsh, fish, pwsh or /usr/local/bin/nu, which gets each command the
+// way it expects: after -Command for pwsh and powershell, after /c for cmd,
+// and after -c for any other. Arguments after the program, as in
+// "bash -euo pipefail -c", replace those defaults. {line} is quoted for fish,
+// PowerShell and cmd.exe by their own rules and for a POSIX shell otherwise.
+// It has no effect with WithRunner.
+func WithShell(shell string) Option {
+	return func(c *config) { c.shell = shell }
+}
+
+// WithLogger emits structured events (probes, verdicts, hook runs and the
+// result) to l. The human-readable progress written to WithOutput is
+// unaffected; silence it with WithOutput(io.Discard).
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) { c.logger = l }
+}
+
+// WithMetrics reports step counts and probe durations to m, and makes m
+// available to oracle decorators such as CachedOracle and RetryOracle
+func WithMetrics(m Metrics) Option {
+	return func(c *config) { c.metrics = m }
+}
+
+// WithInput reads interactive answers from r instead of stdin
+func WithInput(r io.Reader) Option {
+	return func(c *config) { c.input = r }
+}
+
+// WithOutput writes progress, prompts and hook output to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(c *config) { c.out = w }
+}
+
+// WithErrorOutput writes warnings and hook errors to w instead of stderr
+func WithErrorOutput(w io.Writer) Option {
+	return func(c *config) { c.errOut = w }
+}
+
+// WithTTY reads interactive answers from /dev/tty, for when stdin carries the
+// lines being bisected. Falls back to stdin if /dev/tty can't be opened.
+func WithTTY() Option {
+	return func(c *config) { c.useTTY = true }
+}
+
+// WithCandidateMode selects what the candidate file contains
+func WithCandidateMode(mode CandidateMode) Option {
+	return func(c *config) { c.candidateMode = mode }
+}
+
+// output returns the configured output writer, defaulting to stdout
+func (c config) output() io.Writer {
+	if c.out == nil {
+		return os.Stdout
+	}
+	return c.out
+}
+
+// errorOutput returns the configured error writer, defaulting to stderr
+func (c config) errorOutput() io.Writer {
+	if c.errOut == nil {
+		return os.Stderr
+	}
+	return c.errOut
+}
+
+// commandRunner returns the configured Runner, defaulting to a ShellRunner
+func (c config) commandRunner() Runner {
+	if c.runner == nil {
+		return ShellRunner{Shell: c.shell}
+	}
+	return c.runner
+}
+
+// tempBudget returns the limit shared by candidate files, nil if unlimited
+func (c config) tempBudget() *tempBudget {
+	if c.maxTempBytes <= 0 {
+		return nil
+	}
+	return &tempBudget{max: c.maxTempBytes, metrics: c.metrics}
+}
+
+// search returns the initial search state for src
+func (c config) search(src Source) search {
+	goodIdx, badIdx := c.goodIdx, c.badIdx
+	if c.firstGood {
+		// The search narrows between the last line like the first one and
+		// the first line unlike it
+		goodIdx, badIdx = badIdx, goodIdx
+	}
+	return search{
+		src:          src,
+		goodIdx:      goodIdx,
+		badIdx:       badIdx,
+		firstGood:    c.firstGood,
+		termGood:     c.termGood,
+		termBad:      c.termBad,
+		mode:         c.candidateMode,
+		observers:    c.observers,
+		logger:       c.logger,
+		metrics:      c.metrics,
+		rawDisplay:   c.rawDisplay,
+		displayWidth: c.displayWidth,
+		colors:       NewPalette(!c.noColor),
+	}
+}
+
+// newConfig applies opts on top of the defaults for src and validates the
+// resulting boundaries
+func newConfig(src Source, opts []Option) (config, error) {
+	n := src.Len()
+	cfg := config{badIdx: n - 1, onCrash: ActionBad, contextLines: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.firstGood && !cfg.boundaries {
+		cfg.goodIdx, cfg.badIdx = n-1, 0
+	}
+
+	if n == 0 {
+		return cfg, ErrNoInput
+	}
+	if min(cfg.goodIdx, cfg.badIdx) < 0 || max(cfg.goodIdx, cfg.badIdx) >= n {
+		return cfg, fmt.Errorf("boundaries (%d, %d) out of range for %d lines", cfg.goodIdx, cfg.badIdx, n)
+	}
+	if err := cfg.checkTerms(); err != nil {
+		return cfg, err
+	}
+	if cfg.contextLines < 0 {
+		return cfg, fmt.Errorf("context lines can't be negative, got %d", cfg.contextLines)
+	}
+	if cfg.firstGood && cfg.badIdx >= cfg.goodIdx {
+		return cfg, fmt.Errorf("%w (good index %d, bad index %d)", ErrGoodBeforeBad, cfg.goodIdx, cfg.badIdx)
+	}
+	if !cfg.firstGood && cfg.goodIdx >= cfg.badIdx {
+		return cfg, fmt.Errorf("%w (good index %d, bad index %d)", ErrBadBeforeGood, cfg.goodIdx, cfg.badIdx)
+	}
+	names := make(map[string]bool, len(cfg.properties))
+	for _, p := range cfg.properties {
+		switch {
+		case p.Name == "":
+			return cfg, errors.New("every property needs a name")
+		case names[p.Name]:
+			return cfg, fmt.Errorf("duplicate property %q", p.Name)
+		case p.Oracle == nil:
+			return cfg, fmt.Errorf("property %q has no oracle", p.Name)
+		}
+		names[p.Name] = true
+	}
+	if len(cfg.properties) > 0 && cfg.concurrency > 1 {
+		return cfg, errors.New("properties can't be bisected with WithConcurrency")
+	}
+	if cfg.target != "" && cfg.concurrency > 1 {
+		return cfg, fmt.Errorf("a target file can't hold %d candidates at once", cfg.concurrency)
+	}
+	if cfg.speculate {
+		switch {
+		case len(cfg.properties) > 0:
+			return cfg, errors.New("properties can't be bisected with WithSpeculation")
+		case cfg.concurrency > 1:
+			return cfg, errors.New("WithSpeculation can't be combined with WithConcurrency")
+		case cfg.target != "":
+			return cfg, errors.New("a target file can't hold the candidates WithSpeculation tests at once")
+		}
+	}
+	return cfg, nil
+}
+
+// checkTerms returns an error if the WithTerms terms can't be told apart from
+// each other or from the other answers at the prompt
+func (c config) checkTerms() error {
+	if c.termGood == "" && c.termBad == "" {
+		return nil
+	}
+	for _, term := range []string{c.termGood, c.termBad} {
+		switch {
+		case term == "" || strings.ContainsFunc(term, unicode.IsSpace):
+			return fmt.Errorf("invalid term %q: must be a single word", term)
+		case term == "s" || term == "skip" || term == "u" || term == "undo":
+			return fmt.Errorf("invalid term %q: it is taken by another answer", term)
+		}
+	}
+	if c.termGood == c.termBad {
+		return fmt.Errorf("invalid terms: good and bad lines are both called %q", c.termGood)
+	}
+	return nil
+}
+
+// New creates a Bisector for lines. It bisects automatically when a test
+// command or oracle is configured and interactively otherwise.
+func New(lines []string, opts ...Option) (Bisector, error) {
+	return NewFromSource(Lines(lines), opts...)
+}
+
+// NewFromSource is like New but reads lines from src, e.g. a LineIndex over a
+// file too large to load into memory
+func NewFromSource(src Source, opts ...Option) (Bisector, error) {
+	cfg, err := newConfig(src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.properties) > 0 {
+		return newMultiBisector(src, cfg), nil
+	}
+	if cfg.testCommand != "" || cfg.oracle != nil {
+		if cfg.concurrency > 1 {
+			return newParallelBisector(src, cfg), nil
+		}
+		if cfg.speculate {
+			return newSpeculativeBisector(src, cfg), nil
+		}
+		return newAutomaticBisector(src, cfg), nil
+	}
+	return newInteractiveBisector(src, cfg), nil
+}
+
+// Options configures a Bisector in one struct, for programs that embed
+// bisection and would rather fill in fields than chain With* options. The
+// zero value of every field keeps the default. See NewWithOptions.
+type Options struct {
+	// Lines are bisected unless Source is set
+	Lines []string
+	// Source is bisected instead of Lines, e.g. a LineIndex over a large file
+	Source Source
+	// Good and Bad select the first lines containing them as the known good
+	// and known bad lines, like FindBoundaries. By default the first line is
+	// good and the last line bad.
+	Good, Bad string
+	// Test is the test command, as for WithTestCommand
+	Test string
+	// Before and After run around each test, as for WithBeforeCommand and
+	// WithAfterCommand
+	Before, After string
+	// Oracle judges candidates instead of Test, as for WithOracle
+	Oracle Oracle
+	// FindFirstGood searches for the first good line after bad ones, as for
+	// WithFindFirstGood
+	FindFirstGood bool
+	// Concurrency evaluates up to this many probes at once, as for
+	// WithConcurrency
+	Concurrency int
+	// Runner and Shell run commands, as for WithRunner and WithShell
+	Runner Runner
+	Shell  string
+	// Input, Output and ErrorOutput replace stdin, stdout and stderr
+	Input       io.Reader
+	Output      io.Writer
+	ErrorOutput io.Writer
+	// Observers get progress callbacks, as for WithObserver
+	Observers []Observer
+	// Logger gets structured events, as for WithLogger
+	Logger *slog.Logger
+	// Extra options are applied after the fields, for settings without one
+	Extra []Option
+}
+
+// NewWithOptions creates a Bisector configured by o. It maps the fields onto
+// the equivalent With* options and otherwise behaves like NewFromSource.
+func NewWithOptions(o Options) (Bisector, error) {
+	src := o.Source
+	if src == nil {
+		src = Lines(o.Lines)
+	}
+
+	var opts []Option
+	if o.Good != "" || o.Bad != "" {
+		find := FindBoundaries
+		if o.FindFirstGood {
+			find = FindFirstGoodBoundaries
+		}
+		goodIdx, badIdx, err := find(src, o.Good, o.Bad)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithBoundaries(goodIdx, badIdx))
+	}
+	if o.FindFirstGood {
+		opts = append(opts, WithFindFirstGood())
+	}
+	opts = append(opts,
+		WithTestCommand(o.Test),
+		WithBeforeCommand(o.Before),
+		WithAfterCommand(o.After),
+		WithConcurrency(o.Concurrency),
+		WithRunner(o.Runner),
+		WithShell(o.Shell),
+		WithInput(o.Input),
+		WithOutput(o.Output),
+		WithErrorOutput(o.ErrorOutput),
+		WithLogger(o.Logger),
+	)
+	if o.Oracle != nil {
+		opts = append(opts, WithOracle(o.Oracle))
+	}
+	for _, observer := range o.Observers {
+		opts = append(opts, WithObserver(observer))
+	}
+	return NewFromSource(src, append(opts, o.Extra...)...)
+}
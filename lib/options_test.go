@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Interactive(t *testing.T) {
+	lines := []string{"good1", "good2", "bad1", "bad2", "bad3"}
+
+	bisector, err := New(lines, WithInput(strings.NewReader("g\nb\n")))
+	require.NoError(t, err)
+	assert.IsType(t, &InteractiveBisector{}, bisector)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 2, result.StepsTaken)
+}
+
+func TestNew_Automatic(t *testing.T) {
+	lines := []string{"line1", "line2", "ERROR", "line4"}
+
+	var scriptLogic string
+	if runtime.GOOS == "windows" {
+		scriptLogic = `findstr /C:"ERROR" "%1" >nul
+if %errorlevel% equ 0 exit /b 1
+exit /b 0`
+	} else {
+		scriptLogic = `if grep -q "ERROR" "$1"; then
+  exit 1
+fi
+exit 0`
+	}
+
+	scriptPath, cleanup, err := createTestScript(scriptLogic)
+	require.NoError(t, err)
+	defer cleanup()
+
+	bisector, err := New(lines, WithTestCommand(scriptPath))
+	require.NoError(t, err)
+	assert.IsType(t, &AutomaticBisector{}, bisector)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+}
+
+func TestNew_CandidateLine(t *testing.T) {
+	lines := []string{"ERROR early", "line2", "line3", "line4", "ERROR late"}
+
+	// In prefix mode the early ERROR would make every candidate bad
+	var scriptLogic string
+	if runtime.GOOS == "windows" {
+		scriptLogic = `findstr /C:"late" "%1" >nul
+if %errorlevel% equ 0 exit /b 1
+findstr /C:"line4" "%1" >nul
+if %errorlevel% equ 0 exit /b 1
+exit /b 0`
+	} else {
+		scriptLogic = `if grep -q -e "late" -e "line4" "$1"; then
+  exit 1
+fi
+exit 0`
+	}
+
+	scriptPath, cleanup, err := createTestScript(scriptLogic)
+	require.NoError(t, err)
+	defer cleanup()
+
+	bisector, err := New(lines, WithTestCommand(scriptPath), WithCandidateMode(CandidateLine))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+}
+
+func TestNew_Validation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		lines []string
+		opts  []Option
+		err   string
+	}{
+		{"no lines", nil, nil, "no input lines"},
+		{"good after bad", []string{"a", "b", "c"}, []Option{WithBoundaries(2, 1)}, "must come before"},
+		{"equal boundaries", []string{"a", "b", "c"}, []Option{WithBoundaries(1, 1)}, "must come before"},
+		{"out of range", []string{"a", "b", "c"}, []Option{WithBoundaries(0, 3)}, "out of range"},
+		{"single line", []string{"a"}, nil, "must come before"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.lines, tc.opts...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestNew_OutputWriters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+
+	lines := []string{"line1", "line2", "line3"}
+	var out, errOut bytes.Buffer
+
+	bisector, err := New(lines,
+		WithTestCommand("exit 0 #"),
+		WithBeforeCommand("echo before-hook-ran #"),
+		WithAfterCommand("exit 3 #"),
+		WithOutput(&out),
+		WithErrorOutput(&errOut),
+	)
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Starting automatic bisection")
+	assert.Contains(t, out.String(), "before-hook-ran")
+	assert.Contains(t, errOut.String(), "Warning: after command failed")
+}
+
+func TestNew_InteractiveOutput(t *testing.T) {
+	lines := []string{"good1", "good2", "bad"}
+	var out bytes.Buffer
+
+	bisector, err := New(lines, WithInput(strings.NewReader("g\n")), WithOutput(&out))
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Is this line good or bad?")
+	assert.Contains(t, out.String(), "good2")
+}
+
+func TestNew_WithObserver(t *testing.T) {
+	lines := []string{"good1", "good2", "bad1", "bad2", "bad3"}
+
+	var events []string
+	observer := Observer{
+		OnStep: func(p Probe) {
+			events = append(events, fmt.Sprintf("step %d line %d", p.Step, p.Index+1))
+		},
+		OnVerdict: func(p Probe, v Verdict) {
+			events = append(events, fmt.Sprintf("step %d %s", p.Step, v))
+		},
+		OnRangeNarrowed: func(goodIdx, badIdx int) {
+			events = append(events, fmt.Sprintf("range %d-%d", goodIdx+1, badIdx+1))
+		},
+	}
+
+	bisector, err := New(lines,
+		WithInput(strings.NewReader("g\nb\n")),
+		WithOutput(&bytes.Buffer{}),
+		WithObserver(observer),
+		WithObserver(Observer{}), // Nil callbacks are skipped
+	)
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"step 1 line 3", "step 1 good", "range 3-5",
+		"step 2 line 4", "step 2 bad", "range 3-4",
+	}, events)
+}
+
+func TestNew_WithLogger(t *testing.T) {
+	lines := []string{"good", "good", "bad", "bad"}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Line == "bad" {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+	bisector, err := New(lines,
+		WithOracle(oracle),
+		WithBeforeCommand("setup"),
+		WithRunner(outputRunner{code: 1}),
+		WithLogger(logger),
+		WithOutput(&bytes.Buffer{}),
+		WithErrorOutput(&bytes.Buffer{}),
+	)
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `"msg":"probing line","step":1,"line":2`)
+	assert.Contains(t, logs.String(), `"msg":"verdict","step":1,"line":2,"verdict":"good"`)
+	assert.Contains(t, logs.String(), `"level":"WARN","msg":"hook failed","hook":"before","command":"setup `)
+	assert.Contains(t, logs.String(), `"msg":"bisection complete","bad_line":3`)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	lines := []string{"start", "ok", "ok", "ERROR", "ERROR"}
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Line == "ERROR" {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := NewWithOptions(Options{Lines: lines, Oracle: oracle, Output: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.IsType(t, &AutomaticBisector{}, bisector)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+
+	// Patterns select the boundaries
+	var out bytes.Buffer
+	bisector, err = NewWithOptions(Options{
+		Lines:  lines,
+		Good:   "start",
+		Bad:    "ERROR",
+		Input:  strings.NewReader("g\ng\n"),
+		Output: &out,
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &InteractiveBisector{}, bisector)
+	result, err = bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+
+	// Extra options apply on top of the fields
+	bisector, err = NewWithOptions(Options{
+		Source: Lines(lines),
+		Oracle: oracle,
+		Output: &bytes.Buffer{},
+		Extra:  []Option{WithConcurrency(2)},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &ParallelBisector{}, bisector)
+
+	_, err = NewWithOptions(Options{Lines: lines, Bad: "missing"})
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+	_, err = NewWithOptions(Options{})
+	assert.ErrorIs(t, err, ErrNoInput)
+}
@@ -0,0 +1,290 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// Verdict is the outcome of evaluating a candidate
+type Verdict int
+
+const (
+	// Good means the candidate does not exhibit the problem being bisected
+	Good Verdict = iota
+	// Bad means the candidate exhibits the problem being bisected
+	Bad
+	// Skip means the candidate couldn't be tested, so a neighbouring line is
+	// probed instead
+	Skip
+)
+
+// String returns the lowercase name of the verdict
+func (v Verdict) String() string {
+	switch v {
+	case Good:
+		return "good"
+	case Bad:
+		return "bad"
+	case Skip:
+		return "skip"
+	default:
+		return fmt.Sprintf("Verdict(%d)", int(v))
+	}
+}
+
+// MarshalText encodes the verdict as its name
+func (v Verdict) MarshalText() ([]byte, error) {
+	if v != Good && v != Bad && v != Skip {
+		return nil, fmt.Errorf("invalid verdict %d", int(v))
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText decodes a verdict name written by MarshalText
+func (v *Verdict) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "good":
+		*v = Good
+	case "bad":
+		*v = Bad
+	case "skip":
+		*v = Skip
+	default:
+		return fmt.Errorf("invalid verdict %q", text)
+	}
+	return nil
+}
+
+// Candidate is a single probe handed to an Oracle
+type Candidate struct {
+	Index int    // 0-indexed line being tested
+	Line  string // Content of the line being tested
+	Path  string // File holding the candidate content, empty if none was written
+
+	src  Source
+	mode CandidateMode
+}
+
+// WriteTo writes the candidate content to w, one line per input line
+func (c Candidate) WriteTo(w io.Writer) (int64, error) {
+	first := 0
+	if c.mode == CandidateLine {
+		first = c.Index
+	}
+
+	if c.src == nil {
+		return 0, errors.New("candidate has no source to write")
+	}
+	return c.src.WriteLines(w, first, c.Index)
+}
+
+// Oracle decides whether a candidate is good or bad.
+//
+// A ParallelBisector or SpeculativeBisector calls Evaluate and runs the before
+// and after commands from several goroutines at once, so they must be safe for
+// concurrent use there, e.g. a CommandOracle whose command doesn't share state
+// between runs.
+// Observer callbacks are serialized and need no locking.
+type Oracle interface {
+	Evaluate(ctx context.Context, c Candidate) (Verdict, error)
+}
+
+// OracleFunc adapts an ordinary function to the Oracle interface
+type OracleFunc func(ctx context.Context, c Candidate) (Verdict, error)
+
+// Evaluate calls f(ctx, c)
+func (f OracleFunc) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	return f(ctx, c)
+}
+
+// SkipExitCode is the exit code with which a test command says it can't judge
+// a line, such as a commit that doesn't build, as with git bisect run. The
+// line is skipped and a neighbouring one tested instead.
+const SkipExitCode = 125
+
+// CommandOracle judges candidates by running a shell command
+// (exit 0 = good, SkipExitCode = skip, other non-zero = bad)
+type CommandOracle struct {
+	Command string    // Supports {file}, {} and {line} placeholders
+	Stdout  io.Writer // Receives the command's stdout, discarded if nil
+	Stderr  io.Writer // Receives the command's stderr, discarded if nil
+	Runner  Runner    // Runs the command, a ShellRunner if nil
+}
+
+// Evaluate runs the command for c, substituting c.Path and c.Line
+func (o *CommandOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	runner := o.Runner
+	if runner == nil {
+		runner = ShellRunner{}
+	}
+	cmdStr := buildCommand(c.Path, c.Line, o.Command, quoteFor(runner))
+	stdout, stderr := captureOutput(ctx, o.Stdout, o.Stderr)
+	code, err := runner.Run(ctx, cmdStr, stdout, stderr)
+
+	// A command killed because ctx is done says nothing about the line
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Bad, ctxErr
+	}
+
+	if err != nil {
+		// The command never ran, so there is no verdict to give
+		return Bad, &TestCommandError{Command: cmdStr, ExitCode: -1, Err: err}
+	}
+	if err := execError(cmdStr, code); err != nil {
+		return Bad, err
+	}
+	noteExit(ctx, cmdStr, code)
+	if code == SkipExitCode {
+		return Skip, nil
+	}
+	if code != 0 {
+		// Non-zero exit code means bad
+		return Bad, nil
+	}
+	// Exit code 0 means good
+	return Good, nil
+}
+
+// execError returns a TestCommandError for the exit codes a shell uses when it
+// couldn't run the command at all, which say nothing about the candidate
+func execError(cmdStr string, code int) error {
+	switch code {
+	case 126:
+		return &TestCommandError{Command: cmdStr, ExitCode: code, Err: ErrNotExecutable}
+	case 127:
+		return &TestCommandError{Command: cmdStr, ExitCode: code, Err: ErrCommandNotFound}
+	}
+	return nil
+}
+
+// buildCommand constructs the command string with placeholder substitutions,
+// quoting the line with quote for the shell that runs it. Supports:
+//
+//	{} or {file} - replaced with the temp file path
+//	{line} - replaced with the current line content
+func buildCommand(filePath, lineContent, command string, quote func(string) string) string {
+	cmdStr := command
+
+	// Check if command contains placeholders
+	hasPlaceholder := strings.Contains(cmdStr, "{}")
+	hasFilePlaceholder := strings.Contains(cmdStr, "{file}")
+	hasLinePlaceholder := strings.Contains(cmdStr, "{line}")
+
+	// Replace {line} with the actual line content (properly quoted)
+	if hasLinePlaceholder {
+		cmdStr = strings.ReplaceAll(cmdStr, "{line}", quote(lineContent))
+	}
+
+	// Replace {} or {file} with the temp file path
+	if hasPlaceholder {
+		cmdStr = strings.ReplaceAll(cmdStr, "{}", filePath)
+	}
+	if hasFilePlaceholder {
+		cmdStr = strings.ReplaceAll(cmdStr, "{file}", filePath)
+	}
+
+	// If no placeholders found, append file path as before (backward compatibility)
+	if !hasPlaceholder && !hasFilePlaceholder && !hasLinePlaceholder {
+		if strings.ContainsAny(filePath, " \t") {
+			// Such as a Windows temp directory under C:\Users\First Last
+			filePath = quote(filePath)
+		}
+		cmdStr = fmt.Sprintf("%s %s", cmdStr, filePath)
+	}
+
+	return cmdStr
+}
+
+// substitutePlaceholders replaces {file}, {} and {line} in s verbatim, for
+// patterns and arguments that don't go through a shell
+func substitutePlaceholders(s, filePath, lineContent string) string {
+	return strings.NewReplacer("{file}", filePath, "{}", filePath, "{line}", lineContent).Replace(s)
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+}
+
+// cmdQuote quotes s as a single argument for a program run by cmd.exe: first
+// the way Windows programs split their command line, then with every
+// character cmd.exe treats specially escaped by ^, quotes included, so it
+// neither expands %variables% nor sees & or | in s
+func cmdQuote(s string) string {
+	var arg strings.Builder
+	arg.WriteByte('"')
+	slashes := 0
+	for _, r := range s {
+		switch r {
+		case '\\':
+			slashes++
+		case '"':
+			// Backslashes before a quote are doubled, and the quote escaped
+			arg.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		arg.WriteRune(r)
+	}
+	// And so are those before the closing quote
+	arg.WriteString(strings.Repeat(`\`, slashes))
+	arg.WriteByte('"')
+
+	var escaped strings.Builder
+	for _, r := range arg.String() {
+		if strings.ContainsRune(`()%!^"<>&|`, r) {
+			escaped.WriteByte('^')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// fishQuote single-quotes s for fish, which escapes quotes and backslashes
+// inside single quotes with a backslash
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// pwshQuote single-quotes s for PowerShell, which doubles quotes inside them
+func pwshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// shellQuoter returns how shell, as described by WithShell, quotes an
+// argument: like the platform shell if it is empty
+func shellQuoter(shell string) func(string) string {
+	switch name := shellName(shell); {
+	case name == "" && runtime.GOOS == "windows", name == "cmd":
+		return cmdQuote
+	case name == "fish":
+		return fishQuote
+	case name == "pwsh" || name == "powershell":
+		return pwshQuote
+	}
+	return shellQuote
+}
+
+// shellName returns the program name of shell in lower case, without its
+// directory, arguments or .exe
+func shellName(shell string) string {
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := fields[0][strings.LastIndexAny(fields[0], `/\`)+1:]
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// quoteFor returns how the shell behind runner quotes an argument
+func quoteFor(runner Runner) func(string) string {
+	if q, ok := runner.(Quoter); ok {
+		return q.Quote
+	}
+	return shellQuote
+}
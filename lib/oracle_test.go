@@ -0,0 +1,166 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerdict_String(t *testing.T) {
+	assert.Equal(t, "good", Good.String())
+	assert.Equal(t, "bad", Bad.String())
+	assert.Equal(t, "skip", Skip.String())
+	assert.Equal(t, "Verdict(42)", Verdict(42).String())
+}
+
+func TestCandidate_WriteTo(t *testing.T) {
+	lines := []string{"one", "two", "three"}
+
+	var buf bytes.Buffer
+	n, err := Candidate{Index: 1, Line: "two", src: Lines(lines)}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", buf.String())
+	assert.Equal(t, int64(8), n)
+
+	buf.Reset()
+	_, err = Candidate{Index: 2, Line: "three", src: Lines(lines), mode: CandidateLine}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "three\n", buf.String())
+
+	_, err = Candidate{Index: 0, Line: "one"}.WriteTo(&buf)
+	assert.Error(t, err)
+}
+
+func TestNew_WithOracle(t *testing.T) {
+	lines := []string{"ok", "ok", "ok", "broken", "broken", "broken"}
+
+	var probed []int
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		probed = append(probed, c.Index)
+		assert.NotEmpty(t, c.Path, "candidate file should be written before evaluation")
+		if c.Line == "broken" {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, len(probed), result.StepsTaken)
+}
+
+func TestNew_WithOracleError(t *testing.T) {
+	lines := []string{"a", "b", "c"}
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		return Bad, assert.AnError
+	})
+
+	// The after command still cleans up when the oracle fails
+	var out bytes.Buffer
+	bisector, err := New(lines, WithOracle(oracle), WithAfterCommand("echo after-ran"), WithOutput(&out))
+	require.NoError(t, err)
+
+	_, err = bisector.Bisect()
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, out.String(), "Running after command")
+}
+
+func TestCommandOracle_Evaluate(t *testing.T) {
+	var passing, failing string
+	if runtime.GOOS == "windows" {
+		passing, failing = "exit /b 0 &rem", "exit /b 1 &rem"
+	} else {
+		passing, failing = "exit 0 #", "exit 1 #"
+	}
+
+	c := Candidate{Index: 0, Line: "x", src: Lines{"x"}}
+
+	verdict, err := (&CommandOracle{Command: passing}).Evaluate(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, Good, verdict)
+
+	verdict, err = (&CommandOracle{Command: failing}).Evaluate(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, Bad, verdict)
+}
+
+func TestCommandOracle_ExecError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exit codes 126 and 127 come from POSIX shells")
+	}
+	c := Candidate{Index: 0, Line: "x", src: Lines{"x"}}
+
+	_, err := (&CommandOracle{Command: "bsct-no-such-command #"}).Evaluate(context.Background(), c)
+	assert.ErrorIs(t, err, ErrTestCommandFailed)
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+
+	script := filepath.Join(t.TempDir(), "test.sh")
+	require.NoError(t, os.WriteFile(script, []byte("exit 0\n"), 0644))
+	_, err = (&CommandOracle{Command: script + " #"}).Evaluate(context.Background(), c)
+	assert.ErrorIs(t, err, ErrNotExecutable)
+
+	// Exit codes a test picks itself are still verdicts
+	verdict, err := (&CommandOracle{Command: "exit 3 #"}).Evaluate(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, Bad, verdict)
+}
+
+func TestCommandOracle_SkipExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell script")
+	}
+	// Line 4, the first midpoint, can't be tested, so a neighbour is instead
+	lines := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	test := `case {line} in 4) exit 125;; [1-4]) exit 0;; *) exit 1;; esac #`
+	bisector, err := New(lines, WithOracle(&CommandOracle{Command: test}), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, []int{4}, result.Skipped)
+}
+
+func TestCmdQuote(t *testing.T) {
+	testCases := []struct {
+		in, want string
+	}{
+		{"hello world", `^"hello world^"`},
+		{`say "hi"`, `^"say \^"hi\^"^"`},
+		{"50% & more", `^"50^% ^& more^"`},
+		{`C:\dir\`, `^"C:\dir\\^"`},
+		{`a\"b`, `^"a\\\^"b^"`},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, cmdQuote(tc.in), tc.in)
+	}
+}
+
+func TestBuildCommand_Quote(t *testing.T) {
+	assert.Equal(t, `check 'it'\''s' /tmp/c`, buildCommand("/tmp/c", "it's", "check {line} {file}", shellQuote))
+	assert.Equal(t, `check ^"a ^& b^"`, buildCommand("", "a & b", "check {line}", cmdQuote))
+	// A path appended for lack of placeholders is quoted when it has spaces
+	assert.Equal(t, `check ^"C:\Users\First Last\c.txt^"`, buildCommand(`C:\Users\First Last\c.txt`, "", "check", cmdQuote))
+	assert.Equal(t, `check /tmp/c`, buildCommand("/tmp/c", "", "check", shellQuote))
+}
+
+func TestInteractiveBisector_AsOracle(t *testing.T) {
+	prompt, err := New([]string{"unused", "unused"}, WithInput(strings.NewReader("maybe\nb\n")), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	c := Candidate{Index: 0, Line: "first", src: Lines{"first", "second"}}
+	verdict, err := prompt.(*InteractiveBisector).Evaluate(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, Bad, verdict)
+}
@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Output is what a command run by OutputOracle produced
+type Output struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// Matcher checks one condition on a command's Output
+type Matcher interface {
+	Match(out Output) (bool, error)
+}
+
+// MatcherFunc adapts an ordinary function to the Matcher interface
+type MatcherFunc func(out Output) (bool, error)
+
+// Match calls f(out)
+func (f MatcherFunc) Match(out Output) (bool, error) { return f(out) }
+
+// ExitCodeIn matches exit codes from lo through hi (inclusive)
+func ExitCodeIn(lo, hi int) Matcher {
+	return MatcherFunc(func(out Output) (bool, error) {
+		return out.ExitCode >= lo && out.ExitCode <= hi, nil
+	})
+}
+
+// StdoutMatches matches when re matches anywhere in stdout
+func StdoutMatches(re *regexp.Regexp) Matcher {
+	return MatcherFunc(func(out Output) (bool, error) { return re.Match(out.Stdout), nil })
+}
+
+// StderrMatches matches when re matches anywhere in stderr
+func StderrMatches(re *regexp.Regexp) Matcher {
+	return MatcherFunc(func(out Output) (bool, error) { return re.Match(out.Stderr), nil })
+}
+
+// JSONPathEquals matches when stdout is JSON and the value at path equals
+// want. Path elements are separated by dots and index arrays when numeric,
+// e.g. "items.0.status". Strings compare without their quotes, other values
+// by their JSON encoding.
+func JSONPathEquals(path, want string) Matcher {
+	return MatcherFunc(func(out Output) (bool, error) {
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(out.Stdout))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			// Output that isn't JSON simply doesn't match
+			return false, nil
+		}
+
+		value, ok := lookupJSON(doc, path)
+		if !ok {
+			return false, nil
+		}
+		if s, isString := value.(string); isString {
+			return s == want, nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return false, err
+		}
+		return string(encoded) == want, nil
+	})
+}
+
+// lookupJSON walks the dot-separated path through doc
+func lookupJSON(doc any, path string) (any, bool) {
+	if path == "" {
+		return doc, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			doc = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// OutputOracle judges candidates by running a shell command and checking its
+// output with Matchers. Unlike CommandOracle the exit code only matters when
+// a matcher checks it, apart from SkipExitCode, which skips the line.
+type OutputOracle struct {
+	Command  string    // Supports {file}, {} and {line} placeholders
+	Matchers []Matcher // Conditions for a good verdict
+	Any      bool      // Good when any matcher matches instead of all of them
+	Runner   Runner    // Runs the command, a ShellRunner if nil
+	// MaxOutputBytes caps how much of stdout and of stderr is kept for the
+	// matchers, half from the start and half from the end. 0 keeps it all.
+	MaxOutputBytes int
+}
+
+// Evaluate runs the command for c and applies the matchers to its output
+func (o *OutputOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	runner := o.Runner
+	if runner == nil {
+		runner = ShellRunner{}
+	}
+	cmdStr := buildCommand(c.Path, c.Line, o.Command, quoteFor(runner))
+
+	stdout, stderr := o.buffer(), o.buffer()
+	stdoutW, stderrW := captureOutput(ctx, stdout, stderr)
+	code, err := runner.Run(ctx, cmdStr, stdoutW, stderrW)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Bad, ctxErr
+	}
+	if err != nil {
+		return Bad, &TestCommandError{Command: cmdStr, ExitCode: -1, Err: err}
+	}
+	if err := execError(cmdStr, code); err != nil {
+		return Bad, err
+	}
+	noteExit(ctx, cmdStr, code)
+	if code == SkipExitCode {
+		return Skip, nil
+	}
+
+	out := Output{ExitCode: code, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	for _, m := range o.Matchers {
+		ok, err := m.Match(out)
+		if err != nil {
+			return Bad, fmt.Errorf("failed to match output: %w", err)
+		}
+		if ok && o.Any {
+			return Good, nil
+		}
+		if !ok && !o.Any {
+			return Bad, nil
+		}
+	}
+
+	// All matched, or none did when any would have been enough
+	if o.Any && len(o.Matchers) > 0 {
+		return Bad, nil
+	}
+	return Good, nil
+}
+
+// outputBuffer holds what a command printed
+type outputBuffer interface {
+	io.Writer
+	Bytes() []byte
+}
+
+// buffer returns a buffer for one of the command's streams
+func (o *OutputOracle) buffer() outputBuffer {
+	if o.MaxOutputBytes > 0 {
+		return newHeadTailBuffer(o.MaxOutputBytes)
+	}
+	return &bytes.Buffer{}
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchers(t *testing.T) {
+	out := Output{
+		ExitCode: 2,
+		Stdout:   []byte(`{"status": "ok", "items": [{"n": 3}, {"ready": true}]}`),
+		Stderr:   []byte("warning: deprecated flag"),
+	}
+
+	testCases := []struct {
+		name    string
+		matcher Matcher
+		want    bool
+	}{
+		{"exit in range", ExitCodeIn(1, 3), true},
+		{"exit out of range", ExitCodeIn(0, 0), false},
+		{"stdout regex", StdoutMatches(regexp.MustCompile(`"status": "ok"`)), true},
+		{"stderr regex", StderrMatches(regexp.MustCompile(`^error`)), false},
+		{"json string", JSONPathEquals("status", "ok"), true},
+		{"json number", JSONPathEquals("items.0.n", "3"), true},
+		{"json bool", JSONPathEquals("items.1.ready", "true"), true},
+		{"json mismatch", JSONPathEquals("status", "down"), false},
+		{"json missing", JSONPathEquals("items.5.n", "3"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := tc.matcher.Match(out)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ok)
+		})
+	}
+
+	ok, err := JSONPathEquals("status", "ok").Match(Output{Stdout: []byte("not json")})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// outputRunner returns a fixed exit code and stdout for every command
+type outputRunner struct {
+	code   int
+	stdout string
+}
+
+func (r outputRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	io.WriteString(stdout, r.stdout)
+	return r.code, nil
+}
+
+func TestOutputOracle(t *testing.T) {
+	ready := StdoutMatches(regexp.MustCompile(`ready`))
+	exitZero := ExitCodeIn(0, 0)
+
+	testCases := []struct {
+		name     string
+		runner   outputRunner
+		matchers []Matcher
+		any      bool
+		want     Verdict
+	}{
+		{"all match", outputRunner{0, "ready"}, []Matcher{ready, exitZero}, false, Good},
+		{"one fails all", outputRunner{1, "ready"}, []Matcher{ready, exitZero}, false, Bad},
+		{"one is enough for any", outputRunner{1, "ready"}, []Matcher{ready, exitZero}, true, Good},
+		{"none match any", outputRunner{1, "down"}, []Matcher{ready, exitZero}, true, Bad},
+		{"exit code ignored", outputRunner{1, "ready"}, []Matcher{ready}, false, Good},
+		{"125 skips", outputRunner{SkipExitCode, "ready"}, []Matcher{ready}, false, Skip},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oracle := &OutputOracle{Command: "check", Matchers: tc.matchers, Any: tc.any, Runner: tc.runner}
+			v, err := oracle.Evaluate(context.Background(), Candidate{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+}
+
+func TestOutputOracle_MaxOutputBytes(t *testing.T) {
+	// Only the start and end of a huge output are kept for the matchers
+	runner := outputRunner{0, "starting\n" + strings.Repeat(".", 1<<20) + "\nready"}
+	matchers := []Matcher{MatcherFunc(func(out Output) (bool, error) {
+		assert.Less(t, len(out.Stdout), 100)
+		return true, nil
+	}), StdoutMatches(regexp.MustCompile(`(?s)^starting.*ready$`))}
+
+	oracle := &OutputOracle{Command: "check", Matchers: matchers, Runner: runner, MaxOutputBytes: 64}
+	v, err := oracle.Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Good, v)
+}
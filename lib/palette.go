@@ -0,0 +1,31 @@
+package lib
+
+// Palette holds the ANSI escape codes output is styled with. The zero Palette
+// styles nothing, for output that isn't going to a color terminal.
+type Palette struct {
+	Reset  string
+	Bold   string
+	Faded  string // Dim text, for context around what matters
+	Red    string
+	Green  string
+	Yellow string
+	Blue   string
+	Cyan   string
+}
+
+// NewPalette returns the ANSI colors, or the zero Palette if color is false
+func NewPalette(color bool) Palette {
+	if !color {
+		return Palette{}
+	}
+	return Palette{
+		Reset:  "\033[0m",
+		Bold:   "\033[1m",
+		Faded:  "\033[2m",
+		Red:    "\033[31m",
+		Green:  "\033[32m",
+		Yellow: "\033[33m",
+		Blue:   "\033[34m",
+		Cyan:   "\033[36m",
+	}
+}
@@ -0,0 +1,323 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ParallelBisector splits the remaining range into concurrency+1 parts and
+// evaluates every dividing line at once, narrowing by up to a factor of
+// concurrency+1 per round instead of 2. It is created by New when
+// WithConcurrency is greater than 1. See Oracle for what that requires of the
+// oracle and hooks.
+type ParallelBisector struct {
+	auto        *AutomaticBisector
+	concurrency int
+	mu          sync.Mutex // Serializes observers
+}
+
+func newParallelBisector(src Source, cfg config) *ParallelBisector {
+	auto := newAutomaticBisector(src, cfg)
+	auto.out = &syncWriter{w: auto.out}
+	auto.errOut = &syncWriter{w: auto.errOut}
+	auto.search.out = auto.out
+
+	return &ParallelBisector{auto: auto, concurrency: cfg.concurrency}
+}
+
+// Bisect performs parallel bisection
+func (b *ParallelBisector) Bisect() (*Result, error) {
+	return b.BisectContext(context.Background())
+}
+
+// BisectContext performs parallel bisection. When ctx is done, every running
+// command is killed and the progress so far is returned with an error wrapping
+// ErrInterrupted.
+func (b *ParallelBisector) BisectContext(ctx context.Context) (*Result, error) {
+	a := b.auto
+	s := &a.search
+
+	fmt.Fprintf(a.out, "Starting parallel bisection between lines %d and %d (%d lines total, %d at a time)\n",
+		s.goodIdx+1, s.badIdx+1, s.src.Len(), b.concurrency)
+	if a.testCommand != "" {
+		fmt.Fprintf(a.out, "Test command: %s\n", a.testCommand)
+	}
+	fmt.Fprintln(a.out)
+	if a.checkTest {
+		if err := a.checkBoundaries(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	s.started = time.Now()
+	ctx = s.withMetrics(ctx)
+	for round := 1; ; round++ {
+		points := b.points()
+		if len(points) == 0 {
+			break
+		}
+		if ctx.Err() != nil {
+			return s.partial(), interrupted(ctx)
+		}
+
+		fmt.Fprintf(a.out, "Round %d: Testing %d lines between %d and %d\n", round, len(points), s.goodIdx+1, s.badIdx+1)
+
+		verdicts, err := b.evaluate(ctx, points)
+		if err != nil {
+			if ctx.Err() != nil {
+				return s.partial(), interrupted(ctx)
+			}
+			return nil, err
+		}
+
+		b.merge(points, verdicts)
+		fmt.Fprintf(a.out, "Searching lines %d-%d\n\n", s.goodIdx+1, s.badIdx+1)
+		s.notifyRangeNarrowed()
+	}
+
+	a.warnUnread()
+	return s.result()
+}
+
+// Save writes the progress made so far
+func (b *ParallelBisector) Save(w io.Writer) error { return b.auto.Save(w) }
+
+// Load restores progress written by Save
+func (b *ParallelBisector) Load(r io.Reader) error { return b.auto.Load(r) }
+
+// History returns every verdict reached so far, in order
+func (b *ParallelBisector) History() []Step { return b.auto.History() }
+
+// Replay applies the verdicts of steps in order, like AutomaticBisector.Replay
+func (b *ParallelBisector) Replay(steps []Step) error { return b.auto.Replay(steps) }
+
+// points returns up to concurrency evenly spaced indices strictly between the
+// last good and first bad lines, moving off skipped lines to their nearest
+// untested neighbours
+func (b *ParallelBisector) points() []int {
+	s := &b.auto.search
+	width := s.badIdx - s.goodIdx
+	if width <= 1 {
+		return nil
+	}
+
+	n := b.concurrency
+	if n > width-1 {
+		n = width - 1
+	}
+
+	points := make([]int, 0, n)
+	taken := make(map[int]bool, n)
+	for i := range n {
+		idx, ok := s.nearest(s.goodIdx+(i+1)*width/(n+1), taken)
+		if !ok {
+			break
+		}
+		taken[idx] = true
+		points = append(points, idx)
+	}
+	slices.Sort(points)
+	return points
+}
+
+// evaluate probes every index in points concurrently and returns the verdicts
+// in the same order. The first error cancels the remaining probes.
+func (b *ParallelBisector) evaluate(ctx context.Context, points []int) ([]Verdict, error) {
+	a := b.auto
+	s := &a.search
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	probes := make([]Probe, len(points))
+	ids := make([]string, len(points))
+	for i, idx := range points {
+		c, err := s.candidate(idx)
+		if err != nil {
+			return nil, err
+		}
+		if ids[i], err = s.candidateID(c); err != nil {
+			return nil, err
+		}
+		s.steps++
+		probes[i] = Probe{Candidate: c, Step: s.steps}
+	}
+
+	verdicts := make([]Verdict, len(points))
+	errs := make([]error, len(points))
+	panics := make([]*probePanic, len(points))
+	tested := make([]testRun, 0, len(points))
+	dupOf := make(map[int]int) // Probes with the same candidate as an earlier one this round
+	first := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := range probes {
+		if j, ok := first[ids[i]]; ok {
+			dupOf[i] = j
+			continue
+		}
+		first[ids[i]] = i
+		if v, ok := s.seen[ids[i]]; ok {
+			verdicts[i] = v
+			b.mu.Lock()
+			s.reuse(probes[i], v)
+			s.notifyVerdict(probes[i], v)
+			b.mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer catchPanic(&panics[i], cancel)
+
+			p := probes[i]
+			b.mu.Lock()
+			s.notifyStep(p)
+			b.mu.Unlock()
+
+			// Concurrent probes each need a candidate file of their own
+			file, err := newCandidateFile(a.budget)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			defer file.remove()
+			start := time.Now()
+			run, err := a.probe(ctx, p.Candidate, file, fmt.Sprintf("step %d", p.Step))
+			s.observeProbe(start)
+			if err == nil {
+				// Probes of the same round are only checked against earlier
+				// rounds; merge sorts out disagreements between them
+				err = a.runs.check(run)
+			}
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			verdicts[i] = run.step.Verdict
+			b.mu.Lock()
+			tested = append(tested, run)
+			b.mu.Unlock()
+
+			fmt.Fprintf(a.out, "Step %d: Line %d is %s\n", p.Step, p.Index+1, a.term(verdicts[i]))
+			b.mu.Lock()
+			s.notifyVerdict(p, verdicts[i])
+			b.mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range panics {
+		p.raise()
+	}
+
+	// Report the error that caused the cancellation, not the probes it stopped
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	for i := range probes {
+		if j, ok := dupOf[i]; ok {
+			verdicts[i] = verdicts[j]
+			s.reuse(probes[i], verdicts[i])
+			s.notifyVerdict(probes[i], verdicts[i])
+		}
+	}
+	for i, v := range verdicts {
+		s.remember(ids[i], v)
+	}
+	a.runs.add(tested...)
+	return verdicts, nil
+}
+
+// merge narrows the range with a round of verdicts. The first bad point
+// becomes the new bad line and the last good point before it the new good
+// line; good verdicts after a bad one contradict monotonicity and are ignored,
+// and skipped points are left for later rounds to avoid.
+func (b *ParallelBisector) merge(points []int, verdicts []Verdict) {
+	s := &b.auto.search
+
+	badIdx := s.badIdx
+	for i, idx := range points {
+		s.history = append(s.history, Step{Index: idx, Verdict: verdicts[i]})
+		if verdicts[i] == Skip {
+			s.skip(idx)
+		}
+		if s.searched(verdicts[i]) == Bad && idx < badIdx {
+			badIdx = idx
+		}
+	}
+
+	goodIdx := s.goodIdx
+	for i, idx := range points {
+		if s.searched(verdicts[i]) == Good && idx < badIdx && idx > goodIdx {
+			goodIdx = idx
+		}
+	}
+
+	s.goodIdx, s.badIdx = goodIdx, badIdx
+}
+
+// syncWriter serializes writes from concurrent probes
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes p to the underlying writer while holding the lock
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// probePanic is a panic recovered in a probe's goroutine, carried to the
+// goroutine running the bisection so that it unwinds through its deferred
+// cleanup of temp files and hooks instead of crashing the process without it
+type probePanic struct {
+	value any
+	stack []byte
+}
+
+// Error describes the panic along with the stack of the goroutine it came
+// from, which raising it again would otherwise lose
+func (p *probePanic) Error() string {
+	return fmt.Sprintf("%v [recovered from probe]\n\n%s", p.value, p.stack)
+}
+
+// Unwrap returns the panic value if it was an error
+func (p *probePanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// raise panics again with p if it is set
+func (p *probePanic) raise() {
+	if p != nil {
+		panic(p)
+	}
+}
+
+// catchPanic is deferred by probe goroutines to store a panic in *p and
+// cancel the other probes, if cancel is set
+func catchPanic(p **probePanic, cancel func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*p = &probePanic{value: r, stack: debug.Stack()}
+	if cancel != nil {
+		cancel()
+	}
+}
@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thresholdOracle judges every line at or after firstBad as bad and records how
+// many evaluations ran at the same time
+type thresholdOracle struct {
+	firstBad int
+	delay    time.Duration
+
+	mu         sync.Mutex
+	calls      int
+	running    int32
+	maxRunning int32
+}
+
+func (o *thresholdOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	running := atomic.AddInt32(&o.running, 1)
+	defer atomic.AddInt32(&o.running, -1)
+
+	o.mu.Lock()
+	o.calls++
+	if running > o.maxRunning {
+		o.maxRunning = running
+	}
+	o.mu.Unlock()
+
+	time.Sleep(o.delay)
+	if c.Index >= o.firstBad {
+		return Bad, nil
+	}
+	return Good, nil
+}
+
+func TestParallelBisector(t *testing.T) {
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = "line"
+	}
+
+	for _, firstBad := range []int{1, 2, 137, 500, 998, 999} {
+		oracle := &thresholdOracle{firstBad: firstBad}
+		bisector, err := New(lines, WithOracle(oracle), WithConcurrency(4), WithOutput(&bytes.Buffer{}))
+		require.NoError(t, err)
+		require.IsType(t, &ParallelBisector{}, bisector)
+
+		result, err := bisector.Bisect()
+		require.NoError(t, err)
+		assert.Equal(t, firstBad+1, result.BadLineNumber, "first bad index %d", firstBad)
+		assert.Equal(t, oracle.calls, result.StepsTaken)
+		assert.Equal(t, len(result.History), result.StepsTaken)
+	}
+}
+
+func TestParallelBisector_RunsConcurrently(t *testing.T) {
+	lines := make([]string, 100)
+	oracle := &thresholdOracle{firstBad: 60, delay: 20 * time.Millisecond}
+
+	var rounds int32
+	bisector, err := New(lines,
+		WithOracle(oracle),
+		WithConcurrency(3),
+		WithOutput(&bytes.Buffer{}),
+		WithObserver(Observer{OnRangeNarrowed: func(goodIdx, badIdx int) { atomic.AddInt32(&rounds, 1) }}),
+	)
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 61, result.BadLineNumber)
+	assert.Equal(t, int32(3), oracle.maxRunning)
+	// Splitting into 4 parts per round takes ceil(log4(99)) rounds
+	assert.LessOrEqual(t, rounds, int32(4))
+}
+
+func TestParallelBisector_NonMonotonicVerdicts(t *testing.T) {
+	lines := make([]string, 9)
+	// Line 3 is bad but line 6 claims to be good again
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Index >= 2 && c.Index != 5 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithConcurrency(8), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.Equal(t, 2, result.LastGoodLineNumber)
+}
+
+func TestParallelBisector_Skip(t *testing.T) {
+	lines := make([]string, 100)
+	// Every line in 31-50 is untestable, and the problem starts at line 41
+	var mu sync.Mutex
+	probed := make(map[int]int)
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		mu.Lock()
+		probed[c.Index]++
+		mu.Unlock()
+		switch {
+		case c.Index >= 30 && c.Index < 50:
+			return Bad, &TestCommandError{Command: "make", ExitCode: 126, Err: ErrNotExecutable}
+		case c.Index >= 40:
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithConcurrency(4), WithOnExecError(ActionSkip),
+		WithOutput(io.Discard), WithErrorOutput(io.Discard))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 51, result.BadLineNumber)
+	assert.Equal(t, 31, result.RangeStart)
+	assert.Equal(t, 51, result.RangeEnd)
+	assert.Len(t, result.Skipped, 20)
+	for idx, n := range probed {
+		assert.Equal(t, 1, n, "line %d probed more than once", idx+1)
+	}
+}
+
+func TestParallelBisector_Error(t *testing.T) {
+	lines := make([]string, 50)
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if c.Index == 9 { // The first of the points 9, 19, 29 and 39
+			return Bad, assert.AnError
+		}
+		<-ctx.Done() // Every other probe waits to be canceled
+		return Bad, ctx.Err()
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithConcurrency(4), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	// A probe that never returns would otherwise hang the test
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestParallelBisector_Panic(t *testing.T) {
+	lines := make([]string, 50)
+	var mu sync.Mutex
+	var paths []string
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		mu.Lock()
+		paths = append(paths, c.Path)
+		mu.Unlock()
+		if c.Index == 9 {
+			panic(assert.AnError)
+		}
+		<-ctx.Done()
+		return Bad, ctx.Err()
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithConcurrency(4), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	// The panic reaches the caller, after every probe has cleaned up
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			assert.ErrorIs(t, err, assert.AnError)
+		}()
+		bisector.BisectContext(context.Background())
+		t.Error("expected a panic")
+	}()
+	require.Len(t, paths, 4)
+	for _, path := range paths {
+		assert.NoFileExists(t, path)
+	}
+}
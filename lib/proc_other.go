@@ -0,0 +1,11 @@
+//go:build !unix
+
+package lib
+
+import "os/exec"
+
+// isolate does nothing: there are no process groups to kill children by
+func isolate(cmd *exec.Cmd, setGroup bool) {}
+
+// killGroup does nothing and reports that nothing was left
+func killGroup(cmd *exec.Cmd) bool { return false }
@@ -0,0 +1,32 @@
+//go:build unix
+
+package lib
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolate makes cmd lead a process group of its own and kills the whole
+// group when cmd's context is done. setGroup is false when something else,
+// like starting a pseudo-terminal session, makes cmd a group leader already.
+func isolate(cmd *exec.Cmd, setGroup bool) {
+	if setGroup {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Setpgid = true
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// killGroup kills what is left of the process group cmd led and reports
+// whether anything was
+func killGroup(cmd *exec.Cmd) bool {
+	if cmd.Process == nil {
+		return false
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) == nil
+}
@@ -0,0 +1,61 @@
+//go:build !js && !wasip1 && !windows
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// runPTY runs command with shell, the platform shell if empty, with a
+// pseudo-terminal of the given size as its stdin, stdout and stderr, copying
+// what it prints to out
+func runPTY(ctx context.Context, shell, command string, rows, cols uint16, out io.Writer) (int, error) {
+	if out == nil {
+		out = io.Discard
+	}
+	cmd := createCommand(ctx, shell, command)
+	r := reaperFrom(ctx)
+	if r != nil {
+		// The pseudo-terminal's session makes cmd a group leader
+		isolate(cmd, false)
+	}
+	tty, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+	if err != nil {
+		return -1, err
+	}
+	defer tty.Close()
+
+	copied := make(chan struct{})
+	go func() {
+		// Reading fails with EIO on Linux once nothing has the terminal open
+		io.Copy(out, tty)
+		close(copied)
+	}()
+	err = cmd.Wait()
+	if r != nil && killGroup(cmd) {
+		r.reaped.Store(true)
+	}
+
+	// A child left running in the background can keep the terminal open
+	select {
+	case <-copied:
+	case <-time.After(waitDelay):
+		tty.Close()
+		<-copied
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
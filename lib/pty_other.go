@@ -0,0 +1,15 @@
+//go:build js || wasip1 || windows
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+)
+
+// runPTY fails: there are no pseudo-terminals to run commands on
+func runPTY(ctx context.Context, shell, command string, rows, cols uint16, out io.Writer) (int, error) {
+	return -1, errors.New("pseudo-terminals are not supported on " + runtime.GOOS)
+}
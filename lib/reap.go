@@ -0,0 +1,29 @@
+package lib
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type reaperKey struct{}
+
+// reaper asks runShell to run a command in a process group of its own and to
+// kill whatever is left of the group once the command exits, so a server a
+// test started can't hold on to its port and sway the verdicts of later
+// steps
+type reaper struct {
+	reaped atomic.Bool // Whether anything was left to kill
+}
+
+// withReaper returns a copy of ctx whose shell commands are reaped by the
+// returned reaper
+func withReaper(ctx context.Context) (context.Context, *reaper) {
+	r := &reaper{}
+	return context.WithValue(ctx, reaperKey{}, r), r
+}
+
+// reaperFrom returns the reaper carried by ctx, or nil
+func reaperFrom(ctx context.Context) *reaper {
+	r, _ := ctx.Value(reaperKey{}).(*reaper)
+	return r
+}
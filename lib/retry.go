@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryMode selects how RetryOracle uses its attempts
+type RetryMode int
+
+const (
+	// RetryOnError re-evaluates only when the inner oracle returns an error
+	RetryOnError RetryMode = iota
+	// RetryOnBad also re-evaluates bad verdicts and returns good as soon as one
+	// attempt is good, absorbing tests that fail spuriously
+	RetryOnBad
+	// MajorityVote evaluates every attempt and returns the verdict most of them
+	// agree on, bad on a tie. Errors count as neither.
+	MajorityVote
+)
+
+// RetryStrategy configures RetryOracle
+type RetryStrategy struct {
+	Mode    RetryMode
+	Backoff time.Duration // Wait before the first retry, doubled before each one after
+}
+
+// RetryOracle wraps inner so each candidate gets up to n attempts (at least
+// one) according to strategy. When every attempt fails, the last error is
+// returned.
+func RetryOracle(inner Oracle, n int, strategy RetryStrategy) Oracle {
+	return &retryOracle{inner: inner, attempts: max(n, 1), strategy: strategy}
+}
+
+type retryOracle struct {
+	inner    Oracle
+	attempts int
+	strategy RetryStrategy
+}
+
+// Evaluate asks the inner oracle until the strategy is satisfied
+func (o *retryOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	var good, bad int
+	var lastErr error
+
+	for attempt := 0; attempt < o.attempts; attempt++ {
+		if attempt > 0 {
+			if err := o.wait(ctx, attempt); err != nil {
+				return Bad, err
+			}
+			metricsFrom(ctx).Add(MetricRetries, 1)
+		}
+
+		v, err := o.inner.Evaluate(ctx, c)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Bad, err
+			}
+			lastErr = err
+			continue
+		}
+
+		// A line that can't be judged won't become judgeable by asking again
+		if v == Skip {
+			return Skip, nil
+		}
+		switch o.strategy.Mode {
+		case RetryOnError:
+			return v, nil
+		case RetryOnBad:
+			if v == Good {
+				return Good, nil
+			}
+			bad++
+		case MajorityVote:
+			if v == Good {
+				good++
+			} else {
+				bad++
+			}
+			// Stop once the outcome can no longer change
+			if left := o.attempts - attempt - 1; good > bad+left || bad >= good+left {
+				return o.majority(good, bad), nil
+			}
+		}
+	}
+
+	if good+bad == 0 {
+		return Bad, fmt.Errorf("all %d attempts failed: %w", o.attempts, lastErr)
+	}
+	return o.majority(good, bad), nil
+}
+
+// majority returns the verdict with more votes, bad on a tie
+func (o *retryOracle) majority(good, bad int) Verdict {
+	if good > bad {
+		return Good
+	}
+	return Bad
+}
+
+// wait sleeps before the given retry, giving up when ctx is done
+func (o *retryOracle) wait(ctx context.Context, attempt int) error {
+	if o.strategy.Backoff <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(o.strategy.Backoff << (attempt - 1))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
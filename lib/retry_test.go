@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedOracle returns its results in order, one per call
+type scriptedOracle struct {
+	results []any // Verdict or error
+	calls   int
+}
+
+func (o *scriptedOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	r := o.results[o.calls%len(o.results)]
+	o.calls++
+	if err, ok := r.(error); ok {
+		return Bad, err
+	}
+	return r.(Verdict), nil
+}
+
+func TestRetryOracle(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mode    RetryMode
+		n       int
+		results []any
+		want    Verdict
+		calls   int
+	}{
+		{"error then good", RetryOnError, 3, []any{assert.AnError, Good}, Good, 2},
+		{"bad is final", RetryOnError, 3, []any{Bad, Good}, Bad, 1},
+		{"flaky bad absorbed", RetryOnBad, 3, []any{Bad, Bad, Good}, Good, 3},
+		{"always bad", RetryOnBad, 3, []any{Bad}, Bad, 3},
+		{"majority good", MajorityVote, 3, []any{Good, Bad, Good}, Good, 3},
+		{"majority decided early", MajorityVote, 5, []any{Bad, Bad, Bad, Good, Good}, Bad, 3},
+		{"tie is bad", MajorityVote, 2, []any{Good, Bad}, Bad, 2},
+		{"errors don't vote", MajorityVote, 3, []any{assert.AnError, Good, assert.AnError}, Good, 3},
+		{"n below one", RetryOnError, 0, []any{Good}, Good, 1},
+		{"skip isn't retried", RetryOnBad, 3, []any{Skip, Good}, Skip, 1},
+		{"skip doesn't vote", MajorityVote, 3, []any{Skip, Good}, Skip, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &scriptedOracle{results: tc.results}
+			v, err := RetryOracle(inner, tc.n, RetryStrategy{Mode: tc.mode}).Evaluate(context.Background(), Candidate{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+			assert.Equal(t, tc.calls, inner.calls)
+		})
+	}
+}
+
+func TestRetryOracle_AllFail(t *testing.T) {
+	inner := &scriptedOracle{results: []any{assert.AnError}}
+	_, err := RetryOracle(inner, 3, RetryStrategy{}).Evaluate(context.Background(), Candidate{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestRetryOracle_BackoffCanceled(t *testing.T) {
+	inner := &scriptedOracle{results: []any{assert.AnError}}
+	oracle := RetryOracle(inner, 3, RetryStrategy{Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := oracle.Evaluate(ctx, Candidate{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, inner.calls)
+}
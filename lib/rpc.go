@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// RPCServer drives bisections over JSON-RPC 2.0 with LSP-style
+// Content-Length framing, so editor plugins can run bsct over stdio, show
+// probes inline and report the result as a diagnostic. Methods:
+//
+//	bisect/start    {"lines": [...], "good_pattern", "bad_pattern"} -> state
+//	bisect/state    {"id"} -> state
+//	bisect/verdict  {"id", "verdict": "good"} -> state
+//	bisect/result   {"id"} -> result
+//	bisect/cancel   {"id"} -> null
+//
+// Once a session finishes, a bisect/done notification carries the result and
+// a diagnostic for the first bad line.
+type RPCServer struct {
+	Options []Option // Applied to every session's Iterator, e.g. WithCandidateMode
+
+	sessions map[string]*session
+	writeMu  sync.Mutex
+	w        io.Writer
+}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcRequestFailed  = -32000
+)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve answers requests read from r on w until r is exhausted or ctx is done.
+// Requests are handled one at a time in the order they arrive.
+func (s *RPCServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.sessions = make(map[string]*session)
+	s.w = w
+
+	tp := textproto.NewReader(bufio.NewReader(r))
+	for ctx.Err() == nil {
+		body, err := readFrame(tp)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &rpcError{rpcParseError, err.Error()})
+			continue
+		}
+		if msg.JSONRPC != "2.0" || msg.Method == "" {
+			s.reply(msg.ID, nil, &rpcError{rpcInvalidRequest, "not a JSON-RPC 2.0 request"})
+			continue
+		}
+
+		result, rerr := s.handle(msg.Method, msg.Params)
+		// Requests without an ID are notifications and get no reply
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rerr)
+		}
+	}
+	return ctx.Err()
+}
+
+// handle runs one method
+func (s *RPCServer) handle(method string, params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ID          string   `json:"id"`
+		Lines       []string `json:"lines"`
+		GoodPattern string   `json:"good_pattern"`
+		BadPattern  string   `json:"bad_pattern"`
+		Verdict     *Verdict `json:"verdict"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+	}
+
+	if method == "bisect/start" {
+		sess, err := newSession(p.Lines, p.GoodPattern, p.BadPattern, s.Options)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		s.sessions[sess.id] = sess
+		return sess.stateLocked(), nil
+	}
+
+	sess, ok := s.sessions[p.ID]
+	if !ok {
+		switch method {
+		case "bisect/state", "bisect/verdict", "bisect/result", "bisect/cancel":
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("no session %q", p.ID)}
+		}
+		return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("unknown method %q", method)}
+	}
+
+	switch method {
+	case "bisect/state":
+		return sess.stateLocked(), nil
+
+	case "bisect/verdict":
+		if p.Verdict == nil {
+			return nil, &rpcError{rpcInvalidParams, "missing verdict"}
+		}
+		if _, ok := sess.it.Next(); !ok {
+			return nil, &rpcError{rpcRequestFailed, sess.doneError().Error()}
+		}
+		if err := sess.it.Report(*p.Verdict); err != nil {
+			return nil, &rpcError{rpcRequestFailed, err.Error()}
+		}
+		state := sess.stateLocked()
+		if state.Done {
+			if res, err := sess.it.Result(); err == nil {
+				s.notify("bisect/done", rpcDone(sess.id, res))
+			}
+		}
+		return state, nil
+
+	case "bisect/result":
+		if err := sess.it.Err(); err != nil {
+			return nil, &rpcError{rpcRequestFailed, err.Error()}
+		}
+		res, err := sess.it.Result()
+		if err != nil {
+			return nil, &rpcError{rpcRequestFailed, err.Error()}
+		}
+		return newResultResponse(res), nil
+
+	case "bisect/cancel":
+		delete(s.sessions, p.ID)
+		return nil, nil
+
+	default:
+		return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("unknown method %q", method)}
+	}
+}
+
+// rpcDone builds the bisect/done notification, with an LSP diagnostic
+// pointing at the bad line
+func rpcDone(id string, res *Result) map[string]any {
+	line := res.BadLineNumber - 1
+	message := fmt.Sprintf("First bad line, found in %d steps", res.StepsTaken)
+	if !res.Verified {
+		message += " (assumed bad, never tested)"
+	}
+	return map[string]any{
+		"id":     id,
+		"result": newResultResponse(res),
+		"diagnostic": map[string]any{
+			"range": map[string]any{
+				"start": map[string]int{"line": line, "character": 0},
+				"end":   map[string]int{"line": line, "character": len([]rune(res.BadLineContent))},
+			},
+			"severity": 1,
+			"source":   "bsct",
+			"message":  message,
+		},
+	}
+}
+
+// reply sends the response to request id
+func (s *RPCServer) reply(id json.RawMessage, result any, rerr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	msg := rpcMessage{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		// A null result must still be present in a successful response
+		msg.Result = json.RawMessage("null")
+		if result != nil {
+			msg.Result = result
+		}
+	}
+	s.write(msg)
+}
+
+// notify sends a notification
+func (s *RPCServer) notify(method string, params any) {
+	data, _ := json.Marshal(params)
+	s.write(rpcMessage{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *RPCServer) write(msg rpcMessage) {
+	data, _ := json.Marshal(msg)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readFrame reads one Content-Length framed message body
+func readFrame(tp *textproto.Reader) ([]byte, error) {
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(tp.R, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
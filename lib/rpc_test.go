@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCServer(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- (&RPCServer{}).Serve(context.Background(), serverR, serverW)
+		serverW.Close()
+	}()
+
+	responses := textproto.NewReader(bufio.NewReader(clientR))
+	nextID := 0
+	var notifications []rpcMessage
+	call := func(method string, params any) rpcMessage {
+		nextID++
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": nextID, "method": method, "params": params})
+		require.NoError(t, err)
+		fmt.Fprintf(clientW, "Content-Length: %d\r\n\r\n%s", len(data), data)
+		for {
+			msg := readMessage(t, responses)
+			if msg.Method == "" {
+				return msg
+			}
+			notifications = append(notifications, msg)
+		}
+	}
+
+	resp := call("bisect/start", map[string]any{"lines": []string{"good", "good", "bad", "bad", "bad"}})
+	require.Nil(t, resp.Error)
+	var state sessionState
+	remarshal(t, resp.Result, &state)
+	id := state.ID
+
+	for !state.Done {
+		verdict := "good"
+		if state.Probe.Line == "bad" {
+			verdict = "bad"
+		}
+		resp := call("bisect/verdict", map[string]any{"id": id, "verdict": verdict})
+		require.Nil(t, resp.Error)
+		remarshal(t, resp.Result, &state)
+	}
+
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "bisect/done", notifications[0].Method)
+	var params struct {
+		Diagnostic struct {
+			Range struct {
+				Start struct{ Line int }
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(notifications[0].Params, &params))
+	assert.Equal(t, 2, params.Diagnostic.Range.Start.Line)
+
+	resp = call("bisect/result", map[string]any{"id": id})
+	var result resultResponse
+	remarshal(t, resp.Result, &result)
+	assert.Equal(t, 3, result.BadLineNumber)
+
+	resp = call("bisect/verdict", map[string]any{"id": id, "verdict": "bad"})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, rpcRequestFailed, resp.Error.Code)
+
+	resp = call("bisect/nope", map[string]any{"id": id})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, rpcMethodNotFound, resp.Error.Code)
+
+	resp = call("bisect/cancel", map[string]any{"id": id})
+	assert.Nil(t, resp.Error)
+	resp = call("bisect/state", map[string]any{"id": id})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, rpcInvalidParams, resp.Error.Code)
+
+	clientW.Close()
+	require.NoError(t, <-done)
+}
+
+func readMessage(t *testing.T, tp *textproto.Reader) rpcMessage {
+	t.Helper()
+	body, err := readFrame(tp)
+	require.NoError(t, err)
+	var msg rpcMessage
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func remarshal(t *testing.T, from, to any) {
+	t.Helper()
+	data, err := json.Marshal(from)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, to))
+}
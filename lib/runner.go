@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"context"
+	"io"
+)
+
+// Runner runs the shell commands behind CommandOracle and the before and after
+// hooks. Replacing it lets commands run elsewhere, e.g. over SSH, or be faked
+// in tests.
+type Runner interface {
+	// Run runs command to completion and returns its exit code. The error is
+	// only non-nil when the command could not be run at all.
+	Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error)
+}
+
+// Stager is implemented by Runners whose commands can't see local files. The
+// candidate file is staged before the before command runs and unstaged after
+// the after command; placeholders are substituted with the staged path.
+type Stager interface {
+	// Stage copies the local file to where commands run and returns its path there
+	Stage(ctx context.Context, localPath string) (string, error)
+	// Unstage removes a file returned by Stage
+	Unstage(ctx context.Context, path string) error
+}
+
+// Quoter is implemented by Runners whose shell doesn't quote like a POSIX
+// shell. {line} placeholders in the commands they run are quoted with Quote.
+type Quoter interface {
+	// Quote returns s as a single argument in a command line of the shell
+	Quote(s string) string
+}
+
+// ShellRunner runs commands locally with sh -c, or cmd /c on Windows. It is the
+// default Runner. On WebAssembly, where there is no shell, every run fails.
+type ShellRunner struct {
+	Shell string // Runs commands in place of the platform shell if set, as described by WithShell
+}
+
+// Run runs command in the shell
+func (r ShellRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	return runShell(ctx, r.Shell, command, stdout, stderr)
+}
+
+// Quote quotes s for the shell
+func (r ShellRunner) Quote(s string) string { return shellQuoter(r.Shell)(s) }
+
+// PTYRunner runs commands locally like ShellRunner, but attached to a
+// pseudo-terminal, for tools that behave differently or refuse to run without
+// a TTY. A terminal has a single output stream, so everything the command
+// prints goes to stdout. Pseudo-terminals aren't available on Windows or
+// WebAssembly, where every run fails.
+type PTYRunner struct {
+	Rows, Cols uint16 // Terminal size, 24 by 80 if zero
+	Shell      string // Runs commands in place of the platform shell if set, as described by WithShell
+}
+
+// Quote quotes s for the shell
+func (r PTYRunner) Quote(s string) string { return shellQuoter(r.Shell)(s) }
+
+// Run runs command in the shell on a new pseudo-terminal
+func (r PTYRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	rows, cols := r.Rows, r.Cols
+	if rows == 0 {
+		rows = 24
+	}
+	if cols == 0 {
+		cols = 80
+	}
+	return runPTY(ctx, r.Shell, command, rows, cols, stdout)
+}
@@ -0,0 +1,106 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner records every command it is given and fails test commands whose
+// substituted line contains ERROR
+type fakeRunner struct {
+	commands []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	r.commands = append(r.commands, command)
+	if !strings.HasPrefix(command, "test ") {
+		return 0, nil
+	}
+	if strings.Contains(command, "ERROR") {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func TestNew_WithRunner(t *testing.T) {
+	lines := []string{"ok", "ok", "ERROR", "ERROR"}
+	runner := &fakeRunner{}
+	var errOut bytes.Buffer
+
+	bisector, err := New(lines,
+		WithTestCommand("test {line}"),
+		WithBeforeCommand("setup {line}"),
+		WithRunner(runner),
+		WithOutput(&bytes.Buffer{}),
+		WithErrorOutput(&errOut),
+	)
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.Equal(t, []string{"setup 'ok'", "test 'ok'", "setup 'ERROR'", "test 'ERROR'"}, runner.commands)
+	assert.Empty(t, errOut.String())
+}
+
+func TestShellRunner_ExitCode(t *testing.T) {
+	code, err := ShellRunner{}.Run(context.Background(), "exit 3", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	code, err = ShellRunner{}.Run(context.Background(), "exit 0", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+}
+
+func TestShellRunner_Shell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("needs bash")
+	}
+	var out bytes.Buffer
+	code, err := ShellRunner{Shell: "bash"}.Run(context.Background(), `[[ -n $BASH_VERSION ]] && echo "$0"; exit 5`, &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, code)
+	assert.Equal(t, "bash\n", out.String())
+
+	// Arguments given with the shell replace -c
+	code, err = ShellRunner{Shell: "bash -e -c"}.Run(context.Background(), "false; exit 0", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, code)
+}
+
+func TestShellRunner_Quote(t *testing.T) {
+	testCases := []struct {
+		shell, want string
+	}{
+		{"bash", `'it'\''s \'`},
+		{"/usr/bin/fish", `'it\'s \\'`},
+		{"pwsh", `'it''s \'`},
+		{`C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`, `'it''s \'`},
+		{"CMD.EXE", `^"it's \\^"`},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, ShellRunner{Shell: tc.shell}.Quote(`it's \`), tc.shell)
+	}
+}
+
+func TestPTYRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pseudo-terminals are not supported on Windows")
+	}
+
+	var out bytes.Buffer
+	code, err := PTYRunner{Rows: 30, Cols: 100}.Run(context.Background(), "[ -t 0 ] && [ -t 1 ] && stty size && echo oops >&2; exit 4", &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, code)
+	// Terminals end lines with \r\n and have one stream for both outputs
+	assert.Equal(t, "30 100\r\noops\r\n", out.String())
+}
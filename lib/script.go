@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteScript writes a POSIX shell script to w that reproduces the test of
+// the line r reports bad without bsct: it recreates that line's candidate in
+// a temp file, puts it in place of the WithTarget file if there is one, and
+// runs the before, test and after commands on it with sh, exiting with the
+// test command's status. The commands run locally even if WithRunner ran them
+// elsewhere.
+func (b *AutomaticBisector) WriteScript(w io.Writer, r *Result) error {
+	if b.testCommand == "" {
+		return errors.New("a reproduction script needs a test command")
+	}
+	c, err := b.candidate(r.BadLineNumber - 1)
+	if err != nil {
+		return err
+	}
+	var content bytes.Buffer
+	if _, err := c.WriteTo(&content); err != nil {
+		return fmt.Errorf("failed to write candidate: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#!/bin/sh")
+	fmt.Fprintf(bw, "# Reproduces the test bsct judged %s for line %d of %d:\n", b.term(b.searched(Bad)), c.Index+1, b.src.Len())
+	fmt.Fprintf(bw, "#   %s\n", b.display(c.Line))
+	if !r.Verified {
+		fmt.Fprintf(bw, "# Line %d was assumed %s, so bsct never tested it.\n", c.Index+1, b.term(b.searched(Bad)))
+	}
+	fmt.Fprintln(bw, "# Exits with the status of the test command.")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, `file=$(mktemp "${TMPDIR:-/tmp}/bsct-repro.XXXXXX") || exit 1`)
+	cleanup := `rm -f "$file"`
+	if b.targetPath != "" {
+		fmt.Fprintf(bw, "target=%s\n", shellQuote(b.targetPath))
+		fmt.Fprintln(bw, `backup=$(mktemp "${TMPDIR:-/tmp}/bsct-repro.XXXXXX") || exit 1`)
+		fmt.Fprintln(bw, `cp -p "$target" "$backup" || exit 1`)
+		cleanup = `cat "$backup" > "$target"; rm -f "$file" "$backup"`
+	}
+	fmt.Fprintf(bw, "trap %s EXIT\n", shellQuote(cleanup))
+	fmt.Fprintln(bw, "trap 'exit 130' INT TERM")
+	fmt.Fprintln(bw)
+	writeHeredoc(bw, content.Bytes())
+	if b.targetPath != "" {
+		fmt.Fprintln(bw, `cat "$file" > "$target"`)
+	}
+	fmt.Fprintln(bw)
+
+	// Each command runs in a subshell, as bsct runs it in a shell of its own
+	if b.beforeCommand != "" {
+		fmt.Fprintf(bw, "(\n%s\n) || echo \"Warning: before command failed: exit status $?\" >&2\n", buildCommand(`"$file"`, c.Line, b.beforeCommand, shellQuote))
+	}
+	fmt.Fprintf(bw, "(\n%s\n)\nstatus=$?\n", buildCommand(`"$file"`, c.Line, b.testCommand, shellQuote))
+	if b.afterCommand != "" {
+		fmt.Fprintf(bw, "(\n%s\n) || echo \"Warning: after command failed: exit status $?\" >&2\n", buildCommand(`"$file"`, c.Line, b.afterCommand, shellQuote))
+	}
+	fmt.Fprintln(bw, `echo "Test command exited with status $status" >&2`)
+	fmt.Fprintln(bw, `exit "$status"`)
+	return bw.Flush()
+}
+
+// WriteScript writes a script reproducing the test of the line r reports bad,
+// like AutomaticBisector.WriteScript
+func (b *ParallelBisector) WriteScript(w io.Writer, r *Result) error { return b.auto.WriteScript(w, r) }
+
+// WriteScript is like AutomaticBisector.WriteScript
+func (b *SpeculativeBisector) WriteScript(w io.Writer, r *Result) error {
+	return b.auto.WriteScript(w, r)
+}
+
+// writeHeredoc writes shell commands that recreate content exactly in "$file",
+// with a quoted here-document so nothing in it is expanded. Content that
+// doesn't end in a newline goes through a variable to drop the one the
+// here-document adds.
+func writeHeredoc(w io.Writer, content []byte) {
+	if len(content) == 0 {
+		fmt.Fprintln(w, `: > "$file"`)
+		return
+	}
+	if bytes.HasSuffix(content, []byte("\n")) {
+		marker := heredocMarker(content)
+		fmt.Fprintf(w, "cat > \"$file\" <<'%s'\n", marker)
+		w.Write(content)
+		fmt.Fprintln(w, marker)
+		return
+	}
+	// A trailing x keeps command substitution from stripping newlines
+	body := append(bytes.Clone(content), "x\n"...)
+	marker := heredocMarker(body)
+	fmt.Fprintf(w, "content=$(cat <<'%s'\n", marker)
+	w.Write(body)
+	fmt.Fprintf(w, "%s\n)\n", marker)
+	io.WriteString(w, `printf '%s' "${content%x}" > "$file"`+"\n")
+}
+
+// heredocMarker returns a here-document delimiter that no line of body equals
+func heredocMarker(body []byte) string {
+	used := make(map[string]bool)
+	for line := range strings.SplitSeq(string(body), "\n") {
+		if strings.HasPrefix(line, "BSCT_CANDIDATE") {
+			used[line] = true
+		}
+	}
+	marker := "BSCT_CANDIDATE"
+	for i := 1; used[marker]; i++ {
+		marker = "BSCT_CANDIDATE_" + strconv.Itoa(i)
+	}
+	return marker
+}
@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runScript bisects src with the test command and returns the exit status of
+// the script it writes and what the script left in out
+func runScript(t *testing.T, src Source, test string, opts ...Option) (int, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	bisector, err := NewFromSource(src, append([]Option{WithTestCommand(test), WithOutput(io.Discard)}, opts...)...)
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+
+	var script bytes.Buffer
+	require.NoError(t, bisector.(*AutomaticBisector).WriteScript(&script, result))
+	path := filepath.Join(t.TempDir(), "repro.sh")
+	require.NoError(t, os.WriteFile(path, script.Bytes(), 0o755))
+	err = exec.Command("sh", path).Run()
+	code := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else {
+		require.NoError(t, err)
+	}
+	return code, script.String()
+}
+
+func TestWriteScript(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "seen")
+	lines := Lines{"a", "b", "BSCT_CANDIDATE", "bad 'quoted' $HOME", "e"}
+	code, script := runScript(t, lines, "cp {file} "+out+" && ! grep -q bad {file}",
+		WithBeforeCommand("echo {line} > "+out+".line"))
+
+	assert.Equal(t, 1, code)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nBSCT_CANDIDATE\nbad 'quoted' $HOME\n", string(data))
+	data, err = os.ReadFile(out + ".line")
+	require.NoError(t, err)
+	assert.Equal(t, "bad 'quoted' $HOME\n", string(data))
+	assert.Contains(t, script, "<<'BSCT_CANDIDATE_1'")
+}
+
+func TestWriteScript_NoTrailingNewline(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "seen")
+	src := Framed(Lines{"a", "", "X", "d"}, "", "end")
+	code, _ := runScript(t, src, "cp {file} "+out+" && ! grep -q X {file}")
+
+	assert.Equal(t, 1, code)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n\nX\nend", string(data))
+}
+
+func TestWriteScript_Target(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(target, []byte("original\n"), 0o644))
+	out := filepath.Join(dir, "seen")
+	code, _ := runScript(t, Lines{"a", "bad", "c"}, "cp "+target+" "+out+" && ! grep -q bad "+target+" #{file}",
+		WithTarget(target))
+
+	assert.Equal(t, 1, code)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nbad\n", string(data))
+	data, err = os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(data))
+}
+
+func TestWriteScript_NeedsTestCommand(t *testing.T) {
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		if strings.Contains(c.Line, "bad") {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+	bisector, err := New([]string{"a", "bad"}, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Error(t, bisector.(*AutomaticBisector).WriteScript(io.Discard, result))
+}
@@ -0,0 +1,426 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// search tracks the range still being bisected: goodIdx is the last index known
+// to be good and badIdx the first index known to be bad. Either may lie just
+// outside the input (-1 or len) when that side hasn't been established. With
+// firstGood the roles swap, so goodIdx is the last index known to be bad and
+// badIdx the first known to be good.
+type search struct {
+	src          Source
+	goodIdx      int
+	badIdx       int
+	firstGood    bool
+	termGood     string // What good lines are called, if not good
+	termBad      string // What bad lines are called, if not bad
+	steps        int
+	history      []Step
+	mode         CandidateMode
+	observers    []Observer
+	logger       *slog.Logger
+	metrics      Metrics
+	started      time.Time
+	out          io.Writer          // Progress output of the bisector, if any
+	seen         map[string]Verdict // Verdicts by candidateID, to skip duplicate probes
+	skipped      map[int]bool       // Indices whose verdict was Skip
+	rawDisplay   bool               // Whether lines are printed without escaping control characters
+	displayWidth int                // Terminal columns displayed lines are wrapped to, 0 for no wrapping
+	colors       Palette            // Styles of prompts and displayed lines
+	fingerprint  string             // Cached by inputFingerprint
+}
+
+// Step records one verdict reached during a bisection
+type Step struct {
+	Index   int // 0-indexed position that was probed
+	Verdict Verdict
+}
+
+// Observer receives progress callbacks from a bisection so embedding
+// applications can render their own UI. Nil callbacks are skipped.
+type Observer struct {
+	// OnStep is called before a probe is evaluated
+	OnStep func(p Probe)
+	// OnVerdict is called once a verdict for p has been reached
+	OnVerdict func(p Probe, v Verdict)
+	// OnRangeNarrowed is called with the new 0-indexed last good and first
+	// bad lines after every verdict, or last bad and first good lines with
+	// WithFindFirstGood
+	OnRangeNarrowed func(goodIdx, badIdx int)
+}
+
+// next returns the index to probe next, or false once goodIdx and badIdx are
+// adjacent and the first bad index is known, or every line between them was
+// skipped
+func (s *search) next() (int, bool) {
+	return s.nearest(s.goodIdx+(s.badIdx-s.goodIdx)/2, nil)
+}
+
+// nearest returns the index strictly between goodIdx and badIdx closest to
+// idx that wasn't skipped and isn't in taken, preferring the lower one on
+// ties, or false if there is none
+func (s *search) nearest(idx int, taken map[int]bool) (int, bool) {
+	for d := 0; idx-d > s.goodIdx || idx+d < s.badIdx; d++ {
+		for _, i := range []int{idx - d, idx + d} {
+			if i > s.goodIdx && i < s.badIdx && !s.skipped[i] && !taken[i] {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// record narrows the range with the verdict for idx. A skipped index leaves
+// the range as it is and is passed over by next from then on.
+func (s *search) record(idx int, v Verdict) {
+	s.history = append(s.history, Step{Index: idx, Verdict: v})
+	switch s.searched(v) {
+	case Good:
+		s.goodIdx = idx
+	case Bad:
+		s.badIdx = idx
+	case Skip:
+		s.skip(idx)
+	}
+}
+
+// searched returns the verdict v counts as for the range: as it is, or with
+// good and bad swapped when searching for the first good line
+func (s *search) searched(v Verdict) Verdict {
+	switch {
+	case !s.firstGood || v == Skip:
+		return v
+	case v == Good:
+		return Bad
+	}
+	return Good
+}
+
+// term returns what lines with verdict v are called in output
+func (s *search) term(v Verdict) string {
+	switch {
+	case v == Good && s.termGood != "":
+		return s.termGood
+	case v == Bad && s.termBad != "":
+		return s.termBad
+	}
+	return v.String()
+}
+
+// skip marks idx as untestable
+func (s *search) skip(idx int) {
+	if s.skipped == nil {
+		s.skipped = make(map[int]bool)
+	}
+	s.skipped[idx] = true
+}
+
+// errUndone is returned by an evaluate that took the search back to an
+// earlier state instead of judging its line, for narrow to pick a line again
+var errUndone = errors.New("undone")
+
+// narrow halves the range until goodIdx and badIdx are adjacent. Each step asks
+// evaluate for a verdict on the midpoint and then calls report, if non-nil,
+// with the narrowed range in place.
+func (s *search) narrow(ctx context.Context, evaluate func(ctx context.Context, idx int) (Verdict, error), report func(idx int, v Verdict)) error {
+	for {
+		midIdx, ok := s.next()
+		if !ok {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return interrupted(ctx)
+		}
+
+		s.steps++
+		verdict, err := evaluate(ctx, midIdx)
+		if errors.Is(err, errUndone) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				s.steps-- // Never finished
+				return interrupted(ctx)
+			}
+			return err
+		}
+
+		s.record(midIdx, verdict)
+		if report != nil {
+			report(midIdx, verdict)
+		}
+	}
+}
+
+// run narrows the range over s.src, handing each midpoint to evaluate as a
+// Candidate, and returns the first bad line
+func (s *search) run(ctx context.Context, evaluate func(context.Context, Candidate) (Verdict, error), report func(Candidate, Verdict)) (*Result, error) {
+	s.started = time.Now()
+	ctx = s.withMetrics(ctx)
+
+	var current Probe
+	err := s.narrow(ctx,
+		func(ctx context.Context, idx int) (Verdict, error) {
+			c, err := s.candidate(idx)
+			if err != nil {
+				return Bad, err
+			}
+			current = s.newProbe(c, s.steps)
+			s.notifyStep(current)
+
+			id, err := s.candidateID(c)
+			if err != nil {
+				return Bad, err
+			}
+			if v, ok := s.seen[id]; ok {
+				s.reuse(current, v)
+				return v, nil
+			}
+
+			start := time.Now()
+			v, err := evaluate(ctx, c)
+			s.observeProbe(start)
+			if err == nil {
+				s.remember(id, v)
+			}
+			return v, err
+		},
+		func(idx int, v Verdict) {
+			report(current.Candidate, v)
+			s.notifyVerdict(current, v)
+			s.notifyRangeNarrowed()
+		},
+	)
+	if errors.Is(err, ErrInterrupted) {
+		return s.partial(), err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.result()
+}
+
+// notifyStep logs p and calls OnStep on every observer
+func (s *search) notifyStep(p Probe) {
+	s.log().Debug("probing line", "step", p.Step, "line", p.Index+1)
+	for _, o := range s.observers {
+		if o.OnStep != nil {
+			o.OnStep(p)
+		}
+	}
+}
+
+// notifyVerdict logs v and calls OnVerdict on every observer
+func (s *search) notifyVerdict(p Probe, v Verdict) {
+	s.log().Info("verdict", "step", p.Step, "line", p.Index+1, "verdict", v.String())
+	for _, o := range s.observers {
+		if o.OnVerdict != nil {
+			o.OnVerdict(p, v)
+		}
+	}
+}
+
+// notifyRangeNarrowed logs the current range and calls OnRangeNarrowed on
+// every observer with it
+func (s *search) notifyRangeNarrowed() {
+	s.log().Debug("range narrowed", "good_line", s.goodIdx+1, "bad_line", s.badIdx+1)
+	for _, o := range s.observers {
+		if o.OnRangeNarrowed != nil {
+			o.OnRangeNarrowed(s.goodIdx, s.badIdx)
+		}
+	}
+}
+
+// withMetrics returns ctx carrying the configured Metrics, if any
+func (s *search) withMetrics(ctx context.Context) context.Context {
+	if s.metrics == nil {
+		return ctx
+	}
+	return ContextWithMetrics(ctx, s.metrics)
+}
+
+// observeProbe counts a probe that started at start
+func (s *search) observeProbe(start time.Time) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.Add(MetricSteps, 1)
+	s.metrics.Observe(MetricProbeDuration, time.Since(start))
+}
+
+// candidateID identifies c's content among the candidates of this search.
+// Prefix candidates of different lines never have the same content, so only
+// single-line candidates need hashing.
+func (s *search) candidateID(c Candidate) (string, error) {
+	if c.mode != CandidateLine {
+		return strconv.Itoa(c.Index), nil
+	}
+	return CandidateKey(c)
+}
+
+// remember records the verdict for the candidate with id
+func (s *search) remember(id string, v Verdict) {
+	if s.seen == nil {
+		s.seen = make(map[string]Verdict)
+	}
+	s.seen[id] = v
+}
+
+// reuse reports that p got the verdict of an earlier probe with the same
+// content instead of being evaluated
+func (s *search) reuse(p Probe, v Verdict) {
+	if s.out != nil {
+		fmt.Fprintf(s.out, "Step %d: Line %d has the same candidate as an earlier probe, which was %s\n", p.Step, p.Index+1, s.term(v))
+	}
+	s.log().Debug("reusing verdict of duplicate probe", "step", p.Step, "line", p.Index+1, "verdict", v.String())
+	if s.metrics != nil {
+		s.metrics.Add(MetricDuplicates, 1)
+	}
+}
+
+// log returns the configured logger, or one that discards everything
+func (s *search) log() *slog.Logger {
+	if s.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return s.logger
+}
+
+// summary describes the search so far, with the narrowed range and the line
+// that is the first bad one once the search is done
+func (s *search) summary() (*Result, error) {
+	content, err := s.src.Line(s.badIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := false
+	var skipped []int
+	for _, step := range s.history {
+		if step.Index == s.badIdx && s.searched(step.Verdict) == Bad {
+			verified = true
+		}
+		if step.Verdict == Skip && !slices.Contains(skipped, step.Index+1) {
+			skipped = append(skipped, step.Index+1)
+		}
+	}
+	slices.Sort(skipped)
+
+	firstGood := 0
+	if s.firstGood {
+		firstGood = s.badIdx + 1
+	}
+	return &Result{
+		FirstGoodLine:      firstGood,
+		BadLineNumber:      s.badIdx + 1, // Convert to 1-indexed
+		BadLineContent:     content,
+		StepsTaken:         s.steps,
+		LastGoodLineNumber: s.goodIdx + 1,
+		RangeStart:         s.goodIdx + 2,
+		RangeEnd:           s.badIdx + 1,
+		Verified:           verified,
+		Skipped:            skipped,
+		History:            append([]Step(nil), s.history...),
+		Duration:           time.Since(s.started),
+	}, nil
+}
+
+// result describes the first bad line once the search is done, and logs it
+func (s *search) result() (*Result, error) {
+	r, err := s.summary()
+	if err != nil {
+		return nil, err
+	}
+	s.log().Info("bisection complete", "bad_line", r.BadLineNumber, "steps", r.StepsTaken, "verified", r.Verified, "duration", r.Duration)
+	return r, nil
+}
+
+// partial returns the progress of a bisection that was interrupted, with the
+// range still to be searched, or nil if it can't be read
+func (s *search) partial() *Result {
+	r, err := s.summary()
+	if err != nil {
+		return nil
+	}
+	r.Interrupted = true
+	s.log().Info("bisection interrupted", "range_start", r.RangeStart, "range_end", r.RangeEnd, "steps", r.StepsTaken, "duration", r.Duration)
+	return r
+}
+
+// candidate describes the probe for the line at idx
+func (s *search) candidate(idx int) (Candidate, error) {
+	line, err := s.src.Line(idx)
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	return Candidate{
+		Index: idx,
+		Line:  line,
+		src:   s.src,
+		mode:  s.mode,
+	}, nil
+}
+
+// newProbe wraps c, the midpoint of the current range, as step's Probe
+func (s *search) newProbe(c Candidate, step int) Probe {
+	return Probe{Candidate: c, Step: step, goodIdx: s.goodIdx, badIdx: s.badIdx, skipped: maps.Clone(s.skipped), sequential: true}
+}
+
+// BisectFunc returns the first index in [0, n) for which f reports bad,
+// assuming every index before it is good and every index from it on is bad.
+// Like sort.Search it returns n when f never reports bad, and only calls f for
+// indices in [0, n); a negative n is treated as 0. The first error returned by
+// f stops the search.
+func BisectFunc(n int, f func(i int) (bool, error)) (int, error) {
+	n = max(n, 0)
+	s := search{goodIdx: -1, badIdx: n}
+	err := s.narrow(context.Background(), func(_ context.Context, idx int) (Verdict, error) {
+		bad, err := f(idx)
+		if bad {
+			return Bad, err
+		}
+		return Good, err
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return s.badIdx, nil
+}
+
+// Outcome is the result of Bisect
+type Outcome[T any] struct {
+	Index   int    // 0-indexed position of the first bad item, len(items) if none
+	Item    T      // The first bad item, the zero value if none
+	Found   bool   // Whether any item was judged bad
+	History []Step // Every probe in the order it was made
+}
+
+// Bisect finds the first item in items whose prefix oracle judges bad. The
+// oracle is called with items[:i+1] for probed positions i and must be
+// monotonic: once a prefix is bad, every longer prefix is bad too. Nothing is
+// assumed about either end, so at most ceil(log2(len(items)+1)) probes are made.
+func Bisect[T any](items []T, oracle func(prefix []T) Verdict) *Outcome[T] {
+	s := search{goodIdx: -1, badIdx: len(items)}
+	// evaluate never fails, so neither can narrow
+	_ = s.narrow(context.Background(), func(_ context.Context, idx int) (Verdict, error) {
+		return oracle(items[:idx+1]), nil
+	}, nil)
+
+	outcome := &Outcome[T]{Index: s.badIdx, History: s.history}
+	if s.badIdx < len(items) {
+		outcome.Item = items[s.badIdx]
+		outcome.Found = true
+	}
+	return outcome
+}
@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisectFunc(t *testing.T) {
+	testCases := []struct {
+		name     string
+		n        int
+		firstBad int
+	}{
+		{"empty", 0, 0},
+		{"single bad", 1, 0},
+		{"single good", 1, 1},
+		{"first is bad", 10, 0},
+		{"last is bad", 10, 9},
+		{"none bad", 10, 10},
+		{"middle", 100, 37},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			idx, err := BisectFunc(tc.n, func(i int) (bool, error) {
+				calls++
+				require.GreaterOrEqual(t, i, 0)
+				require.Less(t, i, tc.n)
+				return i >= tc.firstBad, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.firstBad, idx)
+
+			// ceil(log2(n+1)) probes at most
+			maxCalls := 0
+			for 1<<maxCalls < tc.n+1 {
+				maxCalls++
+			}
+			assert.LessOrEqual(t, calls, maxCalls)
+		})
+	}
+}
+
+func TestBisectFunc_NegativeLength(t *testing.T) {
+	idx, err := BisectFunc(-5, func(i int) (bool, error) {
+		t.Fatalf("f called with %d", i)
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, idx)
+}
+
+func TestBisectFunc_Error(t *testing.T) {
+	boom := errors.New("boom")
+	_, err := BisectFunc(10, func(i int) (bool, error) {
+		return false, boom
+	})
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestBisect_Generic(t *testing.T) {
+	type release struct {
+		version string
+		broken  bool
+	}
+	releases := []release{
+		{"1.0", false}, {"1.1", false}, {"1.2", false}, {"2.0", true}, {"2.1", true},
+	}
+
+	outcome := Bisect(releases, func(prefix []release) Verdict {
+		if prefix[len(prefix)-1].broken {
+			return Bad
+		}
+		return Good
+	})
+
+	require.True(t, outcome.Found)
+	assert.Equal(t, 3, outcome.Index)
+	assert.Equal(t, "2.0", outcome.Item.version)
+	assert.LessOrEqual(t, len(outcome.History), 3) // ceil(log2(6))
+
+	// History is replayable: every probe narrows towards the answer
+	for _, step := range outcome.History {
+		if step.Index < outcome.Index {
+			assert.Equal(t, Good, step.Verdict)
+		} else {
+			assert.Equal(t, Bad, step.Verdict)
+		}
+	}
+}
+
+func TestBisect_NoneBad(t *testing.T) {
+	outcome := Bisect([]int{1, 2, 3}, func(prefix []int) Verdict { return Good })
+	assert.False(t, outcome.Found)
+	assert.Equal(t, 3, outcome.Index)
+	assert.Equal(t, 0, outcome.Item)
+}
+
+func TestBisect_PrefixLength(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	outcome := Bisect(items, func(prefix []string) Verdict {
+		// Bad once the prefix contains "c"
+		for _, item := range prefix {
+			if item == "c" {
+				return Bad
+			}
+		}
+		return Good
+	})
+	assert.Equal(t, 2, outcome.Index)
+}
@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// SectionSource is a Source whose lines are sections of another Source's
+// lines, like INI sections, SQL statements or XML elements, so bisection,
+// candidates and reports deal in whole sections. Line returns a section's
+// lines joined by newlines.
+type SectionSource struct {
+	src    Source
+	starts []int // First line in src of every section
+}
+
+// Sections groups the lines of src into sections delimited by markers: a
+// section starts at every line start matches and ends with every line end
+// matches. Either may be nil, but not both. With both, lines outside any
+// section join the section after them, or the last one at the end of the
+// input; with start, lines before its first match form a section of their
+// own either way.
+func Sections(src Source, start, end *regexp.Regexp) (*SectionSource, error) {
+	if start == nil && end == nil {
+		return nil, errors.New("sections need a start or an end marker")
+	}
+
+	s := &SectionSource{src: src, starts: []int{0}}
+	add := func(i int) {
+		if i > s.starts[len(s.starts)-1] {
+			s.starts = append(s.starts, i)
+		}
+	}
+	inside, first, ended := false, true, false
+	afterEnd := 0
+	for i := range src.Len() {
+		line, err := src.Line(i)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case end == nil:
+			if start.MatchString(line) {
+				add(i)
+			}
+		case start == nil:
+			if ended {
+				add(i)
+			}
+			ended = end.MatchString(line)
+		default:
+			if !inside && start.MatchString(line) {
+				inside = true
+				if first {
+					add(i)
+					first = false
+				} else {
+					add(afterEnd)
+				}
+			}
+			if inside && end.MatchString(line) {
+				inside = false
+				afterEnd = i + 1
+			}
+		}
+	}
+	return s, nil
+}
+
+// Len returns the number of sections
+func (s *SectionSource) Len() int {
+	if s.src.Len() == 0 {
+		return 0
+	}
+	return len(s.starts)
+}
+
+// Span returns the 0-indexed first and last lines of section i in the
+// underlying Source
+func (s *SectionSource) Span(i int) (first, last int) {
+	if i+1 < len(s.starts) {
+		return s.starts[i], s.starts[i+1] - 1
+	}
+	return s.starts[i], s.src.Len() - 1
+}
+
+// Line returns the lines of section i joined by newlines
+func (s *SectionSource) Line(i int) (string, error) {
+	if err := checkLine(i, s.Len()); err != nil {
+		return "", err
+	}
+	first, last := s.Span(i)
+	lines := make([]string, 0, last-first+1)
+	for j := first; j <= last; j++ {
+		line, err := s.src.Line(j)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// WriteLines writes the lines of sections from through to (inclusive) to w
+// as the underlying Source writes them
+func (s *SectionSource) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if err := checkRange(from, to, s.Len()); err != nil {
+		return 0, err
+	}
+	first, _ := s.Span(from)
+	_, last := s.Span(to)
+	return s.src.WriteLines(w, first, last)
+}
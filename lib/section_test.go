@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSections(t *testing.T) {
+	testCases := []struct {
+		name       string
+		lines      Lines
+		start, end string
+		want       []string
+	}{
+		{
+			name:  "ini sections",
+			lines: Lines{"; comment", "[a]", "x=1", "[b]", "y=2", "z=3"},
+			start: `^\[`,
+			want:  []string{"; comment", "[a]\nx=1", "[b]\ny=2\nz=3"},
+		},
+		{
+			name:  "sql statements",
+			lines: Lines{"CREATE TABLE t (", "  id int", ");", "INSERT INTO t VALUES (1);", "-- trailing"},
+			end:   `;\s*$`,
+			want:  []string{"CREATE TABLE t (\n  id int\n);", "INSERT INTO t VALUES (1);", "-- trailing"},
+		},
+		{
+			name:  "xml elements",
+			lines: Lines{"<items>", "<item>", "a", "</item>", "", "<item>b</item>", "</items>"},
+			start: `<item>`,
+			end:   `</item>`,
+			want:  []string{"<items>", "<item>\na\n</item>", "\n<item>b</item>\n</items>"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var start, end *regexp.Regexp
+			if tc.start != "" {
+				start = regexp.MustCompile(tc.start)
+			}
+			if tc.end != "" {
+				end = regexp.MustCompile(tc.end)
+			}
+			src, err := Sections(tc.lines, start, end)
+			require.NoError(t, err)
+
+			var got []string
+			for i := range src.Len() {
+				section, err := src.Line(i)
+				require.NoError(t, err)
+				got = append(got, section)
+			}
+			assert.Equal(t, tc.want, got)
+
+			// Candidates hold whole sections as the lines were
+			var buf bytes.Buffer
+			_, err = Candidate{Index: 1, src: src}.WriteTo(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want[0]+"\n"+tc.want[1]+"\n", buf.String())
+		})
+	}
+
+	_, err := Sections(Lines{"a"}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestSections_Span(t *testing.T) {
+	src, err := Sections(Lines{"[a]", "x=1", "[b]", "y=2"}, regexp.MustCompile(`^\[`), nil)
+	require.NoError(t, err)
+	first, last := src.Span(1)
+	assert.Equal(t, 2, first)
+	assert.Equal(t, 3, last)
+}
+
+func TestSections_Bisect(t *testing.T) {
+	lines := Lines{"[a]", "x=1", "[b]", "y=2", "[c]", "broken", "[d]", "w=4"}
+	src, err := Sections(Framed(lines, "; generated\n", ""), regexp.MustCompile(`^\[`), nil)
+	require.NoError(t, err)
+
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		data, err := os.ReadFile(c.Path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "; generated"))
+		if strings.Contains(string(data), "broken") {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+	bisector, err := NewFromSource(src, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.Equal(t, "[c]\nbroken", result.BadLineContent)
+}
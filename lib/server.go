@@ -0,0 +1,557 @@
+package lib
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an http.Handler that lets other tools drive bisections over a REST
+// API. Each session wraps an Iterator; clients fetch the current probe, post
+// verdicts for it and read the result once the first bad line is known.
+//
+//	POST   /sessions                 start a session from {"lines": [...]}
+//	GET    /sessions/{id}            current probe and range
+//	GET    /sessions/{id}/candidate  content of the current probe's candidate
+//	GET    /sessions/{id}/context    input lines around the current probe, ?around=5 on each side
+//	POST   /sessions/{id}/verdict    judge the current probe with {"verdict": "good"}
+//	GET    /sessions/{id}/result     outcome once done
+//	GET    /sessions/{id}/events     server-sent events for every change
+//	DELETE /sessions/{id}            end a session
+//
+// Sessions left alone for SessionTTL are ended, and no more than MaxSessions
+// are kept at a time.
+type Server struct {
+	Options []Option // Applied to every session's Iterator, e.g. WithCandidateMode
+	Metrics Metrics  // Receives sessions in progress, steps and the time each probe waited for its verdict
+
+	// Token, if set, must accompany every request, either in an
+	// "Authorization: Bearer" header or, for clients like EventSource that
+	// can't set headers, a token query parameter
+	Token        string
+	MaxSessions  int           // Sessions kept at a time, 100 if zero
+	SessionTTL   time.Duration // How long an unused session is kept, 24 hours if zero
+	MaxBodyBytes int64         // Largest request body accepted, 32 MiB if zero
+
+	once     sync.Once
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// errTooManySessions refuses a session beyond Server.MaxSessions
+var errTooManySessions = errors.New("too many sessions in progress; delete one or try again later")
+
+// session is one bisection driven through a Server
+type session struct {
+	id       string
+	lastUsed time.Time // When a request last named the session; guarded by Server.mu
+
+	mu          sync.Mutex
+	it          *Iterator
+	subscribers map[chan serverEvent]struct{}
+	metrics     Metrics
+	probeStart  time.Time // When the current probe was first offered
+	finished    bool      // Whether the session no longer counts as in progress
+}
+
+// serverEvent is a change streamed to /events subscribers
+type serverEvent struct {
+	name string
+	data any
+}
+
+// ServeHTTP routes r to the session API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.setup()
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+		return
+	}
+	maxBody := s.MaxBodyBytes
+	if maxBody == 0 {
+		maxBody = 32 << 20
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether r carries the Token, if one is required
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = auth
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// setup creates the session table and routes on first use
+func (s *Server) setup() {
+	s.once.Do(func() {
+		s.sessions = make(map[string]*session)
+		s.mux = http.NewServeMux()
+		s.mux.HandleFunc("POST /sessions", s.create)
+		s.mux.HandleFunc("GET /sessions/{id}", s.withSession(s.state))
+		s.mux.HandleFunc("GET /sessions/{id}/candidate", s.withSession(s.candidate))
+		s.mux.HandleFunc("GET /sessions/{id}/context", s.withSession(s.context))
+		s.mux.HandleFunc("POST /sessions/{id}/verdict", s.withSession(s.verdict))
+		s.mux.HandleFunc("GET /sessions/{id}/result", s.withSession(s.result))
+		s.mux.HandleFunc("GET /sessions/{id}/events", s.withSession(s.events))
+		s.mux.HandleFunc("DELETE /sessions/{id}", s.delete)
+	})
+}
+
+// create starts a session for the posted lines
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Lines       []string `json:"lines"`
+		GoodPattern string   `json:"good_pattern"`
+		BadPattern  string   `json:"bad_pattern"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	sess, err := s.add(req.Lines, req.GoodPattern, req.BadPattern)
+	if errors.Is(err, errTooManySessions) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusCreated, sess.stateLocked())
+}
+
+// NewSession starts a session for lines as if it had been posted to
+// /sessions, e.g. to hand its ID to a browser, and returns the session ID
+func (s *Server) NewSession(lines []string, goodPattern, badPattern string) (string, error) {
+	s.setup()
+	sess, err := s.add(lines, goodPattern, badPattern)
+	if err != nil {
+		return "", err
+	}
+	return sess.id, nil
+}
+
+// add starts a session for lines and puts it in the session table, ending
+// expired sessions first to make room
+func (s *Server) add(lines []string, goodPattern, badPattern string) (*session, error) {
+	maxSessions := s.MaxSessions
+	if maxSessions == 0 {
+		maxSessions = 100
+	}
+	s.expire()
+	s.mu.Lock()
+	full := len(s.sessions) >= maxSessions
+	s.mu.Unlock()
+	if full {
+		return nil, errTooManySessions
+	}
+
+	sess, err := newSession(lines, goodPattern, badPattern, s.Options)
+	if err != nil {
+		return nil, err
+	}
+	if s.Metrics != nil {
+		sess.metrics = s.Metrics
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= maxSessions {
+		return nil, errTooManySessions
+	}
+	sess.metrics.Add(MetricSessions, 1)
+	if sess.it.Done() {
+		sess.finish()
+	}
+	sess.lastUsed = time.Now()
+	s.sessions[sess.id] = sess
+	return sess, nil
+}
+
+// expire ends every session that went unused for longer than SessionTTL
+func (s *Server) expire() {
+	ttl := s.SessionTTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	var expired []*session
+	s.mu.Lock()
+	for id, sess := range s.sessions {
+		if time.Since(sess.lastUsed) > ttl {
+			expired = append(expired, sess)
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, sess := range expired {
+		sess.end()
+	}
+}
+
+// withSession looks up the session named in the path before calling h
+func (s *Server) withSession(h func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		sess, ok := s.sessions[r.PathValue("id")]
+		if ok {
+			sess.lastUsed = time.Now()
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no session %q", r.PathValue("id")))
+			return
+		}
+		h(w, r, sess)
+	}
+}
+
+// state reports the current probe and range
+func (s *Server) state(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusOK, sess.stateLocked())
+}
+
+// candidate writes the content of the current probe's candidate
+func (s *Server) candidate(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	probe, ok := sess.it.Next()
+	if !ok {
+		writeError(w, http.StatusConflict, sess.doneError())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	probe.WriteTo(w)
+}
+
+// maxContext bounds the lines on each side of the probe /context returns, so
+// a page can't ask for the whole input one probe at a time
+const maxContext = 100
+
+// contextResponse is the JSON form of the lines around a probe
+type contextResponse struct {
+	FirstLineNumber int      `json:"first_line_number"` // 1-indexed number of lines[0]
+	ProbeLineNumber int      `json:"probe_line_number"`
+	Lines           []string `json:"lines"`
+}
+
+// context writes the input lines around the current probe, the number given
+// by the around query parameter on each side
+func (s *Server) context(w http.ResponseWriter, r *http.Request, sess *session) {
+	around := 5
+	if param := r.URL.Query().Get("around"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid around %q", param))
+			return
+		}
+		around = min(n, maxContext)
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	probe, ok := sess.it.Next()
+	if !ok {
+		writeError(w, http.StatusConflict, sess.doneError())
+		return
+	}
+	src := sess.it.s.src
+	first, last := max(probe.Index-around, 0), min(probe.Index+around, src.Len()-1)
+	resp := contextResponse{FirstLineNumber: first + 1, ProbeLineNumber: probe.Index + 1}
+	for i := first; i <= last; i++ {
+		line, err := src.Line(i)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp.Lines = append(resp.Lines, line)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// verdict records the posted verdict for the current probe
+func (s *Server) verdict(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req struct {
+		Verdict Verdict `json:"verdict"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	probe, ok := sess.it.Next()
+	if !ok {
+		writeError(w, http.StatusConflict, sess.doneError())
+		return
+	}
+	if err := sess.it.Report(req.Verdict); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	sess.metrics.Add(MetricSteps, 1)
+	sess.metrics.Observe(MetricProbeDuration, time.Since(sess.probeStart))
+	sess.probeStart = time.Now()
+
+	sess.publish(serverEvent{"verdict", map[string]any{"step": probe.Step, "line_number": probe.Index + 1, "verdict": req.Verdict}})
+	state := sess.stateLocked()
+	if state.Done {
+		sess.finish()
+		if res, err := sess.it.Result(); err == nil {
+			sess.publish(serverEvent{"done", newResultResponse(res)})
+		}
+	} else if state.Probe != nil {
+		sess.publish(serverEvent{"probe", state.Probe})
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// result reports the outcome once the session is done
+func (s *Server) result(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if err := sess.it.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	res, err := sess.it.Result()
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newResultResponse(res))
+}
+
+// events streams every change to the session as server-sent events until the
+// client goes away
+func (s *Server) events(w http.ResponseWriter, r *http.Request, sess *session) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	ch := make(chan serverEvent, 16)
+	sess.mu.Lock()
+	sess.subscribers[ch] = struct{}{}
+	state := sess.stateLocked()
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		delete(sess.subscribers, ch)
+		sess.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Start with the current state so late subscribers don't miss the probe
+	writeEvent(w, serverEvent{"state", state})
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, ev)
+			flusher.Flush()
+		}
+	}
+}
+
+// delete ends a session and disconnects its event streams
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	sess, ok := s.sessions[r.PathValue("id")]
+	delete(s.sessions, r.PathValue("id"))
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %q", r.PathValue("id")))
+		return
+	}
+	sess.end()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newSession starts a bisection of lines, finding its boundaries from the
+// patterns if given
+func newSession(lines []string, goodPattern, badPattern string, opts []Option) (*session, error) {
+	src := Lines(lines)
+	goodIdx, badIdx, err := FindBoundaries(src, goodPattern, badPattern)
+	if err != nil {
+		return nil, err
+	}
+	it, err := NewIteratorFromSource(src, append([]Option{WithBoundaries(goodIdx, badIdx)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &session{
+		id:          rand.Text(),
+		it:          it,
+		subscribers: make(map[chan serverEvent]struct{}),
+		metrics:     noMetrics{},
+		probeStart:  time.Now(),
+	}, nil
+}
+
+// end finishes a session taken out of the session table and disconnects its
+// event streams
+func (sess *session) end() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.finish()
+	for ch := range sess.subscribers {
+		close(ch)
+		delete(sess.subscribers, ch)
+	}
+}
+
+// finish stops counting the session as in progress; sess.mu must be held
+func (sess *session) finish() {
+	if !sess.finished {
+		sess.finished = true
+		sess.metrics.Add(MetricSessions, -1)
+	}
+}
+
+// sessionState is the JSON form of a session's progress
+type sessionState struct {
+	ID         string         `json:"id"`
+	Done       bool           `json:"done"`
+	RangeStart int            `json:"range_start"` // 1-indexed first line that may be the first bad line
+	RangeEnd   int            `json:"range_end"`   // 1-indexed last line that may be the first bad line
+	Probe      *probeState    `json:"probe,omitempty"`
+	History    []stepResponse `json:"history"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type probeState struct {
+	Step       int    `json:"step"`
+	LineNumber int    `json:"line_number"`
+	Line       string `json:"line"`
+}
+
+// stateLocked describes the session; sess.mu must be held
+func (sess *session) stateLocked() sessionState {
+	state := sessionState{ID: sess.id, History: newHistory(sess.it.s.history)}
+	if probe, ok := sess.it.Next(); ok {
+		state.Probe = &probeState{Step: probe.Step, LineNumber: probe.Index + 1, Line: probe.Line}
+	}
+	goodIdx, badIdx := sess.it.Range()
+	state.RangeStart, state.RangeEnd = goodIdx+2, badIdx+1
+	if err := sess.it.Err(); err != nil {
+		state.Error = err.Error()
+	}
+	state.Done = sess.it.Done()
+	return state
+}
+
+// doneError explains why there is no probe; sess.mu must be held
+func (sess *session) doneError() error {
+	if err := sess.it.Err(); err != nil {
+		return err
+	}
+	return errors.New("bisection is finished")
+}
+
+// publish sends ev to every subscriber, dropping it for those too slow to
+// keep up; sess.mu must be held
+func (sess *session) publish(ev serverEvent) {
+	for ch := range sess.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// resultResponse is the JSON form of a Result
+type resultResponse struct {
+	BadLineNumber      int            `json:"bad_line_number"`
+	BadLineContent     string         `json:"bad_line_content"`
+	StepsTaken         int            `json:"steps_taken"`
+	LastGoodLineNumber int            `json:"last_good_line_number"`
+	Verified           bool           `json:"verified"`
+	History            []stepResponse `json:"history"`
+	DurationSeconds    float64        `json:"duration_seconds"`
+}
+
+type stepResponse struct {
+	LineNumber int     `json:"line_number"`
+	Verdict    Verdict `json:"verdict"`
+}
+
+func newResultResponse(r *Result) resultResponse {
+	return resultResponse{
+		BadLineNumber:      r.BadLineNumber,
+		BadLineContent:     r.BadLineContent,
+		StepsTaken:         r.StepsTaken,
+		LastGoodLineNumber: r.LastGoodLineNumber,
+		Verified:           r.Verified,
+		History:            newHistory(r.History),
+		DurationSeconds:    r.Duration.Round(time.Millisecond).Seconds(),
+	}
+}
+
+func newHistory(steps []Step) []stepResponse {
+	history := make([]stepResponse, len(steps))
+	for i, step := range steps {
+		history[i] = stepResponse{LineNumber: step.Index + 1, Verdict: step.Verdict}
+	}
+	return history
+}
+
+// readJSON decodes the body of r into v, answering with an error if it can't
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit))
+	case err != nil:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+	}
+	return err == nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeEvent(w http.ResponseWriter, ev serverEvent) {
+	data, _ := json.Marshal(ev.data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, data)
+}
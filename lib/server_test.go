@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	metrics := &PrometheusMetrics{}
+	srv := httptest.NewServer(&Server{Metrics: metrics})
+	defer srv.Close()
+
+	do := func(method, path, body string, out any) int {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	var state sessionState
+	require.Equal(t, http.StatusCreated, do("POST", "/sessions", `{"lines": ["good", "good", "good", "bad", "bad", "bad", "bad"]}`, &state))
+	require.NotEmpty(t, state.ID)
+	assert.Equal(t, int64(1), metrics.values[MetricSessions])
+	base := "/sessions/" + state.ID
+
+	// Follow the event stream while the session is driven
+	resp, err := http.Get(srv.URL + base + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	nextEvent := func() string {
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+		events.ReadString('\n') // data
+		events.ReadString('\n') // blank separator
+		return strings.TrimPrefix(strings.TrimSpace(line), "event: ")
+	}
+	assert.Equal(t, "state", nextEvent())
+
+	assert.Equal(t, http.StatusConflict, do("GET", base+"/result", "", nil))
+
+	for !state.Done {
+		require.NotNil(t, state.Probe)
+
+		cand, err := http.Get(srv.URL + base + "/candidate")
+		require.NoError(t, err)
+		content, _ := io.ReadAll(cand.Body)
+		cand.Body.Close()
+		assert.Equal(t, state.Probe.LineNumber, strings.Count(string(content), "\n"))
+
+		var around contextResponse
+		require.Equal(t, http.StatusOK, do("GET", base+"/context?around=1", "", &around))
+		assert.Equal(t, state.Probe.LineNumber, around.ProbeLineNumber)
+		assert.Equal(t, state.Probe.Line, around.Lines[around.ProbeLineNumber-around.FirstLineNumber])
+		assert.LessOrEqual(t, len(around.Lines), 3)
+
+		verdict := "good"
+		if state.Probe.Line == "bad" {
+			verdict = "bad"
+		}
+		require.Equal(t, http.StatusOK, do("POST", base+"/verdict", `{"verdict": "`+verdict+`"}`, &state))
+		assert.Equal(t, "verdict", nextEvent())
+		if state.Done {
+			assert.Equal(t, "done", nextEvent())
+		} else {
+			assert.Equal(t, "probe", nextEvent())
+		}
+	}
+
+	assert.Equal(t, int64(0), metrics.values[MetricSessions])
+	assert.Equal(t, int64(len(state.History)), metrics.values[MetricSteps])
+	assert.Equal(t, int64(len(state.History)), metrics.durations[MetricProbeDuration].count)
+
+	var result resultResponse
+	require.Equal(t, http.StatusOK, do("GET", base+"/result", "", &result))
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, "bad", result.BadLineContent)
+	assert.Equal(t, len(result.History), result.StepsTaken)
+
+	assert.Equal(t, http.StatusConflict, do("POST", base+"/verdict", `{"verdict": "good"}`, nil))
+	assert.Equal(t, http.StatusConflict, do("GET", base+"/context", "", nil))
+	assert.Equal(t, http.StatusBadRequest, do("GET", base+"/context?around=-1", "", nil))
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/sessions", `{"lines": "nope"}`, nil))
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", base, "", nil))
+	assert.Equal(t, http.StatusNotFound, do("GET", base, "", nil))
+}
+
+func TestServer_Limits(t *testing.T) {
+	srv := httptest.NewServer(&Server{Token: "secret", MaxSessions: 1, MaxBodyBytes: 64})
+	defer srv.Close()
+
+	post := func(token, body string) int {
+		req, err := http.NewRequest("POST", srv.URL+"/sessions", strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("", `{"lines": ["good", "bad"]}`))
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", `{"lines": ["good", "bad"]}`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("secret", `{"lines": ["`+strings.Repeat("x", 64)+`", "bad"]}`))
+	assert.Equal(t, http.StatusCreated, post("secret", `{"lines": ["good", "bad"]}`))
+	assert.Equal(t, http.StatusServiceUnavailable, post("secret", `{"lines": ["good", "bad"]}`))
+
+	// EventSource can only pass the token in the URL
+	resp, err := http.Get(srv.URL + "/sessions/nope?token=secret")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_SessionTTL(t *testing.T) {
+	s := &Server{MaxSessions: 1, SessionTTL: time.Millisecond}
+	first, err := s.NewSession([]string{"good", "bad"}, "", "")
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	second, err := s.NewSession([]string{"good", "bad"}, "", "")
+	require.NoError(t, err)
+	assert.NotContains(t, s.sessions, first)
+	assert.Contains(t, s.sessions, second)
+}
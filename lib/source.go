@@ -0,0 +1,470 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Source gives random access to the lines being bisected
+type Source interface {
+	// Len returns the number of lines
+	Len() int
+	// Line returns line i (0-indexed) without its line ending
+	Line(i int) (string, error)
+	// WriteLines writes lines from through to (inclusive) to w, each
+	// terminated by a newline
+	WriteLines(w io.Writer, from, to int) (int64, error)
+}
+
+// Lines is a Source backed by an in-memory slice
+type Lines []string
+
+// Len returns the number of lines
+func (l Lines) Len() int { return len(l) }
+
+// Line returns line i
+func (l Lines) Line(i int) (string, error) {
+	if err := checkLine(i, len(l)); err != nil {
+		return "", err
+	}
+	return l[i], nil
+}
+
+// WriteLines writes lines from through to (inclusive) to w
+func (l Lines) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if err := checkRange(from, to, len(l)); err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	for i := from; i <= to; i++ {
+		written, err := bw.WriteString(l[i] + "\n")
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// LineIndex is a Source that reads lines from an io.ReaderAt on demand. Only
+// the byte offset of each line is kept in memory, so inputs far larger than RAM
+// can be bisected; candidate content is copied straight from the underlying
+// bytes.
+type LineIndex struct {
+	r      io.ReaderAt
+	starts []int64 // Byte offset where each line starts
+	size   int64
+}
+
+// NewLineIndex scans the first size bytes of r once and records where every
+// line starts. Lines are split the same way as bufio.ScanLines.
+func NewLineIndex(r io.ReaderAt, size int64) (*LineIndex, error) {
+	return NewLineIndexTee(r, size, nil)
+}
+
+// NewLineIndexTee is like NewLineIndex but also writes every byte it scans to
+// w, e.g. a hash.Hash, so the input can be fingerprinted in the same pass
+// instead of being read again. A nil w is ignored.
+func NewLineIndexTee(r io.ReaderAt, size int64, w io.Writer) (*LineIndex, error) {
+	idx := &LineIndex{r: r, size: size}
+
+	var in io.Reader = io.NewSectionReader(r, 0, size)
+	if w != nil {
+		in = io.TeeReader(in, w)
+	}
+	br := bufio.NewReaderSize(in, 64*1024)
+	var offset int64
+	for offset < size {
+		idx.starts = append(idx.starts, offset)
+		chunk, err := br.ReadSlice('\n')
+		offset += int64(len(chunk))
+		for err == bufio.ErrBufferFull {
+			// Line longer than the buffer: keep reading until its end
+			chunk, err = br.ReadSlice('\n')
+			offset += int64(len(chunk))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to index input: %w", err)
+		}
+	}
+
+	return idx, nil
+}
+
+// Len returns the number of lines
+func (x *LineIndex) Len() int { return len(x.starts) }
+
+// end returns the offset just past line i, including its line ending
+func (x *LineIndex) end(i int) int64 {
+	if i+1 < len(x.starts) {
+		return x.starts[i+1]
+	}
+	return x.size
+}
+
+// Line reads line i from the underlying reader
+func (x *LineIndex) Line(i int) (string, error) {
+	if err := checkLine(i, len(x.starts)); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, x.end(i)-x.starts[i])
+	if _, err := x.r.ReadAt(buf, x.starts[i]); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read line %d: %w", i+1, err)
+	}
+
+	buf = bytes.TrimSuffix(buf, []byte("\n"))
+	buf = bytes.TrimSuffix(buf, []byte("\r"))
+	return string(buf), nil
+}
+
+// WriteLines copies the raw bytes of lines from through to (inclusive) to w,
+// adding a trailing newline if the input doesn't end with one. Line endings are
+// kept as they are, so CRLF input produces CRLF candidates, unlike Lines built
+// by ReadLines.
+func (x *LineIndex) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if err := checkRange(from, to, len(x.starts)); err != nil {
+		return 0, err
+	}
+
+	start, end := x.starts[from], x.end(to)
+	n, err := io.Copy(w, io.NewSectionReader(x.r, start, end-start))
+	if err != nil {
+		return n, err
+	}
+
+	if end == x.size {
+		last := make([]byte, 1)
+		if _, err := x.r.ReadAt(last, end-1); err != nil && err != io.EOF {
+			return n, err
+		}
+		if last[0] != '\n' {
+			written, err := io.WriteString(w, "\n")
+			return n + int64(written), err
+		}
+	}
+	return n, nil
+}
+
+// checkLine reports whether i is a valid index into n lines
+func checkLine(i, n int) error {
+	if i < 0 || i >= n {
+		return fmt.Errorf("line index %d out of range for %d lines", i, n)
+	}
+	return nil
+}
+
+// checkRange reports whether from through to (inclusive) is a non-empty range
+// of valid indices into n lines
+func checkRange(from, to, n int) error {
+	if from < 0 || to >= n || from > to {
+		return fmt.Errorf("line range %d-%d out of range for %d lines", from, to, n)
+	}
+	return nil
+}
+
+// Framed returns a Source whose written content is always surrounded by
+// header and footer, e.g. to keep options or brackets that every candidate of
+// a structured file needs. Len and Line are those of src.
+func Framed(src Source, header, footer string) Source {
+	return framed{Source: src, header: header, footer: footer}
+}
+
+type framed struct {
+	Source
+	header, footer string
+}
+
+// WriteLines writes the header, lines from through to of the wrapped Source
+// and the footer
+func (f framed) WriteLines(w io.Writer, from, to int) (int64, error) {
+	n, err := io.WriteString(w, f.header)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	written, err := f.Source.WriteLines(w, from, to)
+	total += written
+	if err != nil {
+		return total, err
+	}
+
+	n, err = io.WriteString(w, f.footer)
+	return total + int64(n), err
+}
+
+// Joined returns a Source whose written content puts lines from through to of
+// src on a single line, separated by sep instead of newlines. With SplitLine
+// it turns the parts of one long line back into a prefix of it. Len and Line
+// are those of src.
+func Joined(src Source, sep string) Source {
+	return joined{Source: src, sep: sep}
+}
+
+type joined struct {
+	Source
+	sep string
+}
+
+// WriteLines writes lines from through to of the wrapped Source separated by
+// sep, followed by a newline
+func (j joined) WriteLines(w io.Writer, from, to int) (int64, error) {
+	if err := checkRange(from, to, j.Len()); err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	for i := from; i <= to; i++ {
+		line, err := j.Line(i)
+		if err != nil {
+			return n, err
+		}
+		if i > from {
+			line = j.sep + line
+		}
+		written, err := bw.WriteString(line)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	written, err := bw.WriteString("\n")
+	n += int64(written)
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// SplitLine splits line into the fields between sep, or into its characters
+// when sep is empty, so a single huge line like a JSON blob or a CSV row can
+// be bisected with Joined. The parts share line's memory.
+func SplitLine(line, sep string) Lines {
+	if sep != "" {
+		return strings.Split(line, sep)
+	}
+	parts := make(Lines, 0, utf8.RuneCountInString(line))
+	for i := 0; i < len(line); {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		parts = append(parts, line[i:i+size])
+		i += size
+	}
+	return parts
+}
+
+// ReadLines reads every line from r into memory. Lines are split the same way
+// as bufio.ScanLines, without its 64KB limit on line length.
+func ReadLines(r io.Reader) (Lines, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, math.MaxInt32)
+
+	var lines Lines
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// ReadLinesFS reads every line of the named file in fsys, so embedded files,
+// archives and test fixtures can be bisected without touching the OS
+// filesystem
+func ReadLinesFS(fsys fs.FS, name string) (Lines, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLines(file)
+}
+
+// FindBoundaries returns the 0-indexed first lines of src containing
+// goodPattern and badPattern. An empty pattern selects the first line for good
+// and the last line for bad. Large inputs are scanned in parallel chunks, so
+// Line must be safe for concurrent use.
+func FindBoundaries(src Source, goodPattern, badPattern string) (int, int, error) {
+	return findBoundaries(src, containsPattern(goodPattern), containsPattern(badPattern), false)
+}
+
+// FindBoundariesRegexp is like FindBoundaries but looks for the first lines
+// matching regular expressions. A nil one selects the first line for good and
+// the last line for bad.
+func FindBoundariesRegexp(src Source, good, bad *regexp.Regexp) (int, int, error) {
+	return findBoundaries(src, regexpPattern(good), regexpPattern(bad), false)
+}
+
+// FindFirstGoodBoundaries is like FindBoundaries for WithFindFirstGood, where
+// the known bad line comes first: an empty badPattern selects the first line
+// and an empty goodPattern the last.
+func FindFirstGoodBoundaries(src Source, goodPattern, badPattern string) (int, int, error) {
+	return findBoundaries(src, containsPattern(goodPattern), containsPattern(badPattern), true)
+}
+
+// FindFirstGoodBoundariesRegexp is like FindFirstGoodBoundaries but looks for
+// the first lines matching regular expressions
+func FindFirstGoodBoundariesRegexp(src Source, good, bad *regexp.Regexp) (int, int, error) {
+	return findBoundaries(src, regexpPattern(good), regexpPattern(bad), true)
+}
+
+// linePattern selects a boundary line. A nil match selects the default one.
+type linePattern struct {
+	desc  string // Shown when nothing matches
+	match func(line string) bool
+}
+
+func containsPattern(s string) linePattern {
+	if s == "" {
+		return linePattern{}
+	}
+	return linePattern{desc: s, match: func(line string) bool { return strings.Contains(line, s) }}
+}
+
+func regexpPattern(re *regexp.Regexp) linePattern {
+	if re == nil {
+		return linePattern{}
+	}
+	return linePattern{desc: re.String(), match: re.MatchString}
+}
+
+func findBoundaries(src Source, good, bad linePattern, firstGood bool) (int, int, error) {
+	if src.Len() == 0 {
+		return 0, 0, ErrNoInput
+	}
+
+	goodIdx := 0
+	badIdx := src.Len() - 1
+	if firstGood {
+		goodIdx, badIdx = badIdx, goodIdx
+	}
+
+	// Search for good pattern if provided
+	if good.match != nil {
+		idx, err := findLine(src, good.match)
+		if err != nil {
+			return 0, 0, err
+		}
+		if idx < 0 {
+			return 0, 0, fmt.Errorf("good %w: %q", ErrPatternNotFound, good.desc)
+		}
+		goodIdx = idx
+	}
+
+	// Search for bad pattern if provided
+	if bad.match != nil {
+		idx, err := findLine(src, bad.match)
+		if err != nil {
+			return 0, 0, err
+		}
+		if idx < 0 {
+			return 0, 0, fmt.Errorf("bad %w: %q", ErrPatternNotFound, bad.desc)
+		}
+		badIdx = idx
+	}
+
+	if firstGood && badIdx >= goodIdx {
+		return 0, 0, fmt.Errorf("%w (good line %d, bad line %d)", ErrGoodBeforeBad, goodIdx+1, badIdx+1)
+	}
+	if !firstGood && goodIdx >= badIdx {
+		return 0, 0, fmt.Errorf("%w (good line %d, bad line %d)", ErrBadBeforeGood, goodIdx+1, badIdx+1)
+	}
+
+	return goodIdx, badIdx, nil
+}
+
+// scanChunk is how many lines findLine hands to a worker at a time
+const scanChunk = 16 * 1024
+
+// findLine returns the index of the first line match accepts, or -1. Inputs
+// of more than one chunk are scanned by a worker per CPU taking chunks in
+// order; chunks after one that matched are skipped.
+func findLine(src Source, match func(string) bool) (int, error) {
+	n := src.Len()
+	workers := runtime.GOMAXPROCS(0)
+	if n <= scanChunk || workers == 1 {
+		return scanLines(src, match, 0, n, nil)
+	}
+
+	var (
+		next    atomic.Int64 // Next chunk to hand out
+		found   atomic.Int64 // Lowest matching index so far
+		mu      sync.Mutex
+		errAt   = math.MaxInt
+		scanErr error
+		wg      sync.WaitGroup
+	)
+	found.Store(math.MaxInt64)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				from := int(next.Add(1)-1) * scanChunk
+				if from >= n || int64(from) > found.Load() {
+					return
+				}
+				idx, err := scanLines(src, match, from, min(from+scanChunk, n), &found)
+				if err != nil {
+					mu.Lock()
+					if from < errAt {
+						errAt, scanErr = from, err
+					}
+					mu.Unlock()
+					return
+				}
+				for idx >= 0 {
+					cur := found.Load()
+					if int64(idx) >= cur || found.CompareAndSwap(cur, int64(idx)) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// An error only matters if no earlier line matched
+	if scanErr != nil && int64(errAt) < found.Load() {
+		return 0, scanErr
+	}
+	if found.Load() == math.MaxInt64 {
+		return -1, nil
+	}
+	return int(found.Load()), nil
+}
+
+// scanLines returns the index of the first line in [from, to) match accepts,
+// or -1. It gives up early once found drops below from.
+func scanLines(src Source, match func(string) bool, from, to int, found *atomic.Int64) (int, error) {
+	for i := from; i < to; i++ {
+		if found != nil && i%1024 == 0 && found.Load() < int64(from) {
+			return -1, nil
+		}
+		line, err := src.Line(i)
+		if err != nil {
+			return 0, err
+		}
+		if match(line) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
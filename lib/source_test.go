@@ -0,0 +1,276 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineIndex(t *testing.T) {
+	long := strings.Repeat("x", 200*1024) // Longer than the index's read buffer
+
+	testCases := []struct {
+		name  string
+		input string
+		lines []string
+	}{
+		{"empty", "", nil},
+		{"trailing newline", "a\nb\nc\n", []string{"a", "b", "c"}},
+		{"no trailing newline", "a\nb\nc", []string{"a", "b", "c"}},
+		{"crlf", "a\r\nb\r\n", []string{"a", "b"}},
+		{"blank lines", "\n\nx\n", []string{"", "", "x"}},
+		{"long line", "a\n" + long + "\nb\n", []string{"a", long, "b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			idx, err := NewLineIndex(strings.NewReader(tc.input), int64(len(tc.input)))
+			require.NoError(t, err)
+			require.Equal(t, len(tc.lines), idx.Len())
+
+			for i, want := range tc.lines {
+				got, err := idx.Line(i)
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestLineIndex_WriteLines(t *testing.T) {
+	input := "one\ntwo\nthree"
+	idx, err := NewLineIndex(strings.NewReader(input), int64(len(input)))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = idx.WriteLines(&buf, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", buf.String())
+
+	// The final line gets the newline the input is missing
+	buf.Reset()
+	n, err := idx.WriteLines(&buf, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "two\nthree\n", buf.String())
+	assert.Equal(t, int64(len("two\nthree\n")), n)
+
+	_, err = idx.Line(3)
+	assert.Error(t, err)
+	_, err = idx.WriteLines(&buf, 2, 3)
+	assert.Error(t, err)
+	_, err = idx.WriteLines(&buf, 2, 1)
+	assert.Error(t, err)
+}
+
+func TestNewLineIndexTee(t *testing.T) {
+	input := "one\n" + strings.Repeat("x", 100*1024) + "\nthree"
+	var tee bytes.Buffer
+	idx, err := NewLineIndexTee(strings.NewReader(input), int64(len(input)), &tee)
+	require.NoError(t, err)
+	assert.Equal(t, 3, idx.Len())
+	assert.Equal(t, input, tee.String())
+}
+
+func TestLineIndex_WriteLinesCRLF(t *testing.T) {
+	input := "one\r\ntwo\r\n"
+	idx, err := NewLineIndex(strings.NewReader(input), int64(len(input)))
+	require.NoError(t, err)
+
+	// Raw bytes are copied, so line endings are preserved
+	var buf bytes.Buffer
+	_, err = idx.WriteLines(&buf, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, input, buf.String())
+}
+
+func TestLines_Bounds(t *testing.T) {
+	lines := Lines{"a", "b"}
+
+	line, err := lines.Line(1)
+	require.NoError(t, err)
+	assert.Equal(t, "b", line)
+
+	_, err = lines.Line(2)
+	assert.Error(t, err)
+	_, err = lines.Line(-1)
+	assert.Error(t, err)
+	_, err = lines.WriteLines(&bytes.Buffer{}, 0, 2)
+	assert.Error(t, err)
+}
+
+func TestNewFromSource_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(path, []byte("line1\nline2\nERROR\nline4\n"), 0644))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	stat, err := file.Stat()
+	require.NoError(t, err)
+
+	idx, err := NewLineIndex(file, stat.Size())
+	require.NoError(t, err)
+
+	var scriptLogic string
+	if runtime.GOOS == "windows" {
+		scriptLogic = `findstr /C:"ERROR" "%1" >nul
+if %errorlevel% equ 0 exit /b 1
+exit /b 0`
+	} else {
+		scriptLogic = `if grep -q "ERROR" "$1"; then
+  exit 1
+fi
+exit 0`
+	}
+
+	scriptPath, cleanup, err := createTestScript(scriptLogic)
+	require.NoError(t, err)
+	defer cleanup()
+
+	bisector, err := NewFromSource(idx, WithTestCommand(scriptPath), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+	assert.Equal(t, "ERROR", result.BadLineContent)
+}
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("y", 100*1024) // Longer than bufio.MaxScanTokenSize
+
+	lines, err := ReadLines(strings.NewReader("a\r\nb\n" + long + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Lines{"a", "b", long}, lines)
+}
+
+func TestReadLinesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/log.txt": {Data: []byte("start\nok\nFATAL\n")},
+	}
+
+	lines, err := ReadLinesFS(fsys, "fixtures/log.txt")
+	require.NoError(t, err)
+	assert.Equal(t, Lines{"start", "ok", "FATAL"}, lines)
+
+	_, err = ReadLinesFS(fsys, "missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestFramed(t *testing.T) {
+	src := Framed(Lines{"a==1", "b==2", "c==3"}, "--index-url https://example.com\n", "# end\n")
+	assert.Equal(t, 3, src.Len())
+
+	line, err := src.Line(1)
+	require.NoError(t, err)
+	assert.Equal(t, "b==2", line)
+
+	var buf bytes.Buffer
+	n, err := Candidate{Index: 1, src: src}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "--index-url https://example.com\na==1\nb==2\n# end\n", buf.String())
+	assert.Equal(t, int64(buf.Len()), n)
+}
+
+func TestSplitLine(t *testing.T) {
+	assert.Equal(t, Lines{"a", "b", "", "c"}, SplitLine("a,b,,c", ","))
+	assert.Equal(t, Lines{"h", "é", "🙂", "!"}, SplitLine("hé🙂!", ""))
+	assert.Empty(t, SplitLine("", ""))
+}
+
+func TestJoined(t *testing.T) {
+	src := Joined(SplitLine(`{"a":1,"b":[2,3]}`, ","), ",")
+	assert.Equal(t, 3, src.Len())
+
+	var buf bytes.Buffer
+	n, err := Candidate{Index: 1, src: src}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1,\"b\":[2\n", buf.String())
+	assert.Equal(t, int64(buf.Len()), n)
+
+	// Growing a prefix rewrites it whole rather than appending another line
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		data, err := os.ReadFile(c.Path)
+		require.NoError(t, err)
+		if strings.Count(string(data), "\n") != 1 {
+			t.Errorf("candidate %q isn't a single line", data)
+		}
+		if strings.Contains(string(data), "x") {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+	bisector, err := NewFromSource(Joined(SplitLine("abcdefxgh", ""), ""), WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.BadLineNumber)
+}
+
+func TestFindBoundaries_Large(t *testing.T) {
+	lines := make(Lines, 10*scanChunk+5)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("entry %d", i)
+	}
+	// Matches in several chunks; the earliest must win
+	lines[3*scanChunk+7] = "deploy ok"
+	lines[6*scanChunk+1] = "FATAL: boom"
+	lines[6*scanChunk+2] = "FATAL: again"
+	lines[9*scanChunk] = "FATAL: later"
+
+	goodIdx, badIdx, err := FindBoundaries(lines, "deploy ok", "FATAL")
+	require.NoError(t, err)
+	assert.Equal(t, 3*scanChunk+7, goodIdx)
+	assert.Equal(t, 6*scanChunk+1, badIdx)
+
+	_, _, err = FindBoundaries(lines, "missing", "")
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+}
+
+func TestFindBoundariesRegexp(t *testing.T) {
+	src := Lines{"boot", "status=200", "status=500 retry", "status=503"}
+
+	goodIdx, badIdx, err := FindBoundariesRegexp(src, regexp.MustCompile(`status=2\d\d`), regexp.MustCompile(`status=5\d\d$`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, goodIdx)
+	assert.Equal(t, 3, badIdx)
+
+	goodIdx, badIdx, err = FindBoundariesRegexp(src, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, goodIdx)
+	assert.Equal(t, 3, badIdx)
+
+	_, _, err = FindBoundariesRegexp(src, regexp.MustCompile(`^panic`), nil)
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+	assert.Contains(t, err.Error(), `"^panic"`)
+}
+
+func TestFindFirstGoodBoundaries(t *testing.T) {
+	src := Lines{"boot", "status=500", "status=503", "status=200", "status=200"}
+
+	goodIdx, badIdx, err := FindFirstGoodBoundaries(src, "status=200", "status=5")
+	require.NoError(t, err)
+	assert.Equal(t, 3, goodIdx)
+	assert.Equal(t, 1, badIdx)
+
+	// The bad line defaults to the first and the good line to the last
+	goodIdx, badIdx, err = FindFirstGoodBoundariesRegexp(src, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, goodIdx)
+	assert.Equal(t, 0, badIdx)
+
+	_, _, err = FindFirstGoodBoundaries(src, "boot", "")
+	assert.ErrorIs(t, err, ErrGoodBeforeBad)
+}
@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SpeculativeBisector tests the midpoint together with both lines that may be
+// probed after it, one for each verdict, and stops the test the verdict rules
+// out. It takes the steps an AutomaticBisector would, but with up to three
+// tests running at a time a slow test costs about half as much wall-clock
+// time. It is created by New with WithSpeculation. See Oracle for what that
+// requires of the oracle and hooks.
+type SpeculativeBisector struct {
+	auto    *AutomaticBisector
+	running map[int]*speculation // Tests of lines that may be probed, by index
+}
+
+// speculation is the test of a line started before it was known to be needed
+type speculation struct {
+	cancel func()
+	done   chan struct{} // Closed once run and err are set
+	run    testRun
+	err    error
+	panic  *probePanic
+}
+
+func newSpeculativeBisector(src Source, cfg config) *SpeculativeBisector {
+	auto := newAutomaticBisector(src, cfg)
+	auto.out = &syncWriter{w: auto.out}
+	auto.errOut = &syncWriter{w: auto.errOut}
+	auto.search.out = auto.out
+
+	return &SpeculativeBisector{auto: auto}
+}
+
+// Bisect performs speculative bisection
+func (b *SpeculativeBisector) Bisect() (*Result, error) {
+	return b.BisectContext(context.Background())
+}
+
+// BisectContext performs speculative bisection. When ctx is done, every
+// running command is killed and the progress so far is returned with an error
+// wrapping ErrInterrupted.
+func (b *SpeculativeBisector) BisectContext(ctx context.Context) (*Result, error) {
+	a := b.auto
+	s := &a.search
+
+	fmt.Fprintf(a.out, "Starting speculative bisection between lines %d and %d (%d lines total)\n",
+		s.goodIdx+1, s.badIdx+1, s.src.Len())
+	if a.testCommand != "" {
+		fmt.Fprintf(a.out, "Test command: %s\n", a.testCommand)
+	}
+	fmt.Fprintln(a.out)
+	if a.checkTest {
+		if err := a.checkBoundaries(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	s.started = time.Now()
+	ctx = s.withMetrics(ctx)
+	b.running = make(map[int]*speculation)
+	defer b.stop()
+	for {
+		idx, ok := s.next()
+		if !ok {
+			break
+		}
+		if ctx.Err() != nil {
+			return s.partial(), interrupted(ctx)
+		}
+
+		c, err := s.candidate(idx)
+		if err != nil {
+			return nil, err
+		}
+		s.steps++
+		p := s.newProbe(c, s.steps)
+		s.notifyStep(p)
+
+		v, err := b.verdict(ctx, p)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.steps-- // Never finished
+				return s.partial(), interrupted(ctx)
+			}
+			return nil, err
+		}
+		s.record(idx, v)
+		fmt.Fprintf(a.out, "Step %d: Line %d is %s. Searching lines %d-%d\n\n", p.Step, idx+1, a.term(v), s.goodIdx+1, s.badIdx+1)
+		s.notifyVerdict(p, v)
+		s.notifyRangeNarrowed()
+	}
+
+	a.warnUnread()
+	return s.result()
+}
+
+// verdict returns the verdict for p, reusing that of an earlier probe with the
+// same candidate, and makes sure both probes that may follow it are tested
+// in the meantime
+func (b *SpeculativeBisector) verdict(ctx context.Context, p Probe) (Verdict, error) {
+	a := b.auto
+	s := &a.search
+
+	id, err := s.candidateID(p.Candidate)
+	if err != nil {
+		return Bad, err
+	}
+	if v, ok := s.seen[id]; ok {
+		s.reuse(p, v)
+		return v, nil
+	}
+
+	wanted := []Candidate{p.Candidate}
+	for _, v := range []Verdict{Good, Bad} {
+		if next, ok := p.Next(v); ok {
+			wanted = append(wanted, next)
+		}
+	}
+	if err := b.speculate(ctx, wanted); err != nil {
+		return Bad, err
+	}
+
+	sp := b.running[p.Index]
+	delete(b.running, p.Index)
+	<-sp.done
+	sp.panic.raise()
+	if sp.err != nil {
+		return Bad, sp.err
+	}
+	if err := a.runs.check(sp.run); err != nil {
+		return Bad, err
+	}
+	a.runs.add(sp.run)
+	v := sp.run.step.Verdict
+	s.remember(id, v)
+	return v, nil
+}
+
+// speculate starts testing every candidate in wanted that isn't being tested
+// or known already and stops the tests of all others. Stopped tests are waited
+// for before new ones start, so no more than three ever run at once.
+func (b *SpeculativeBisector) speculate(ctx context.Context, wanted []Candidate) error {
+	s := &b.auto.search
+
+	keep := make(map[int]bool, len(wanted))
+	for _, c := range wanted {
+		keep[c.Index] = true
+	}
+	var stopped []*speculation
+	for idx, sp := range b.running {
+		if !keep[idx] {
+			fmt.Fprintf(b.auto.out, "Stopping the test of line %d, which won't be probed\n", idx+1)
+			sp.cancel()
+			stopped = append(stopped, sp)
+			delete(b.running, idx)
+		}
+	}
+	for _, sp := range stopped {
+		<-sp.done
+		sp.panic.raise()
+	}
+
+	for _, c := range wanted {
+		if b.running[c.Index] != nil {
+			continue
+		}
+		id, err := s.candidateID(c)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.seen[id]; ok {
+			continue
+		}
+		sp, err := b.start(ctx, c)
+		if err != nil {
+			return err
+		}
+		b.running[c.Index] = sp
+	}
+	return nil
+}
+
+// start tests c in the background on a candidate file of its own
+func (b *SpeculativeBisector) start(ctx context.Context, c Candidate) (*speculation, error) {
+	a := b.auto
+	file, err := newCandidateFile(a.budget)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	sp := &speculation{cancel: func() { cancel(errWrongGuess) }, done: make(chan struct{})}
+	fmt.Fprintf(a.out, "Testing line %d of %d: %s\n", c.Index+1, a.src.Len(), a.display(c.Line))
+	go func() {
+		defer close(sp.done)
+		defer cancel(nil)
+		defer file.remove()
+		defer catchPanic(&sp.panic, nil)
+
+		start := time.Now()
+		sp.run, sp.err = a.probe(ctx, c, file, fmt.Sprintf("line %d", c.Index+1))
+		a.observeProbe(start)
+	}()
+	return sp, nil
+}
+
+// stop stops every test still running and waits for all of them to clean up
+func (b *SpeculativeBisector) stop() {
+	for _, sp := range b.running {
+		sp.cancel()
+	}
+	var panicked *probePanic
+	for _, sp := range b.running {
+		<-sp.done
+		if panicked == nil {
+			panicked = sp.panic
+		}
+	}
+	b.running = nil
+	panicked.raise()
+}
+
+// Save writes the progress made so far
+func (b *SpeculativeBisector) Save(w io.Writer) error { return b.auto.Save(w) }
+
+// Load restores progress written by Save
+func (b *SpeculativeBisector) Load(r io.Reader) error { return b.auto.Load(r) }
+
+// History returns every verdict reached so far, in order
+func (b *SpeculativeBisector) History() []Step { return b.auto.History() }
+
+// Replay applies the verdicts of steps in order, like AutomaticBisector.Replay
+func (b *SpeculativeBisector) Replay(steps []Step) error { return b.auto.Replay(steps) }
@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeculativeBisector(t *testing.T) {
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = "line"
+	}
+
+	for _, firstBad := range []int{1, 2, 137, 500, 998, 999} {
+		sequential, err := New(lines, WithOracle(&thresholdOracle{firstBad: firstBad}), WithOutput(&bytes.Buffer{}))
+		require.NoError(t, err)
+		want, err := sequential.Bisect()
+		require.NoError(t, err)
+
+		bisector, err := New(lines, WithOracle(&thresholdOracle{firstBad: firstBad}), WithSpeculation(), WithOutput(&bytes.Buffer{}))
+		require.NoError(t, err)
+		require.IsType(t, &SpeculativeBisector{}, bisector)
+
+		result, err := bisector.Bisect()
+		require.NoError(t, err)
+		assert.Equal(t, firstBad+1, result.BadLineNumber, "first bad index %d", firstBad)
+		assert.Equal(t, want.History, result.History, "first bad index %d", firstBad)
+		assert.Equal(t, want.StepsTaken, result.StepsTaken)
+	}
+}
+
+func TestSpeculativeBisector_StopsUnneededTests(t *testing.T) {
+	lines := make([]string, 100)
+	var running, maxRunning, stopped, calls int32
+	// The first step tests three lines, which wait for each other so they are
+	// certain to overlap
+	first := make(chan struct{})
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		if call := atomic.AddInt32(&calls, 1); call <= 3 {
+			if call == 3 {
+				close(first)
+			}
+			<-first
+		}
+
+		select {
+		case <-ctx.Done():
+			atomic.AddInt32(&stopped, 1)
+			return Bad, ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+		}
+		if c.Index >= 60 {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithSpeculation(), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 61, result.BadLineNumber)
+	assert.Equal(t, int32(3), maxRunning)
+	assert.Positive(t, stopped)
+	assert.Zero(t, running)
+}
+
+func TestSpeculativeBisector_Interrupted(t *testing.T) {
+	lines := make([]string, 50)
+	var running int32
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		<-ctx.Done()
+		return Bad, ctx.Err()
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithSpeculation(), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := bisector.BisectContext(ctx)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	require.NotNil(t, result)
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, 0, result.StepsTaken)
+	assert.Zero(t, atomic.LoadInt32(&running), "every test returns before BisectContext does")
+}
+
+func TestWithSpeculation_Invalid(t *testing.T) {
+	lines := []string{"a", "b", "c"}
+	_, err := New(lines, WithTestCommand("true"), WithSpeculation(), WithConcurrency(2))
+	assert.Error(t, err)
+	_, err = New(lines, WithTestCommand("true"), WithSpeculation(), WithTarget("config.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+)
+
+// SSHRunner runs commands on a remote host with the ssh CLI and copies
+// candidate files there with scp, for failures that only reproduce on one
+// machine
+type SSHRunner struct {
+	Target    string   // Destination such as user@host
+	Args      []string // Extra options for both ssh and scp, e.g. -o Port=2222
+	RemoteDir string   // Where candidate files are copied, /tmp if empty
+}
+
+// Run runs command on Target through its login shell
+func (r *SSHRunner) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	args := append(append([]string{}, r.Args...), r.Target, command)
+	return runProcess(exec.CommandContext(ctx, "ssh", args...), stdout, stderr)
+}
+
+// Stage copies localPath to RemoteDir on Target
+func (r *SSHRunner) Stage(ctx context.Context, localPath string) (string, error) {
+	dir := r.RemoteDir
+	if dir == "" {
+		dir = "/tmp"
+	}
+	remote := path.Join(dir, filepath.Base(localPath))
+
+	args := append(append([]string{"-q"}, r.Args...), localPath, r.Target+":"+remote)
+	if err := exec.CommandContext(ctx, "scp", args...).Run(); err != nil {
+		return "", err
+	}
+	return remote, nil
+}
+
+// Unstage removes a staged file from Target
+func (r *SSHRunner) Unstage(ctx context.Context, remote string) error {
+	code, err := r.Run(ctx, "rm -f "+shellQuote(remote), nil, nil)
+	if err == nil && code != 0 {
+		err = errors.New("rm failed on " + r.Target)
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+//go:build !js && !wasip1
+
+package lib
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSSH puts ssh and scp stand-ins on PATH that run commands and copy files
+// locally, logging every invocation to the returned path
+func fakeSSH(t *testing.T) string {
+	t.Helper()
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls.log")
+
+	scripts := map[string]string{
+		// The command is the last argument
+		"ssh": `echo "ssh $*" >> ` + log + `
+for last; do :; done
+exec sh -c "$last"`,
+		// Copy the local file to the path after host:
+		"scp": `echo "scp $*" >> ` + log + `
+for last; do :; done
+src=""
+for arg; do [ "$arg" = "$last" ] || src="$arg"; done
+cp "$src" "${last#*:}"`,
+	}
+	for name, body := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+body+"\n"), 0755))
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestSSHRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ssh is a shell script")
+	}
+
+	remoteDir := t.TempDir()
+	log := fakeSSH(t)
+	runner := &SSHRunner{Target: "me@buildbox", Args: []string{"-o", "BatchMode=yes"}, RemoteDir: remoteDir}
+
+	lines := []string{"ok", "ok", "ERROR", "ERROR"}
+	var out bytes.Buffer
+	bisector, err := New(lines,
+		WithTestCommand("grep -q ERROR {file} && exit 1 || exit 0"),
+		WithBeforeCommand("echo staged at {file}"),
+		WithRunner(runner),
+		WithOutput(&out),
+	)
+	require.NoError(t, err)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+
+	// Commands saw the remote copy, which is gone afterwards
+	assert.Contains(t, out.String(), "staged at "+remoteDir)
+	entries, err := os.ReadDir(remoteDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(calls), "ssh -o BatchMode=yes me@buildbox grep -q ERROR "+remoteDir)
+	assert.Contains(t, string(calls), "scp -q -o BatchMode=yes ")
+	assert.Contains(t, string(calls), "me@buildbox:"+remoteDir)
+}
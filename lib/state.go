@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// stateVersion is bumped whenever the saved state format changes
+// incompatibly
+const stateVersion = 1
+
+// savedState is the JSON form written by Save
+type savedState struct {
+	Version     int         `json:"version"`
+	Lines       int         `json:"lines"`
+	Fingerprint string      `json:"fingerprint,omitempty"` // SHA-256 of the whole input, absent from older states
+	GoodIdx     int         `json:"good_index"`
+	BadIdx      int         `json:"bad_index"`
+	Steps       int         `json:"steps"`
+	History     []savedStep `json:"history"`
+}
+
+type savedStep struct {
+	Index   int     `json:"index"`
+	Verdict Verdict `json:"verdict"`
+}
+
+// Save writes the progress made so far as versioned JSON, so a bisection can
+// be resumed later with Load
+func (s *search) Save(w io.Writer) error {
+	fingerprint, err := s.inputFingerprint()
+	if err != nil {
+		return err
+	}
+	state := savedState{
+		Version:     stateVersion,
+		Lines:       s.src.Len(),
+		Fingerprint: fingerprint,
+		GoodIdx:     s.goodIdx,
+		BadIdx:      s.badIdx,
+		Steps:       s.steps,
+		History:     make([]savedStep, len(s.history)),
+	}
+	for i, step := range s.history {
+		state.History[i] = savedStep(step)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(state)
+}
+
+// Load replaces the progress made so far with state written by Save for the
+// same input. Bisecting afterwards picks up where the saved bisection stopped.
+func (s *search) Load(r io.Reader) error {
+	var state savedState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("failed to read saved state: %w", err)
+	}
+
+	if state.Version != stateVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrStateMismatch, state.Version)
+	}
+	if n := s.src.Len(); state.Lines != n {
+		return fmt.Errorf("%w: saved for %d lines, input has %d", ErrStateMismatch, state.Lines, n)
+	}
+	if state.Fingerprint != "" {
+		fingerprint, err := s.inputFingerprint()
+		if err != nil {
+			return err
+		}
+		if state.Fingerprint != fingerprint {
+			return fmt.Errorf("%w: the input changed since it was saved", ErrStateMismatch)
+		}
+	}
+	if state.GoodIdx < -1 || state.BadIdx > state.Lines || state.GoodIdx >= state.BadIdx {
+		return fmt.Errorf("%w: invalid range %d-%d", ErrStateMismatch, state.GoodIdx+1, state.BadIdx+1)
+	}
+
+	s.goodIdx, s.badIdx, s.steps = state.GoodIdx, state.BadIdx, state.Steps
+	s.history = make([]Step, len(state.History))
+	s.skipped = nil
+	for i, step := range state.History {
+		s.history[i] = Step(step)
+		if step.Verdict == Skip {
+			s.skip(step.Index)
+		}
+	}
+	return nil
+}
+
+// History returns every verdict reached so far, in order, including those
+// restored by Load or Replay
+func (s *search) History() []Step {
+	return slices.Clone(s.history)
+}
+
+// Replay applies the verdicts of steps in order as if probes had reached
+// them, such as those logged by an earlier bisection of the same input.
+// Bisecting afterwards continues from there. Each step must be for a line
+// still being searched when it is applied.
+func (s *search) Replay(steps []Step) error {
+	for _, step := range steps {
+		if step.Index <= s.goodIdx || step.Index >= s.badIdx {
+			return fmt.Errorf("%w: line %d is outside lines %d-%d still being searched", ErrStateMismatch, step.Index+1, s.goodIdx+1, s.badIdx+1)
+		}
+		s.steps++
+		s.record(step.Index, step.Verdict)
+		s.notifyRangeNarrowed()
+	}
+	return nil
+}
+
+// inputFingerprint returns the SHA-256 of the whole input as candidates hold
+// it, computed once
+func (s *search) inputFingerprint() (string, error) {
+	if s.fingerprint != "" || s.src.Len() == 0 {
+		return s.fingerprint, nil
+	}
+	h := sha256.New()
+	if _, err := s.src.WriteLines(h, 0, s.src.Len()-1); err != nil {
+		return "", fmt.Errorf("failed to fingerprint input: %w", err)
+	}
+	s.fingerprint = hex.EncodeToString(h.Sum(nil))
+	return s.fingerprint, nil
+}
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator_SaveLoad(t *testing.T) {
+	lines := []string{"good", "good", "good", "good", "bad", "bad", "bad", "bad"}
+
+	first, err := NewIterator(lines)
+	require.NoError(t, err)
+	probe, ok := first.Next()
+	require.True(t, ok)
+	require.NoError(t, first.Report(Good))
+	assert.Equal(t, 3, probe.Index)
+
+	var saved bytes.Buffer
+	require.NoError(t, first.Save(&saved))
+	assert.Contains(t, saved.String(), `"verdict": "good"`)
+
+	// A fresh iterator continues where the first stopped
+	resumed, err := NewIterator(lines)
+	require.NoError(t, err)
+	require.NoError(t, resumed.Load(&saved))
+	goodIdx, badIdx := resumed.Range()
+	assert.Equal(t, 3, goodIdx)
+	assert.Equal(t, 7, badIdx)
+
+	for {
+		probe, ok := resumed.Next()
+		if !ok {
+			break
+		}
+		if probe.Line == "bad" {
+			require.NoError(t, resumed.Report(Bad))
+		} else {
+			require.NoError(t, resumed.Report(Good))
+		}
+	}
+
+	result, err := resumed.Result()
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Len(t, result.History, 3)
+}
+
+func TestBisector_Load(t *testing.T) {
+	lines := []string{"good1", "good2", "bad1", "bad2", "bad3"}
+	saved := `{"version": 1, "lines": 5, "good_index": 2, "bad_index": 4, "steps": 1, "history": [{"index": 2, "verdict": "good"}]}`
+
+	// Only the last question is left to answer
+	bisector, err := New(lines, WithInput(strings.NewReader("b\n")), WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+	require.NoError(t, bisector.Load(strings.NewReader(saved)))
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+	assert.Equal(t, 2, result.StepsTaken)
+}
+
+func TestLoad_Mismatch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		state string
+	}{
+		{"version", `{"version": 99, "lines": 3, "good_index": 0, "bad_index": 2}`},
+		{"line count", `{"version": 1, "lines": 4, "good_index": 0, "bad_index": 3}`},
+		{"range", `{"version": 1, "lines": 3, "good_index": 2, "bad_index": 1}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it, err := NewIterator([]string{"a", "b", "c"})
+			require.NoError(t, err)
+			assert.ErrorIs(t, it.Load(strings.NewReader(tc.state)), ErrStateMismatch)
+		})
+	}
+
+	it, err := NewIterator([]string{"a", "b", "c"})
+	require.NoError(t, err)
+	err = it.Load(strings.NewReader(`{"version": 1, "lines": 3, "good_index": 0, "bad_index": 2, "history": [{"index": 1, "verdict": "maybe"}]}`))
+	assert.Error(t, err)
+}
+
+func TestLoad_ChangedInput(t *testing.T) {
+	first, err := NewIterator([]string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	var saved bytes.Buffer
+	require.NoError(t, first.Save(&saved))
+	assert.Contains(t, saved.String(), `"fingerprint"`)
+
+	// Same number of lines, different content
+	changed, err := NewIterator([]string{"a", "b", "x", "d"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, changed.Load(bytes.NewReader(saved.Bytes())), ErrStateMismatch)
+
+	same, err := NewIterator([]string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	assert.NoError(t, same.Load(&saved))
+}
+
+func TestBisector_Replay(t *testing.T) {
+	lines := []string{"good1", "good2", "good3", "bad1", "bad2", "bad3", "bad4", "bad5"}
+	var narrowed [][2]int
+	bisector, err := New(lines,
+		WithInput(strings.NewReader("b\n")),
+		WithOutput(&bytes.Buffer{}),
+		WithObserver(Observer{OnRangeNarrowed: func(goodIdx, badIdx int) {
+			narrowed = append(narrowed, [2]int{goodIdx, badIdx})
+		}}),
+	)
+	require.NoError(t, err)
+	replayer := bisector.(interface {
+		Replay(steps []Step) error
+		History() []Step
+	})
+
+	// Two logged decisions leave only line 4 to ask about
+	logged := []Step{{Index: 3, Verdict: Skip}, {Index: 2, Verdict: Good}}
+	require.NoError(t, replayer.Replay(logged))
+	assert.Equal(t, logged, replayer.History())
+	assert.Equal(t, [][2]int{{0, 7}, {2, 7}}, narrowed)
+
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.BadLineNumber)
+	assert.Equal(t, 3, result.StepsTaken)
+	assert.Equal(t, []int{4}, result.Skipped)
+}
+
+func TestBisector_ReplayOutsideRange(t *testing.T) {
+	bisector, err := New([]string{"good", "bad1", "bad2", "bad3"}, WithOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+	replayer := bisector.(interface{ Replay(steps []Step) error })
+
+	require.NoError(t, replayer.Replay([]Step{{Index: 2, Verdict: Bad}}))
+	err = replayer.Replay([]Step{{Index: 3, Verdict: Good}})
+	assert.ErrorIs(t, err, ErrStateMismatch)
+}
@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StreamOracle judges candidates by asking another program over a pair of
+// streams, such as the named pipes of a FIFOOracle. Each probe is
+// written to Probes as a JSON object on one line:
+//
+//	{"line_number": 42, "line": "...", "file": "/tmp/bsct-123.txt"}
+//
+// and answered with one line read from Verdicts: good, bad or skip, or a JSON
+// object like {"verdict": "good"}.
+type StreamOracle struct {
+	Probes   io.Writer
+	Verdicts io.Reader
+
+	mu        sync.Mutex // One probe is asked about at a time
+	start     sync.Once
+	answers   chan streamAnswer
+	abandoned int // Probes given up on whose answers are still to come
+}
+
+// streamProbe is a probe as written to StreamOracle.Probes
+type streamProbe struct {
+	LineNumber int    `json:"line_number"`
+	Line       string `json:"line"`
+	File       string `json:"file,omitempty"`
+}
+
+// Evaluate writes c to Probes and waits for its verdict on Verdicts. A probe
+// given up on because ctx is done still gets an answer, which is passed over.
+func (o *StreamOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.start.Do(func() {
+		o.answers = make(chan streamAnswer)
+		go o.read()
+	})
+
+	probe, err := json.Marshal(streamProbe{LineNumber: c.Index + 1, Line: c.Line, File: c.Path})
+	if err != nil {
+		return Bad, err
+	}
+	if _, err := o.Probes.Write(append(probe, '\n')); err != nil {
+		return Bad, fmt.Errorf("failed to write probe: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			o.abandoned++
+			return Bad, ctx.Err()
+		case a := <-o.answers:
+			if a.err != nil {
+				return Bad, a.err
+			}
+			if o.abandoned > 0 {
+				o.abandoned--
+				continue
+			}
+			return parseStreamVerdict(a.line)
+		}
+	}
+}
+
+// streamAnswer is a line read from Verdicts, or why there are no more
+type streamAnswer struct {
+	line string
+	err  error
+}
+
+// read sends every line of Verdicts to answers, and then the error that ended
+// them from then on
+func (o *StreamOracle) read() {
+	r := bufio.NewReader(o.Verdicts)
+	for {
+		line, err := r.ReadString('\n')
+		if err == nil || (errors.Is(err, io.EOF) && line != "") {
+			o.answers <- streamAnswer{line: strings.TrimSpace(line)}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			err = errors.New("verdict stream closed before answering")
+		} else {
+			err = fmt.Errorf("failed to read verdict: %w", err)
+		}
+		for {
+			o.answers <- streamAnswer{err: err}
+		}
+	}
+}
+
+// parseStreamVerdict parses a verdict written as a bare name or a JSON object
+func parseStreamVerdict(answer string) (Verdict, error) {
+	if strings.HasPrefix(answer, "{") {
+		var msg struct {
+			Verdict *Verdict `json:"verdict"`
+		}
+		if err := json.Unmarshal([]byte(answer), &msg); err != nil {
+			return Bad, fmt.Errorf("invalid verdict %q: %w", answer, err)
+		}
+		if msg.Verdict == nil {
+			return Bad, fmt.Errorf("invalid verdict %q: no verdict field", answer)
+		}
+		return *msg.Verdict, nil
+	}
+	var v Verdict
+	if err := v.UnmarshalText([]byte(strings.ToLower(answer))); err != nil {
+		return Bad, err
+	}
+	return v, nil
+}
+
+// FIFOOracle is a StreamOracle over the named pipes Path.probes, which
+// probes are written to, and Path.verdicts, which answers are read from. They
+// are created if they don't exist and opened on the first Evaluate, which
+// waits for another program to open both, in either order.
+type FIFOOracle struct {
+	Path string
+
+	mu     sync.Mutex
+	stream *StreamOracle
+	files  []*os.File
+}
+
+// Evaluate opens the pipes if they aren't yet and asks about c over them
+func (o *FIFOOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	stream, err := o.open(ctx)
+	if err != nil {
+		return Bad, err
+	}
+	return stream.Evaluate(ctx, c)
+}
+
+// open returns the StreamOracle over the pipes, opening them first if needed
+func (o *FIFOOracle) open(ctx context.Context) (*StreamOracle, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.stream != nil {
+		return o.stream, nil
+	}
+
+	probesPath, verdictsPath := o.Path+".probes", o.Path+".verdicts"
+	for _, path := range []string{probesPath, verdictsPath} {
+		if err := mkfifo(path); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			return nil, fmt.Errorf("%s exists and isn't a named pipe", path)
+		}
+	}
+
+	// Opening a pipe blocks until its other end is opened too
+	type opened struct {
+		f   *os.File
+		err error
+	}
+	done := make(chan opened, 2)
+	go func() {
+		f, err := os.OpenFile(probesPath, os.O_WRONLY, 0)
+		done <- opened{f, err}
+	}()
+	go func() {
+		f, err := os.Open(verdictsPath)
+		done <- opened{f, err}
+	}()
+	stream := &StreamOracle{}
+	for range 2 {
+		var r opened
+		select {
+		case <-ctx.Done():
+			o.closeFiles()
+			return nil, ctx.Err()
+		case r = <-done:
+		}
+		if r.err != nil {
+			o.closeFiles()
+			return nil, r.err
+		}
+		o.files = append(o.files, r.f)
+		if r.f.Name() == probesPath {
+			stream.Probes = r.f
+		} else {
+			stream.Verdicts = r.f
+		}
+	}
+	o.stream = stream
+	return stream, nil
+}
+
+// Close closes the pipes
+func (o *FIFOOracle) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closeFiles()
+}
+
+func (o *FIFOOracle) closeFiles() error {
+	var errs []error
+	for _, f := range o.files {
+		errs = append(errs, f.Close())
+	}
+	o.files, o.stream = nil, nil
+	return errors.Join(errs...)
+}
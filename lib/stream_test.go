@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// judge answers the probes read from probes on verdicts with answer
+func judge(t *testing.T, probes io.Reader, verdicts io.Writer, answer func(p streamProbe) string) {
+	scanner := bufio.NewScanner(probes)
+	for scanner.Scan() {
+		var p streamProbe
+		if !assert.NoError(t, json.Unmarshal(scanner.Bytes(), &p)) {
+			return
+		}
+		if _, err := fmt.Fprintln(verdicts, answer(p)); err != nil {
+			return
+		}
+	}
+}
+
+func TestStreamOracle(t *testing.T) {
+	probesR, probesW := io.Pipe()
+	verdictsR, verdictsW := io.Pipe()
+	go judge(t, probesR, verdictsW, func(p streamProbe) string {
+		if strings.Contains(p.Line, "bad") {
+			return `{"verdict": "bad"}`
+		}
+		return "GOOD"
+	})
+
+	oracle := &StreamOracle{Probes: probesW, Verdicts: verdictsR}
+	bisector, err := New([]string{"a", "b", "c", "bad", "e"}, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+}
+
+func TestStreamOracle_InvalidAnswers(t *testing.T) {
+	for _, answer := range []string{"maybe", `{"verdict": "maybe"}`, `{"other": 1}`, "{"} {
+		t.Run(answer, func(t *testing.T) {
+			probesR, probesW := io.Pipe()
+			oracle := &StreamOracle{Probes: probesW, Verdicts: strings.NewReader(answer + "\n")}
+			go io.Copy(io.Discard, probesR)
+			_, err := oracle.Evaluate(context.Background(), Candidate{Line: "x"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStreamOracle_Closed(t *testing.T) {
+	oracle := &StreamOracle{Probes: io.Discard, Verdicts: strings.NewReader("skip")}
+	v, err := oracle.Evaluate(context.Background(), Candidate{Line: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, Skip, v)
+
+	_, err = oracle.Evaluate(context.Background(), Candidate{Line: "y"})
+	assert.ErrorContains(t, err, "closed")
+}
+
+func TestStreamOracle_AbandonedProbe(t *testing.T) {
+	verdictsR, verdictsW := io.Pipe()
+	oracle := &StreamOracle{Probes: io.Discard, Verdicts: verdictsR}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := oracle.Evaluate(ctx, Candidate{Line: "slow"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The late answer to the abandoned probe isn't taken for the next one's
+	go fmt.Fprint(verdictsW, "good\nbad\n")
+	v, err := oracle.Evaluate(context.Background(), Candidate{Line: "next"})
+	require.NoError(t, err)
+	assert.Equal(t, Bad, v)
+}
+
+func TestFIFOOracle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes need a path")
+	}
+	base := filepath.Join(t.TempDir(), "answers")
+	oracle := &FIFOOracle{Path: base}
+	defer oracle.Close()
+
+	go func() {
+		// Opened in the opposite order to bsct, which doesn't deadlock it
+		for {
+			if _, err := os.Stat(base + ".verdicts"); err == nil {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		verdicts, err := os.OpenFile(base+".verdicts", os.O_WRONLY, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer verdicts.Close()
+		probes, err := os.Open(base + ".probes")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer probes.Close()
+		judge(t, probes, verdicts, func(p streamProbe) string {
+			if p.LineNumber >= 3 {
+				return "bad"
+			}
+			return "good"
+		})
+	}()
+
+	bisector, err := New([]string{"a", "b", "c", "d", "e"}, WithOracle(oracle), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.BadLineNumber)
+}
+
+func TestFIFOOracle_NotAPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes need a path")
+	}
+	base := filepath.Join(t.TempDir(), "answers")
+	require.NoError(t, os.WriteFile(base+".probes", nil, 0o600))
+	_, err := (&FIFOOracle{Path: base}).Evaluate(context.Background(), Candidate{})
+	assert.ErrorContains(t, err, "isn't a named pipe")
+}
@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// target is the file WithTarget puts every candidate in place of, such as the
+// config file of a daemon the test restarts
+type target struct {
+	path     string
+	original []byte // Content to put back once the bisection ends
+	sum      [sha256.Size]byte
+	backup   string   // Copy of the original kept until it is restored
+	meta     fileMeta // Mode, owner and extended attributes candidates keep
+	warn     io.Writer
+	warned   bool // Whether a failure to keep meta has been reported
+	sync     bool // Whether writes are flushed to stable storage
+}
+
+// backupSuffix names the backup of a target next to it
+const backupSuffix = ".bsct-backup"
+
+// openTarget reads the file at path so it can be restored later and backs it
+// up next to it, in case bsct can't restore it itself. A symlink is followed,
+// so candidates replace the file it points to rather than it.
+func openTarget(path string, warn io.Writer) (*target, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target file: %w", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target file: %w", err)
+	}
+	meta, err := statMeta(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target file: %w", err)
+	}
+	t := &target{path: path, original: original, sum: sha256.Sum256(original), backup: path + backupSuffix, meta: meta, warn: warn}
+	if err := t.writeBackup(); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrTargetBackup, path, err)
+	}
+	return t, nil
+}
+
+// writeBackup copies the original to t.backup and checks the copy. An
+// existing backup is most likely all that's left of a run that couldn't
+// restore the target, so it is never overwritten.
+func (t *target) writeBackup() error {
+	f, err := os.OpenFile(t.backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists, likely from an earlier run that didn't finish. Restore the target from it or remove it", t.backup)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(t.original)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = t.verify(t.backup)
+	}
+	if err != nil {
+		os.Remove(t.backup)
+	}
+	return err
+}
+
+// verify checks that the file at path holds the original content
+func (t *target) verify(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(data) != t.sum {
+		return fmt.Errorf("%s doesn't match the original's checksum", path)
+	}
+	return nil
+}
+
+// write makes the target hold c's content
+func (t *target) write(c Candidate) error {
+	if err := t.put(c); err != nil {
+		return fmt.Errorf("failed to write target file: %w", err)
+	}
+	return nil
+}
+
+// restore puts the original content back and checks it, then removes the
+// backup. If either fails, the backup is left for the user.
+func (t *target) restore() error {
+	err := t.put(bytes.NewReader(t.original))
+	if err == nil {
+		err = t.verify(t.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w %s: %w. The original is kept at %s", ErrTargetRestore, t.path, err, t.backup)
+	}
+	if err := os.Remove(t.backup); err != nil {
+		fmt.Fprintf(t.warn, "Warning: failed to remove the backup of %s: %v\n", t.path, err)
+	}
+	return nil
+}
+
+// put replaces the target with what src writes. The content goes to a temp
+// file next to it that gets the original's metadata and is then renamed over
+// it, so a program watching the target never reads half a candidate.
+func (t *target) put(src io.WriterTo) error {
+	// Split would give "" for a bare file name, which CreateTemp takes as
+	// the temp dir
+	dir, base := filepath.Dir(t.path), filepath.Base(t.path)
+	f, err := os.CreateTemp(dir, "."+base+".bsct-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = src.WriteTo(f)
+	if err == nil && t.sync {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if metaErr := t.meta.apply(tmp); metaErr != nil && !t.warned {
+			t.warned = true
+			fmt.Fprintf(t.warn, "Warning: failed to keep the metadata of %s: %v\n", t.path, metaErr)
+		}
+		err = os.Rename(tmp, t.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if t.sync {
+		return syncDir(dir)
+	}
+	return nil
+}
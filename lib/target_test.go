@@ -0,0 +1,181 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutomaticBisector_Target(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0640))
+	require.NoError(t, os.Chmod(path, 0640))
+
+	lines := []string{"a", "b", "c", "bad", "e", "f"}
+	oracle := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+		// A test that replaces the file doesn't change what the next one gets
+		require.NoError(t, os.Remove(path))
+		require.NoError(t, os.WriteFile(path, []byte("replaced\n"), 0666))
+
+		if strings.Contains(string(data), "bad") {
+			return Bad, nil
+		}
+		return Good, nil
+	})
+
+	bisector, err := New(lines, WithOracle(oracle), WithTarget(path), WithOutput(io.Discard))
+	require.NoError(t, err)
+	result, err := bisector.Bisect()
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.BadLineNumber)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(data))
+	assert.NoFileExists(t, path+backupSuffix)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	}
+}
+
+func TestAutomaticBisector_TargetMissing(t *testing.T) {
+	bisector, err := New([]string{"a", "b", "c"}, WithOracle(OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		return Good, nil
+	})), WithTarget(filepath.Join(t.TempDir(), "missing.conf")), WithOutput(io.Discard))
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = New([]string{"a", "b", "c"}, WithTestCommand("true"), WithTarget("app.conf"), WithConcurrency(2))
+	assert.Error(t, err)
+}
+
+func TestFileMeta_Xattrs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("user extended attributes are set with Linux names")
+	}
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0644))
+	if err := setXattr(path, "user.bsct", []byte("label")); err != nil {
+		t.Skipf("filesystem doesn't support user attributes: %v", err)
+	}
+
+	meta, err := statMeta(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("label"), meta.xattrs["user.bsct"])
+
+	// A replaced file gets the attribute back
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.WriteFile(path, []byte("replaced\n"), 0644))
+	require.NoError(t, meta.apply(path))
+	current, err := statMeta(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("label"), current.xattrs["user.bsct"])
+}
+
+func TestTarget_Put(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0644))
+	link := filepath.Join(dir, "current.conf")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	tgt, err := openTarget(link, io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, tgt.write(Candidate{Index: 1, Line: "b", src: Lines{"a", "b", "c"}}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(data))
+
+	// The symlink still points at the file and no temp files are left over
+	require.NoError(t, tgt.restore())
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	data, err = os.ReadFile(link)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(data))
+}
+
+func TestTarget_Backup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0644))
+
+	tgt, err := openTarget(path, io.Discard)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path + backupSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(data))
+
+	// A backup left over from another run stops a second one from starting
+	_, err = openTarget(path, io.Discard)
+	assert.ErrorIs(t, err, ErrTargetBackup)
+
+	// Content that doesn't match the checksum keeps the backup
+	tgt.original = []byte("changed\n")
+	err = tgt.restore()
+	assert.ErrorIs(t, err, ErrTargetRestore)
+	assert.FileExists(t, path+backupSuffix)
+
+	tgt.original = []byte("original\n")
+	require.NoError(t, tgt.restore())
+	assert.NoFileExists(t, path+backupSuffix)
+}
+
+func TestAutomaticBisector_TargetBackupExists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("original\n"), 0644))
+	require.NoError(t, os.WriteFile(path+backupSuffix, []byte("older\n"), 0600))
+
+	probed := false
+	bisector, err := New([]string{"a", "b", "c"}, WithOracle(OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		probed = true
+		return Good, nil
+	})), WithTarget(path), WithOutput(io.Discard))
+	require.NoError(t, err)
+	_, err = bisector.Bisect()
+	assert.ErrorIs(t, err, ErrTargetBackup)
+	assert.False(t, probed)
+
+	// Neither the target nor the older backup were touched
+	data, err := os.ReadFile(path + backupSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "older\n", string(data))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(data))
+}
+
+func TestTarget_RelativePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile("app.conf", []byte("original\n"), 0644))
+
+	tgt, err := openTarget("app.conf", io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, tgt.write(Candidate{Index: 0, Line: "a", src: Lines{"a"}}))
+	require.NoError(t, tgt.restore())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
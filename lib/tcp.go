@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// TCPOracle judges a candidate good when Address accepts TCP connections
+// within Timeout, e.g. a daemon restarted with the candidate by a before
+// command
+type TCPOracle struct {
+	Address  string        // host:port to connect to
+	Timeout  time.Duration // How long to keep trying, 10s if zero
+	Interval time.Duration // Wait between attempts, 100ms if zero
+}
+
+// Evaluate dials Address until it connects or Timeout elapses
+func (o *TCPOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	interval := o.Interval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	deadline := time.Now().Add(timeout)
+	var dialer net.Dialer
+	for {
+		dialCtx, cancel := context.WithDeadline(ctx, deadline)
+		conn, err := dialer.DialContext(dialCtx, "tcp", o.Address)
+		cancel()
+		if err == nil {
+			conn.Close()
+			return Good, nil
+		}
+		if ctx.Err() != nil {
+			return Bad, ctx.Err()
+		}
+
+		wait := min(interval, time.Until(deadline))
+		if wait <= 0 {
+			return Bad, nil
+		}
+		select {
+		case <-ctx.Done():
+			return Bad, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
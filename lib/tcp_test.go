@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPOracle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+
+	oracle := &TCPOracle{Address: addr, Timeout: 200 * time.Millisecond, Interval: 10 * time.Millisecond}
+	v, err := oracle.Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Good, v)
+
+	// Nothing listening any more
+	listener.Close()
+	v, err = oracle.Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Bad, v)
+}
+
+func TestTCPOracle_ComesUpLate(t *testing.T) {
+	// Reserve a port, then only start listening after the oracle begins
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		time.Sleep(time.Second)
+		l.Close()
+	}()
+
+	oracle := &TCPOracle{Address: addr, Timeout: 2 * time.Second, Interval: 10 * time.Millisecond}
+	v, err := oracle.Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Good, v)
+}
+
+func TestTCPOracle_Canceled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = (&TCPOracle{Address: addr, Timeout: time.Hour}).Evaluate(ctx, Candidate{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,27 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutOracle wraps inner so each evaluation is canceled after d and
+// reported as ErrProbeTimeout, keeping a hung test from stalling a bisection.
+// A d of zero or less disables the limit.
+func TimeoutOracle(inner Oracle, d time.Duration) Oracle {
+	if d <= 0 {
+		return inner
+	}
+	return OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		probeCtx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		v, err := inner.Evaluate(probeCtx, c)
+		if err != nil && ctx.Err() == nil && errors.Is(probeCtx.Err(), context.DeadlineExceeded) {
+			return Bad, fmt.Errorf("%w after %s on line %d", ErrProbeTimeout, d, c.Index+1)
+		}
+		return v, err
+	})
+}
@@ -0,0 +1,33 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutOracle(t *testing.T) {
+	hang := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) {
+		<-ctx.Done()
+		return Bad, ctx.Err()
+	})
+
+	_, err := TimeoutOracle(hang, 10*time.Millisecond).Evaluate(context.Background(), Candidate{Index: 2})
+	assert.ErrorIs(t, err, ErrProbeTimeout)
+	assert.Contains(t, err.Error(), "line 3")
+
+	// Canceling the bisection isn't a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = TimeoutOracle(hang, time.Hour).Evaluate(ctx, Candidate{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrProbeTimeout)
+
+	fast := OracleFunc(func(ctx context.Context, c Candidate) (Verdict, error) { return Good, nil })
+	v, err := TimeoutOracle(fast, time.Hour).Evaluate(context.Background(), Candidate{})
+	require.NoError(t, err)
+	assert.Equal(t, Good, v)
+}
@@ -0,0 +1,185 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebhookOracle asks a person for each verdict through a chat webhook, so a
+// long bisection can be answered asynchronously by whoever has context. Every
+// probe is posted to URL as a Slack and Teams compatible {"text": ...} message
+// with a good and a bad link. A link opens a page confirming the verdict, and
+// only submitting it answers the probe, so chat apps that fetch links to
+// preview them can't answer on anyone's behalf.
+type WebhookOracle struct {
+	URL         string       // Incoming webhook each probe is posted to
+	Listen      string       // Address the links are served on, :8090 if empty
+	CallbackURL string       // Base URL the links use, e.g. https://bsct.example.com; http://<Listen> if empty
+	Client      *http.Client // http.DefaultClient if nil
+
+	mu      sync.Mutex
+	srv     *http.Server
+	base    string
+	pending map[string]*pendingProbe
+}
+
+// pendingProbe is a probe waiting for someone to answer it through its links
+type pendingProbe struct {
+	line   int // 1-indexed
+	answer chan Verdict
+}
+
+// Evaluate posts c and waits until a verdict is submitted through one of its
+// links or ctx is done
+func (o *WebhookOracle) Evaluate(ctx context.Context, c Candidate) (Verdict, error) {
+	base, err := o.start()
+	if err != nil {
+		return Bad, err
+	}
+
+	token := rand.Text()
+	answer := make(chan Verdict, 1)
+	o.mu.Lock()
+	o.pending[token] = &pendingProbe{line: c.Index + 1, answer: answer}
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		delete(o.pending, token)
+		o.mu.Unlock()
+	}()
+
+	text := fmt.Sprintf("bsct needs a verdict for line %d:\n```\n%s\n```\nGood: %s/verdict/%s/good\nBad: %s/verdict/%s/bad",
+		c.Index+1, c.Line, base, token, base, token)
+	if err := o.post(ctx, text); err != nil {
+		return Bad, err
+	}
+
+	select {
+	case v := <-answer:
+		return v, nil
+	case <-ctx.Done():
+		return Bad, ctx.Err()
+	}
+}
+
+// Close stops serving links
+func (o *WebhookOracle) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.srv == nil {
+		return nil
+	}
+	err := o.srv.Close()
+	o.srv = nil
+	return err
+}
+
+// start serves the links on first use and returns their base URL
+func (o *WebhookOracle) start() (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.srv != nil {
+		return o.base, nil
+	}
+
+	addr := o.Listen
+	if addr == "" {
+		addr = ":8090"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to serve webhook links: %w", err)
+	}
+
+	o.base = strings.TrimSuffix(o.CallbackURL, "/")
+	if o.base == "" {
+		o.base = "http://" + ln.Addr().String()
+	}
+	o.pending = make(map[string]*pendingProbe)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /verdict/{token}/{verdict}", o.confirm)
+	mux.HandleFunc("POST /verdict/{token}/{verdict}", o.answer)
+	o.srv = &http.Server{Handler: mux}
+	go o.srv.Serve(ln)
+	return o.base, nil
+}
+
+// confirm serves the page a followed link opens, whose button submits the
+// verdict. Fetching it records nothing.
+func (o *WebhookOracle) confirm(w http.ResponseWriter, r *http.Request) {
+	var v Verdict
+	if err := v.UnmarshalText([]byte(r.PathValue("verdict"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	p, ok := o.pending[r.PathValue("token")]
+	o.mu.Unlock()
+	if !ok {
+		http.Error(w, "This probe was already answered or is no longer needed.", http.StatusGone)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<title>bsct verdict</title>
+<form method="post"><p>Mark line %d as %s?</p><button type="submit">Mark %s</button></form>
+`, p.line, v, v)
+}
+
+// answer records the verdict submitted from a confirmation page. Each probe
+// takes one answer; later ones are refused.
+func (o *WebhookOracle) answer(w http.ResponseWriter, r *http.Request) {
+	var v Verdict
+	if err := v.UnmarshalText([]byte(r.PathValue("verdict"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	p, ok := o.pending[r.PathValue("token")]
+	delete(o.pending, r.PathValue("token"))
+	o.mu.Unlock()
+	if !ok {
+		http.Error(w, "This probe was already answered or is no longer needed.", http.StatusGone)
+		return
+	}
+	p.answer <- v
+	fmt.Fprintf(w, "Thanks, recorded %s.\n", v)
+}
+
+// post sends text to the webhook
+func (o *WebhookOracle) post(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("webhook rejected the message: " + resp.Status)
+	}
+	return nil
+}
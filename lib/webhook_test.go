@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookOracle(t *testing.T) {
+	link := regexp.MustCompile(`Bad: (\S+)`)
+	var posted string
+	previewed := make(chan string, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		posted = msg.Text
+
+		// Answer the way a teammate would: chat previews fetch the link,
+		// which records nothing, and the confirmation page submits it
+		go func() {
+			url := link.FindStringSubmatch(msg.Text)[1]
+			resp, err := http.Get(url)
+			if err != nil {
+				return
+			}
+			page, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			previewed <- string(page)
+			if resp, err = http.Post(url, "", nil); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}))
+	defer hook.Close()
+
+	oracle := &WebhookOracle{URL: hook.URL, Listen: "127.0.0.1:0"}
+	defer oracle.Close()
+
+	v, err := oracle.Evaluate(context.Background(), Candidate{Index: 4, Line: "timeout: 0"})
+	require.NoError(t, err)
+	assert.Equal(t, Bad, v)
+	assert.Contains(t, posted, "line 5")
+	assert.Contains(t, posted, "timeout: 0")
+	page := <-previewed
+	assert.Contains(t, page, "Mark line 5 as bad?")
+	assert.Contains(t, page, `method="post"`)
+
+	// Submitting a link twice doesn't answer the next probe
+	resp, err := http.Post(link.FindStringSubmatch(posted)[1], "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+}
+
+func TestWebhookOracle_PreviewDoesNotAnswer(t *testing.T) {
+	link := regexp.MustCompile(`Good: (\S+)`)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		// A chat app unfurling the link
+		go http.Get(link.FindStringSubmatch(msg.Text)[1])
+	}))
+	defer hook.Close()
+
+	oracle := &WebhookOracle{URL: hook.URL, Listen: "127.0.0.1:0"}
+	defer oracle.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := oracle.Evaluate(ctx, Candidate{Index: 1, Line: "x"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWebhookOracleCanceled(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hook.Close()
+
+	oracle := &WebhookOracle{URL: hook.URL, Listen: "127.0.0.1:0"}
+	defer oracle.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := oracle.Evaluate(ctx, Candidate{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,58 @@
+//go:build linux || darwin
+
+package lib
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the extended attributes of the file at path, or nil if
+// its filesystem doesn't support them
+func listXattrs(path string) (map[string][]byte, error) {
+	names, err := readXattr(func(dest []byte) (int, error) { return unix.Listxattr(path, dest) })
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string][]byte)
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := readXattr(func(dest []byte) (int, error) { return unix.Getxattr(path, string(name), dest) })
+		if err != nil {
+			// Attributes can go away between listing and reading them
+			continue
+		}
+		attrs[string(name)] = value
+	}
+	return attrs, nil
+}
+
+// setXattr sets the extended attribute name of the file at path
+func setXattr(path, name string, value []byte) error {
+	return unix.Setxattr(path, name, value, 0)
+}
+
+// readXattr calls read with a buffer of the size it asks for when given none
+func readXattr(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		n, err := read(nil)
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		if n, err = read(buf); errors.Is(err, unix.ERANGE) {
+			continue // Grew in between
+		} else if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
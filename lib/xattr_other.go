@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package lib
+
+// listXattrs reports that extended attributes aren't supported on this
+// platform
+func listXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// setXattr is never called where listXattrs finds no attributes
+func setXattr(path, name string, value []byte) error {
+	return nil
+}